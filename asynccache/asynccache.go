@@ -1,65 +1,179 @@
 package asynccache
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/go-metrics"
 	sf "golang.org/x/sync/singleflight"
 )
 
-// Options controls the behavior of AsyncCache.
-type Options struct {
+// TypedOptions controls the behavior of AsyncCache.
+type TypedOptions[K comparable, V any] struct {
+	// Name, if set, enables the metrics of the cache, labelled with the name.
+	// See the MetricKey constants for what is emitted.
+	Name string
+
 	RefreshDuration time.Duration
-	Fetcher         func(key string) (interface{}, error)
+	Fetcher         func(key K) (V, error)
+
+	// ContextFetcher, if set, is used instead of Fetcher.
+	// The context passed by GetCtx keeps its values but is never canceled, because the fetch is
+	// shared with other callers of the same key. Refreshing passes context.Background().
+	ContextFetcher func(ctx context.Context, key K) (V, error)
+
+	// BatchFetcher, if set, is used by the refresh goroutine instead of Fetcher.
+	// Keys are grouped into chunks of at most BatchSize (DefaultBatchSize if 0).
+	// A key missing from both returned maps is reported as ErrMissingFromBatch.
+	// If Fetcher is nil, the first fetching of a key calls BatchFetcher with that single key.
+	BatchFetcher func(keys []K) (map[K]V, map[K]error)
+	BatchSize    int
+
+	// If EnablePerKeyRefresh is true, every key is refreshed on its own deadline instead of
+	// all keys on the ticker shared by caches with the same RefreshDuration.
+	// The deadline is the last fetch time plus the key's refresh interval plus a random
+	// delay in [0, RefreshJitter). The interval is RefreshDuration unless IntervalFetcher
+	// returns a positive one for the key.
+	// At most MaxRefreshConcurrency (default 1) fetches run at the same time.
+	EnablePerKeyRefresh   bool
+	RefreshJitter         time.Duration
+	IntervalFetcher       func(key K) (V, time.Duration, error)
+	MaxRefreshConcurrency int
 
 	// If EnableExpire is true, ExpireDuration MUST be set.
 	EnableExpire   bool
 	ExpireDuration time.Duration
 
-	ErrorHandler  func(key string, err error)
-	ChangeHandler func(key string, oldData, newData interface{})
-	DeleteHandler func(key string, oldData interface{})
-
-	IsSame     func(key string, oldData, newData interface{}) bool
+	// MaxStaleness bounds how long the last good value of a key is served while refreshing
+	// it fails. Once the value is older than MaxStaleness, Get returns the latest refresh error
+	// until a refresh succeeds. 0 means the last good value is served forever.
+	MaxStaleness time.Duration
+
+	// NegativeTTL maps sentinel errors to how long a key failing with them is cached.
+	// A negative entry is not refreshed until its TTL passes, after which the next Get
+	// fetches it again. Errors are matched with errors.Is.
+	NegativeTTL map[error]time.Duration
+
+	// MaxEntries bounds the number of cached keys, 0 means unbounded.
+	// When a new key would exceed the bound, an entry is evicted according to EvictionPolicy
+	// and DeleteHandler is called for it.
+	MaxEntries     int
+	EvictionPolicy EvictionPolicy
+
+	// SnapshotCodec is used by Snapshot and Restore, JSONCodec if nil.
+	// If SnapshotPath is set, the snapshot is restored from it when the cache is created,
+	// and written to it every SnapshotInterval (if positive) and on Close.
+	SnapshotCodec    Codec[K, V]
+	SnapshotPath     string
+	SnapshotInterval time.Duration
+
+	// Remote, if set, is a second level cache shared with other replicas.
+	// The first fetching of a key reads Remote before calling the fetcher, and every fetched
+	// value is written to Remote with RemoteTTL (3 * RefreshDuration if 0) under RemotePrefix
+	// plus the key. Each refresh of a key takes a lease in Remote, only the replica holding it
+	// calls the fetcher while the others read the value from Remote.
+	// Values are encoded with RemoteMarshal and RemoteUnmarshal, JSON if nil.
+	Remote          RemoteStore
+	RemotePrefix    string
+	RemoteTTL       time.Duration
+	RemoteMarshal   func(val V) ([]byte, error)
+	RemoteUnmarshal func(b []byte) (V, error)
+
+	ErrorHandler  func(key K, err error)
+	ChangeHandler func(key K, oldData, newData V)
+	DeleteHandler func(key K, oldData V)
+
+	IsSame     func(key K, oldData, newData V) bool
 	ErrLogFunc func(str string)
 }
 
-// AsyncCache .
-type AsyncCache interface {
+// Options controls the behavior of the untyped AsyncCache created by NewAsyncCache.
+type Options = TypedOptions[string, interface{}]
+
+// AsyncCache is the untyped cache created by NewAsyncCache.
+type AsyncCache = TypedAsyncCache[string, interface{}]
+
+// TypedAsyncCache .
+type TypedAsyncCache[K comparable, V any] interface {
 	// SetDefault sets the default value of given key if it is new to the cache.
 	// It is useful for cache warming up.
 	// Param val should not be nil.
-	SetDefault(key string, val interface{}) (exist bool)
+	SetDefault(key K, val V) (exist bool)
 
 	// Get tries to fetch a value corresponding to the given key from the cache.
 	// If error occurs during the first time fetching, it will be cached until the
 	// sequential fetching triggered by the refresh goroutine succeed.
-	Get(key string) (val interface{}, err error)
+	Get(key K) (val V, err error)
+
+	// GetCtx is like Get, but returns ctx.Err() as soon as ctx is done while the first fetching
+	// of key is still running. The fetching itself is not canceled and its result is cached
+	// for the other callers.
+	GetCtx(ctx context.Context, key K) (val V, err error)
 
 	// GetOrSet tries to fetch a value corresponding to the given key from the cache.
 	// If the key is not yet cached or error occurs, the default value will be set.
-	GetOrSet(key string, defaultVal interface{}) (val interface{})
+	GetOrSet(key K, defaultVal V) (val V)
 
 	// Dump dumps all cache entries.
 	// This will not cause expire to refresh.
-	Dump() map[string]interface{}
+	Dump() map[K]V
 
 	// DeleteIf deletes cached entries that match the `shouldDelete` predicate.
-	DeleteIf(shouldDelete func(key string) bool)
+	DeleteIf(shouldDelete func(key K) bool)
+
+	// Age returns how long ago the value of key was last fetched successfully or set.
+	// ok is false if key is not cached or has never been loaded successfully.
+	Age(key K) (age time.Duration, ok bool)
+
+	// Stats returns the counters of the cache.
+	Stats() Stats
+
+	// Snapshot writes the successfully fetched entries to w with the snapshot codec.
+	Snapshot(w io.Writer) error
+
+	// Restore reads a snapshot from r and sets its entries as default values.
+	// Keys already in the cache are kept.
+	Restore(r io.Reader) error
 
 	// Close closes the async cache.
 	// This should be called when the cache is no longer needed, or may lead to resource leak.
 	Close()
 }
 
-// asyncCache .
-type asyncCache struct {
+// Stats is a snapshot of the counters of an AsyncCache.
+type Stats struct {
+	Entries   int
+	Evictions uint64
+}
+
+// asyncCache is the untyped typedAsyncCache.
+type asyncCache = typedAsyncCache[string, interface{}]
+
+// typedAsyncCache .
+type typedAsyncCache[K comparable, V any] struct {
 	sfg  sf.Group
-	opt  Options
+	opt  TypedOptions[K, V]
 	data sync.Map
+
+	// mu guards evictor, which is nil if MaxEntries is not set.
+	mu        sync.Mutex
+	evictor   evictor[K]
+	evictions atomic.Uint64
+	size      atomic.Int64
+
+	// labels of the emitted metrics, nil if Name is not set.
+	labels []metrics.Label
+
+	// sched is set if EnablePerKeyRefresh is true.
+	sched *scheduler[K, V]
+
+	// snapStop and snapDone control the snapshot flushing goroutine.
+	snapStop, snapDone chan struct{}
 }
 
 type tickerType int
@@ -69,12 +183,19 @@ const (
 	expireTicker
 )
 
+// tickable is implemented by every instantiation of asyncCache so that
+// caches with different key/value types can share the same ticker.
+type tickable interface {
+	refresh()
+	expire()
+}
+
 type sharedTicker struct {
 	sync.Mutex
 	started  bool
 	stopChan chan bool
 	ticker   *time.Ticker
-	caches   map[*asyncCache]struct{}
+	caches   map[tickable]struct{}
 }
 
 var (
@@ -82,42 +203,91 @@ var (
 	refreshTickerMap, expireTickerMap sync.Map
 )
 
-type entry struct {
-	val    atomic.Value
-	expire int32 // 0 means useful, 1 will expire
-	err    Error
+type entry[V any] struct {
+	val      atomic.Pointer[V]
+	expire   int32 // 0 means useful, 1 will expire
+	err      Error
+	interval atomic.Int64 // refresh interval returned by IntervalFetcher, 0 means default
+	updated  atomic.Int64 // unix nano of the last successful Store, 0 means never
+	negUntil atomic.Int64 // unix nano until which a negative entry is kept, 0 means not negative
 }
 
-func (e *entry) Store(x interface{}, err error) {
-	if x != nil {
-		e.val.Store(x)
-	} else {
-		e.val = atomic.Value{}
-	}
+func (e *entry[V]) Store(x V, err error) {
+	e.val.Store(&x)
 	e.err.Store(err)
+	if err == nil {
+		e.updated.Store(time.Now().UnixNano())
+		e.negUntil.Store(0)
+	}
+}
+
+func (e *entry[V]) Load() V {
+	if p := e.val.Load(); p != nil {
+		return *p
+	}
+	var zero V
+	return zero
 }
 
-func (e *entry) Touch() {
+func (e *entry[V]) Touch() {
 	atomic.StoreInt32(&e.expire, 0)
 }
 
-// NewAsyncCache creates an AsyncCache.
+func (e *entry[V]) Interval() time.Duration {
+	return time.Duration(e.interval.Load())
+}
+
+func (e *entry[V]) SetInterval(d time.Duration) {
+	e.interval.Store(int64(d))
+}
+
+// NewAsyncCache creates an AsyncCache with string keys and untyped values.
+// It is kept for existing callers, new code should prefer NewTypedAsyncCache.
 func NewAsyncCache(opt Options) AsyncCache {
-	c := &asyncCache{
-		sfg: sf.Group{},
-		opt: opt,
+	return NewTypedAsyncCache(opt)
+}
+
+// NewTypedAsyncCache creates a TypedAsyncCache.
+func NewTypedAsyncCache[K comparable, V any](opt TypedOptions[K, V]) TypedAsyncCache[K, V] {
+	c := &typedAsyncCache[K, V]{
+		sfg:    sf.Group{},
+		opt:    opt,
+		labels: newLabels(opt.Name),
 	}
 	if c.opt.ErrLogFunc == nil {
 		c.opt.ErrLogFunc = func(str string) {
 			log.Println(str)
 		}
 	}
+	if c.opt.Fetcher == nil && c.opt.ContextFetcher == nil && c.opt.BatchFetcher == nil && c.opt.IntervalFetcher == nil {
+		panic("asynccache: no fetcher is set")
+	}
+	if c.opt.BatchSize <= 0 {
+		c.opt.BatchSize = DefaultBatchSize
+	}
+	if c.opt.Remote != nil {
+		if c.opt.RemoteTTL <= 0 {
+			c.opt.RemoteTTL = 3 * c.opt.RefreshDuration
+		}
+		if c.opt.RemoteMarshal == nil {
+			c.opt.RemoteMarshal = jsonMarshal[V]
+		}
+		if c.opt.RemoteUnmarshal == nil {
+			c.opt.RemoteUnmarshal = jsonUnmarshal[V]
+		}
+	}
+	if c.opt.MaxEntries < 0 {
+		panic("asynccache: invalid MaxEntries")
+	}
+	if c.opt.MaxEntries > 0 {
+		c.evictor = newEvictor[K](c.opt.EvictionPolicy)
+	}
 	if c.opt.EnableExpire {
 		if c.opt.ExpireDuration == 0 {
 			panic("asynccache: invalid ExpireDuration")
 		}
 		ti, _ := expireTickerMap.LoadOrStore(c.opt.ExpireDuration,
-			&sharedTicker{caches: make(map[*asyncCache]struct{}), stopChan: make(chan bool, 1)})
+			&sharedTicker{caches: make(map[tickable]struct{}), stopChan: make(chan bool, 1)})
 		et := ti.(*sharedTicker)
 		et.Lock()
 		et.caches[c] = struct{}{}
@@ -129,27 +299,58 @@ func NewAsyncCache(opt Options) AsyncCache {
 		et.Unlock()
 	}
 
-	ti, _ := refreshTickerMap.LoadOrStore(c.opt.RefreshDuration,
-		&sharedTicker{caches: make(map[*asyncCache]struct{}), stopChan: make(chan bool, 1)})
-	rt := ti.(*sharedTicker)
-	rt.Lock()
-	rt.caches[c] = struct{}{}
-	if !rt.started {
-		rt.started = true
-		rt.ticker = time.NewTicker(c.opt.RefreshDuration)
-		go rt.tick(rt.ticker, refreshTicker)
+	if c.opt.EnablePerKeyRefresh {
+		if c.opt.RefreshDuration <= 0 {
+			panic("asynccache: invalid RefreshDuration")
+		}
+		if c.opt.MaxRefreshConcurrency <= 0 {
+			c.opt.MaxRefreshConcurrency = 1
+		}
+		c.sched = newScheduler(c)
+		go c.sched.run()
+	} else {
+		ti, _ := refreshTickerMap.LoadOrStore(c.opt.RefreshDuration,
+			&sharedTicker{caches: make(map[tickable]struct{}), stopChan: make(chan bool, 1)})
+		rt := ti.(*sharedTicker)
+		rt.Lock()
+		rt.caches[c] = struct{}{}
+		if !rt.started {
+			rt.started = true
+			rt.ticker = time.NewTicker(c.opt.RefreshDuration)
+			go rt.tick(rt.ticker, refreshTicker)
+		}
+		rt.Unlock()
+	}
+
+	if c.opt.SnapshotPath != "" {
+		c.loadSnapshot()
+		if c.opt.SnapshotInterval > 0 {
+			c.snapStop, c.snapDone = make(chan struct{}), make(chan struct{})
+			go c.flushSnapshot(c.snapStop, c.snapDone)
+		}
 	}
-	rt.Unlock()
 	return c
 }
 
 // SetDefault sets the default value of given key if it is new to the cache.
-func (c *asyncCache) SetDefault(key string, val interface{}) bool {
-	ety := &entry{}
+func (c *typedAsyncCache[K, V]) SetDefault(key K, val V) bool {
+	ety := &entry[V]{}
 	ety.Store(val, nil)
+	if c.evictor != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+	}
 	actual, exist := c.data.LoadOrStore(key, ety)
 	if exist {
-		actual.(*entry).Touch()
+		actual.(*entry[V]).Touch()
+		return exist
+	}
+	c.size.Add(1)
+	if c.evictor != nil {
+		c.admit(key)
+	}
+	if c.sched != nil {
+		c.sched.schedule(key, ety)
 	}
 	return exist
 }
@@ -157,95 +358,129 @@ func (c *asyncCache) SetDefault(key string, val interface{}) bool {
 // Get tries to fetch a value corresponding to the given key from the cache.
 // If error occurs during in the first time fetching, it will be cached until the
 // sequential fetchings triggered by the refresh goroutine succeed.
-func (c *asyncCache) Get(key string) (val interface{}, err error) {
-	var ok bool
-	val, ok = c.data.Load(key)
-	if ok {
-		e := val.(*entry)
-		e.Touch()
-		return e.val.Load(), e.err.Load()
+func (c *typedAsyncCache[K, V]) Get(key K) (val V, err error) {
+	return c.GetCtx(context.Background(), key)
+}
+
+// GetCtx is like Get, but gives control back to the caller once ctx is done.
+func (c *typedAsyncCache[K, V]) GetCtx(ctx context.Context, key K) (val V, err error) {
+	if v, ok := c.data.Load(key); ok {
+		e := v.(*entry[V])
+		if !e.negativeExpired(time.Now()) {
+			e.Touch()
+			c.touch(key)
+			c.incrCounter(MetricKeyHits, 1)
+			return e.Load(), e.err.Load()
+		}
 	}
+	c.incrCounter(MetricKeyMisses, 1)
 
-	val, err, _ = c.sfg.Do(key, func() (v interface{}, e error) {
-		v, e = c.opt.Fetcher(key)
-		ety := &entry{}
+	fetchCtx := context.WithoutCancel(ctx)
+	ch := c.sfg.DoChan(c.flightKey(key), func() (interface{}, error) {
+		v, interval, e := c.load(fetchCtx, key)
+		ety := &entry[V]{}
 		ety.Store(v, e)
-		c.data.Store(key, ety)
-		return
+		ety.SetInterval(interval)
+		c.markNegative(ety, e)
+		c.store(key, ety)
+		return v, e
 	})
-	return
+	select {
+	case res := <-ch:
+		val, _ = res.Val.(V)
+		return val, res.Err
+	case <-ctx.Done():
+		return val, ctx.Err()
+	}
 }
 
 // GetOrSet tries to fetch a value corresponding to the given key from the cache.
 // If the key is not yet cached or fetching failed, the default value will be set.
-func (c *asyncCache) GetOrSet(key string, def interface{}) (val interface{}) {
+func (c *typedAsyncCache[K, V]) GetOrSet(key K, def V) (val V) {
 	if v, ok := c.data.Load(key); ok {
-		e := v.(*entry)
+		e := v.(*entry[V])
 		if e.err.Load() != nil {
-			ety := &entry{}
+			ety := &entry[V]{}
 			ety.Store(def, nil)
-			c.data.Store(key, ety)
+			c.store(key, ety)
 			return def
 		}
 		e.Touch()
-		return e.val.Load()
+		c.touch(key)
+		c.incrCounter(MetricKeyHits, 1)
+		return e.Load()
 	}
+	c.incrCounter(MetricKeyMisses, 1)
 
-	val, _, _ = c.sfg.Do(key, func() (interface{}, error) {
-		v, e := c.opt.Fetcher(key)
+	v, _, _ := c.sfg.Do(c.flightKey(key), func() (interface{}, error) {
+		v, interval, e := c.load(context.Background(), key)
 		if e != nil {
 			v = def
 		}
-		ety := &entry{}
+		ety := &entry[V]{}
 		ety.Store(v, nil)
-		c.data.Store(key, ety)
+		ety.SetInterval(interval)
+		c.store(key, ety)
 		return v, nil
 	})
+	val, _ = v.(V)
 	return
 }
 
 // Dump dumps all cached entries.
-func (c *asyncCache) Dump() map[string]interface{} {
-	data := make(map[string]interface{})
+func (c *typedAsyncCache[K, V]) Dump() map[K]V {
+	data := make(map[K]V)
 	c.data.Range(func(key, val interface{}) bool {
-		k, ok := key.(string)
+		k, ok := key.(K)
 		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not string", k, k))
+			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not %T", key, key, k))
 			c.data.Delete(key)
 			return true
 		}
-		data[k] = val.(*entry).val.Load()
+		data[k] = val.(*entry[V]).Load()
 		return true
 	})
 	return data
 }
 
 // DeleteIf deletes cached entries that match the `shouldDelete` predicate.
-func (c *asyncCache) DeleteIf(shouldDelete func(key string) bool) {
+func (c *typedAsyncCache[K, V]) DeleteIf(shouldDelete func(key K) bool) {
 	c.data.Range(func(key, value interface{}) bool {
-		s := key.(string)
-		if shouldDelete(s) {
+		k := key.(K)
+		if shouldDelete(k) {
 			if c.opt.DeleteHandler != nil {
-				go c.opt.DeleteHandler(s, value)
+				go c.opt.DeleteHandler(k, value.(*entry[V]).Load())
 			}
-			c.data.Delete(key)
+			c.remove(k)
 		}
 		return true
 	})
 }
 
+// Stats returns the counters of the cache.
+func (c *typedAsyncCache[K, V]) Stats() Stats {
+	return Stats{
+		Entries:   int(c.size.Load()),
+		Evictions: c.evictions.Load(),
+	}
+}
+
 // Close stops the background goroutine.
-func (c *asyncCache) Close() {
-	// close refresh ticker
-	ti, _ := refreshTickerMap.Load(c.opt.RefreshDuration)
-	rt := ti.(*sharedTicker)
-	rt.Lock()
-	delete(rt.caches, c)
-	if len(rt.caches) == 0 {
-		rt.stopChan <- true
-		rt.started = false
-	}
-	rt.Unlock()
+func (c *typedAsyncCache[K, V]) Close() {
+	if c.sched != nil {
+		c.sched.close()
+	} else {
+		// close refresh ticker
+		ti, _ := refreshTickerMap.Load(c.opt.RefreshDuration)
+		rt := ti.(*sharedTicker)
+		rt.Lock()
+		delete(rt.caches, c)
+		if len(rt.caches) == 0 {
+			rt.stopChan <- true
+			rt.started = false
+		}
+		rt.Unlock()
+	}
 
 	if c.opt.EnableExpire {
 		// close expire ticker
@@ -259,6 +494,114 @@ func (c *asyncCache) Close() {
 		}
 		et.Unlock()
 	}
+
+	if c.opt.SnapshotPath != "" {
+		if c.snapStop != nil {
+			close(c.snapStop)
+			<-c.snapDone
+		}
+		if err := c.saveSnapshot(); err != nil {
+			c.opt.ErrLogFunc(fmt.Sprintf("asynccache: save snapshot %s error: %v", c.opt.SnapshotPath, err))
+		}
+	}
+}
+
+// store saves ety under key, evicting other entries first if the cache is full.
+func (c *typedAsyncCache[K, V]) store(key K, ety *entry[V]) {
+	if c.evictor != nil {
+		c.mu.Lock()
+	}
+	if _, loaded := c.data.Swap(key, ety); !loaded {
+		c.size.Add(1)
+	}
+	if c.evictor != nil {
+		c.admit(key)
+		c.mu.Unlock()
+	}
+	if c.sched != nil {
+		c.sched.schedule(key, ety)
+	}
+}
+
+// remove deletes key from the cache without calling DeleteHandler.
+func (c *typedAsyncCache[K, V]) remove(key K) {
+	if c.evictor != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.evictor.remove(key)
+	}
+	if _, loaded := c.data.LoadAndDelete(key); loaded {
+		c.size.Add(-1)
+	}
+}
+
+// touch records an access to key for the eviction policy.
+func (c *typedAsyncCache[K, V]) touch(key K) {
+	if c.evictor == nil {
+		return
+	}
+	c.mu.Lock()
+	c.evictor.touch(key)
+	c.mu.Unlock()
+}
+
+// admit tracks a key that has just been stored, evicting victims while the cache is full.
+// c.mu must be held.
+func (c *typedAsyncCache[K, V]) admit(key K) {
+	if c.evictor.contains(key) {
+		c.evictor.touch(key)
+		return
+	}
+	for c.evictor.len() >= c.opt.MaxEntries {
+		victim, ok := c.evictor.victim()
+		if !ok {
+			break
+		}
+		c.evictor.remove(victim)
+		if v, loaded := c.data.LoadAndDelete(victim); loaded {
+			c.size.Add(-1)
+			if c.opt.DeleteHandler != nil {
+				go c.opt.DeleteHandler(victim, v.(*entry[V]).Load())
+			}
+		}
+		c.evictions.Add(1)
+		c.incrCounter(MetricKeyEvictions, 1)
+	}
+	c.evictor.add(key)
+}
+
+// fetch loads a single key through IntervalFetcher, ContextFetcher or Fetcher,
+// falling back to BatchFetcher if none of them is set.
+func (c *typedAsyncCache[K, V]) fetch(ctx context.Context, key K) (v V, interval time.Duration, err error) {
+	if c.opt.IntervalFetcher == nil && c.opt.ContextFetcher == nil && c.opt.Fetcher == nil {
+		vals, errs := c.batchFetch([]K{key})
+		v, err = batchResult(key, vals, errs)
+		return
+	}
+
+	defer c.measureFetch(time.Now())
+	switch {
+	case c.opt.IntervalFetcher != nil:
+		v, interval, err = c.opt.IntervalFetcher(key)
+	case c.opt.ContextFetcher != nil:
+		v, err = c.opt.ContextFetcher(ctx, key)
+	default:
+		v, err = c.opt.Fetcher(key)
+	}
+	if err != nil {
+		c.incrCounter(MetricKeyFetchErrors, 1)
+	} else {
+		c.remoteSet(ctx, key, v)
+	}
+	return
+}
+
+// flightKey converts key to the string used by the singleflight group.
+func (c *typedAsyncCache[K, V]) flightKey(key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
 }
 
 // tick .
@@ -272,7 +615,7 @@ func (t *sharedTicker) tick(ticker *time.Ticker, tt tickerType) {
 			t.Lock()
 			for c := range t.caches {
 				wg.Add(1)
-				go func(c *asyncCache) {
+				go func(c tickable) {
 					defer wg.Done()
 					if tt == expireTicker {
 						c.expire()
@@ -291,15 +634,16 @@ func (t *sharedTicker) tick(ticker *time.Ticker, tt tickerType) {
 	}
 }
 
-func (c *asyncCache) expire() {
+func (c *typedAsyncCache[K, V]) expire() {
+	defer c.reportSize()
 	c.data.Range(func(key, value interface{}) bool {
-		k, ok := key.(string)
+		k, ok := key.(K)
 		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not string", k, k))
+			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not %T", key, key, k))
 			c.data.Delete(key)
 			return true
 		}
-		e, ok := value.(*entry)
+		e, ok := value.(*entry[V])
 		if !ok {
 			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not entry", k, value))
 			c.data.Delete(key)
@@ -307,48 +651,74 @@ func (c *asyncCache) expire() {
 		}
 		if !atomic.CompareAndSwapInt32(&e.expire, 0, 1) {
 			if c.opt.DeleteHandler != nil {
-				go c.opt.DeleteHandler(k, value)
+				go c.opt.DeleteHandler(k, e.Load())
 			}
-			c.data.Delete(key)
+			c.remove(k)
+			c.incrCounter(MetricKeyExpirations, 1)
 		}
 
 		return true
 	})
 }
 
-func (c *asyncCache) refresh() {
+func (c *typedAsyncCache[K, V]) refresh() {
+	defer c.reportSize()
+	if c.opt.BatchFetcher != nil {
+		c.refreshBatch()
+		return
+	}
 	c.data.Range(func(key, value interface{}) bool {
-		k, ok := key.(string)
+		k, e, ok := c.loadEntry(key, value)
 		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not string", k, k))
-			c.data.Delete(key)
 			return true
 		}
-		e, ok := value.(*entry)
-		if !ok {
-			c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not entry", k, value))
-			c.data.Delete(key)
+		if e.negative(time.Now()) || c.followRemote(k, e) {
 			return true
 		}
+		newVal, interval, err := c.fetch(context.Background(), k)
+		if err == nil {
+			e.SetInterval(interval)
+		}
+		c.update(k, e, newVal, err)
+		return true
+	})
+}
 
-		newVal, err := c.opt.Fetcher(k)
-		if err != nil {
-			if c.opt.ErrorHandler != nil {
-				go c.opt.ErrorHandler(k, err)
-			}
-			if e.err.Load() != nil {
-				e.err.Store(err)
-			}
-			return true
+// loadEntry type checks a key/value pair ranged from c.data, dropping invalid ones.
+func (c *typedAsyncCache[K, V]) loadEntry(key, value interface{}) (K, *entry[V], bool) {
+	k, ok := key.(K)
+	if !ok {
+		c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not %T", key, key, k))
+		c.data.Delete(key)
+		return k, nil, false
+	}
+	e, ok := value.(*entry[V])
+	if !ok {
+		c.opt.ErrLogFunc(fmt.Sprintf("invalid key: %v, type: %T is not entry", k, value))
+		c.data.Delete(key)
+		return k, nil, false
+	}
+	return k, e, true
+}
+
+// update applies the result of a refresh fetch to e.
+func (c *typedAsyncCache[K, V]) update(k K, e *entry[V], newVal V, err error) {
+	c.incrCounter(MetricKeyRefreshes, 1)
+	if err != nil {
+		if c.opt.ErrorHandler != nil {
+			go c.opt.ErrorHandler(k, err)
+		}
+		if e.err.Load() != nil || c.markNegative(e, err) || c.tooStale(e, time.Now()) {
+			e.err.Store(err)
 		}
+		return
+	}
 
-		if c.opt.IsSame != nil && !c.opt.IsSame(k, e.val.Load(), newVal) {
-			if c.opt.ChangeHandler != nil {
-				go c.opt.ChangeHandler(k, e.val.Load(), newVal)
-			}
+	if c.opt.IsSame != nil && !c.opt.IsSame(k, e.Load(), newVal) {
+		if c.opt.ChangeHandler != nil {
+			go c.opt.ChangeHandler(k, e.Load(), newVal)
 		}
+	}
 
-		e.Store(newVal, err)
-		return true
-	})
+	e.Store(newVal, err)
 }
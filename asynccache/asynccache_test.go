@@ -1,10 +1,21 @@
 package asynccache
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/go-metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -257,6 +268,497 @@ func TestExpire(t *testing.T) {
 	assert.True(t, trigger)
 }
 
+func TestTypedCache(t *testing.T) {
+	type price struct {
+		Symbol string
+		Value  float64
+	}
+	var deleted []int
+	var mu sync.Mutex
+	op := TypedOptions[int, *price]{
+		RefreshDuration: time.Minute,
+		IsSame: func(key int, oldData, newData *price) bool {
+			return oldData.Value == newData.Value
+		},
+		Fetcher: func(key int) (*price, error) {
+			if key < 0 {
+				return nil, errors.New("error")
+			}
+			return &price{Symbol: "T", Value: float64(key)}, nil
+		},
+		DeleteHandler: func(key int, oldData *price) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, key)
+		},
+	}
+	c := NewTypedAsyncCache(op)
+	defer c.Close()
+
+	v, err := c.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, v.Value)
+
+	v, err = c.Get(-1)
+	assert.Error(t, err)
+	assert.Nil(t, v)
+
+	v = c.GetOrSet(-2, &price{Symbol: "D"})
+	assert.Equal(t, "D", v.Symbol)
+
+	assert.False(t, c.SetDefault(3, &price{Symbol: "S", Value: 3}))
+	dump := c.Dump()
+	assert.Len(t, dump, 4)
+	assert.Equal(t, "S", dump[3].Symbol)
+
+	c.DeleteIf(func(key int) bool { return key == 3 })
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []int{3}, deleted)
+	mu.Unlock()
+	assert.Len(t, c.Dump(), 3)
+}
+
+func TestMaxEntriesLRU(t *testing.T) {
+	var evicted []string
+	var mu sync.Mutex
+	op := TypedOptions[string, string]{
+		RefreshDuration: time.Minute,
+		MaxEntries:      2,
+		EvictionPolicy:  EvictLRU,
+		Fetcher: func(key string) (string, error) {
+			return key, nil
+		},
+		DeleteHandler: func(key string, oldData string) {
+			mu.Lock()
+			defer mu.Unlock()
+			evicted = append(evicted, key)
+		},
+	}
+	c := NewTypedAsyncCache(op)
+	defer c.Close()
+
+	c.Get("a")
+	c.Get("b")
+	c.Get("a") // b is now the least recently used
+	c.Get("c")
+	assert.Equal(t, Stats{Entries: 2, Evictions: 1}, c.Stats())
+	dump := c.Dump()
+	assert.Contains(t, dump, "a")
+	assert.Contains(t, dump, "c")
+
+	c.SetDefault("d", "d")
+	assert.Equal(t, Stats{Entries: 2, Evictions: 2}, c.Stats())
+	assert.NotContains(t, c.Dump(), "a")
+
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"b", "a"}, evicted)
+	mu.Unlock()
+}
+
+func TestMaxEntriesLFU(t *testing.T) {
+	op := TypedOptions[string, string]{
+		RefreshDuration: time.Minute,
+		MaxEntries:      2,
+		EvictionPolicy:  EvictLFU,
+		Fetcher: func(key string) (string, error) {
+			return key, nil
+		},
+	}
+	c := NewTypedAsyncCache(op)
+	defer c.Close()
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("b")
+	c.Get("c") // b is used less than a
+	dump := c.Dump()
+	assert.Contains(t, dump, "a")
+	assert.Contains(t, dump, "c")
+	assert.NotContains(t, dump, "b")
+
+	c.Get("c")
+	c.Get("c")
+	c.Get("c")
+	c.Get("d") // a is now used less than c
+	dump = c.Dump()
+	assert.Contains(t, dump, "c")
+	assert.Contains(t, dump, "d")
+	assert.Equal(t, uint64(2), c.Stats().Evictions)
+
+	c.DeleteIf(func(key string) bool { return key == "c" })
+	assert.Equal(t, Stats{Entries: 1, Evictions: 2}, c.Stats())
+}
+
+func TestBatchFetcher(t *testing.T) {
+	var calls [][]string
+	var errs []error
+	var mu sync.Mutex
+	version := 1
+	op := TypedOptions[string, int]{
+		RefreshDuration: time.Minute,
+		BatchSize:       2,
+		BatchFetcher: func(keys []string) (map[string]int, map[string]error) {
+			calls = append(calls, append([]string(nil), keys...))
+			vals := make(map[string]int)
+			errs := make(map[string]error)
+			for _, k := range keys {
+				switch k {
+				case "bad":
+					errs[k] = errors.New("error")
+				case "missing":
+				default:
+					vals[k] = version
+				}
+			}
+			return vals, errs
+		},
+		ErrorHandler: func(key string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	}
+	c := NewTypedAsyncCache(op).(*typedAsyncCache[string, int])
+	defer c.Close()
+
+	v, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	_, err = c.Get("bad")
+	assert.Error(t, err)
+	c.SetDefault("b", 0)
+	c.SetDefault("missing", 7)
+	assert.Len(t, calls, 2)
+
+	calls = nil
+	version = 2
+	c.refresh()
+	assert.Len(t, calls, 2)
+	assert.Len(t, calls[0], 2)
+	assert.Len(t, calls[1], 2)
+	assert.Equal(t, map[string]int{"a": 2, "b": 2, "bad": 0, "missing": 7}, c.Dump())
+
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs, ErrMissingFromBatch)
+	mu.Unlock()
+}
+
+func TestPerKeyRefresh(t *testing.T) {
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	running, maxRunning := 0, 0
+	op := TypedOptions[string, int]{
+		RefreshDuration:       100 * time.Millisecond,
+		EnablePerKeyRefresh:   true,
+		RefreshJitter:         20 * time.Millisecond,
+		MaxRefreshConcurrency: 2,
+		IntervalFetcher: func(key string) (int, time.Duration, error) {
+			mu.Lock()
+			counts[key]++
+			n := counts[key]
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if key == "fast" {
+				return n, 20 * time.Millisecond, nil
+			}
+			return n, 0, nil
+		},
+	}
+	c := NewTypedAsyncCache(op)
+
+	c.Get("fast")
+	for _, k := range []string{"a", "b", "c", "d"} {
+		c.Get(k)
+	}
+	time.Sleep(250 * time.Millisecond)
+	c.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.GreaterOrEqual(t, counts["fast"], 6)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		assert.GreaterOrEqual(t, counts[k], 2)
+		assert.LessOrEqual(t, counts[k], 3)
+	}
+	assert.LessOrEqual(t, maxRunning, 2)
+}
+
+func TestPerKeyRefreshDropsDeleted(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []string
+	op := TypedOptions[string, string]{
+		RefreshDuration:     20 * time.Millisecond,
+		EnablePerKeyRefresh: true,
+		Fetcher: func(key string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			fetched = append(fetched, key)
+			return key, nil
+		},
+	}
+	c := NewTypedAsyncCache(op)
+	defer c.Close()
+
+	c.SetDefault("a", "a")
+	c.SetDefault("b", "b")
+	c.DeleteIf(func(key string) bool { return key == "b" })
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, fetched, "a")
+	assert.NotContains(t, fetched, "b")
+}
+
+func TestGetCtx(t *testing.T) {
+	type ctxKey struct{}
+	var calls atomic.Int32
+	op := TypedOptions[string, string]{
+		RefreshDuration: time.Minute,
+		ContextFetcher: func(ctx context.Context, key string) (string, error) {
+			calls.Add(1)
+			time.Sleep(100 * time.Millisecond)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return key + ctx.Value(ctxKey{}).(string), nil
+		},
+	}
+	c := NewTypedAsyncCache(op)
+	defer c.Close()
+
+	done := make(chan string)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		v, _ := c.Get("key")
+		done <- v
+	}()
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "-ctx"), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetCtx(ctx, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 80*time.Millisecond)
+
+	assert.Equal(t, "key-ctx", <-done)
+	v, err := c.GetCtx(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "key-ctx", v)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMaxStaleness(t *testing.T) {
+	fail := false
+	op := TypedOptions[string, string]{
+		RefreshDuration: time.Minute,
+		MaxStaleness:    50 * time.Millisecond,
+		Fetcher: func(key string) (string, error) {
+			if fail {
+				return "", errors.New("error")
+			}
+			return "ok", nil
+		},
+	}
+	c := NewTypedAsyncCache(op).(*typedAsyncCache[string, string])
+	defer c.Close()
+
+	v, err := c.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", v)
+	age, ok := c.Age("key")
+	assert.True(t, ok)
+	assert.Less(t, age, 50*time.Millisecond)
+
+	fail = true
+	c.refresh()
+	v, err = c.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", v)
+
+	time.Sleep(60 * time.Millisecond)
+	c.refresh()
+	_, err = c.Get("key")
+	assert.Error(t, err)
+	age, ok = c.Age("key")
+	assert.True(t, ok)
+	assert.Greater(t, age, 50*time.Millisecond)
+
+	fail = false
+	c.refresh()
+	v, err = c.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", v)
+
+	_, ok = c.Age("missing")
+	assert.False(t, ok)
+}
+
+func TestNegativeTTL(t *testing.T) {
+	errNotFound := errors.New("not found")
+	var calls int
+	op := TypedOptions[string, string]{
+		RefreshDuration: time.Minute,
+		NegativeTTL:     map[error]time.Duration{errNotFound: 50 * time.Millisecond},
+		Fetcher: func(key string) (string, error) {
+			calls++
+			if calls == 1 {
+				return "", fmt.Errorf("token %s: %w", key, errNotFound)
+			}
+			return "found", nil
+		},
+	}
+	c := NewTypedAsyncCache(op).(*typedAsyncCache[string, string])
+	defer c.Close()
+
+	_, err := c.Get("key")
+	assert.ErrorIs(t, err, errNotFound)
+	_, ok := c.Age("key")
+	assert.False(t, ok)
+
+	// negative entries are neither refreshed nor fetched again before the TTL passes
+	c.refresh()
+	_, err = c.Get("key")
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Equal(t, 1, calls)
+
+	time.Sleep(60 * time.Millisecond)
+	v, err := c.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "found", v)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	type price struct {
+		Symbol string
+		Value  float64
+	}
+	op := TypedOptions[int, price]{
+		RefreshDuration: time.Minute,
+		Fetcher: func(key int) (price, error) {
+			if key < 0 {
+				return price{}, errors.New("error")
+			}
+			return price{Symbol: "T", Value: float64(key)}, nil
+		},
+	}
+	for _, codec := range []Codec[int, price]{JSONCodec[int, price]{}, GobCodec[int, price]{}} {
+		op.SnapshotCodec = codec
+		c := NewTypedAsyncCache(op)
+		c.Get(1)
+		c.Get(2)
+		c.Get(-1)
+
+		var buf bytes.Buffer
+		assert.NoError(t, c.Snapshot(&buf))
+		c.Close()
+
+		restored := NewTypedAsyncCache(op)
+		restored.SetDefault(2, price{Symbol: "kept"})
+		assert.NoError(t, restored.Restore(&buf))
+		assert.Equal(t, map[int]price{
+			1: {Symbol: "T", Value: 1},
+			2: {Symbol: "kept"},
+		}, restored.Dump())
+		restored.Close()
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	var calls atomic.Int32
+	op := TypedOptions[string, string]{
+		RefreshDuration:  time.Minute,
+		SnapshotPath:     path,
+		SnapshotInterval: 20 * time.Millisecond,
+		Fetcher: func(key string) (string, error) {
+			calls.Add(1)
+			return "v-" + key, nil
+		},
+	}
+	c := NewTypedAsyncCache(op)
+	c.Get("a")
+	time.Sleep(50 * time.Millisecond)
+	_, err := os.Stat(path)
+	assert.NoError(t, err)
+	c.Get("b")
+	c.Close()
+
+	warm := NewTypedAsyncCache(op)
+	defer warm.Close()
+	v, err := warm.Get("b")
+	assert.NoError(t, err)
+	assert.Equal(t, "v-b", v)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMetrics(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	conf := metrics.DefaultConfig("")
+	conf.EnableHostname = false
+	conf.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(conf, sink)
+	assert.NoError(t, err)
+	defer metrics.NewGlobal(conf, &metrics.BlackholeSink{})
+
+	op := TypedOptions[string, string]{
+		Name:            "prices",
+		RefreshDuration: time.Minute,
+		EnableExpire:    true,
+		ExpireDuration:  time.Minute,
+		Fetcher: func(key string) (string, error) {
+			if key == "bad" {
+				return "", errors.New("error")
+			}
+			return key, nil
+		},
+	}
+	c := NewTypedAsyncCache(op).(*typedAsyncCache[string, string])
+	defer c.Close()
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("bad")
+	c.refresh()
+	c.expire()
+	c.expire()
+
+	intervals := sink.Data()
+	assert.NotEmpty(t, intervals)
+	counters := make(map[string]int)
+	for _, it := range intervals {
+		it.RLock()
+		for name, v := range it.Counters {
+			counters[name] += v.Count
+		}
+		it.RUnlock()
+	}
+	assert.Equal(t, 1, counters["asynccache.hits;cache=prices"])
+	assert.Equal(t, 2, counters["asynccache.misses;cache=prices"])
+	assert.Equal(t, 2, counters["asynccache.fetch_errors;cache=prices"])
+	assert.Equal(t, 2, counters["asynccache.refreshes;cache=prices"])
+	assert.Equal(t, 2, counters["asynccache.expirations;cache=prices"])
+
+	last := intervals[len(intervals)-1]
+	last.RLock()
+	defer last.RUnlock()
+	assert.Equal(t, float32(0), last.Gauges["asynccache.size;cache=prices"].Value)
+	assert.Equal(t, 4, last.Samples["asynccache.fetch_duration;cache=prices"].Count)
+}
+
 func BenchmarkGet(b *testing.B) {
 	var key = "key"
 	op := Options{
@@ -384,3 +886,150 @@ func BenchmarkRefreshParallel(b *testing.B) {
 		}
 	})
 }
+
+func TestRemoteStore(t *testing.T) {
+	store := NewMemoryStore()
+	var calls atomic.Int32
+	var version atomic.Int32
+	op := TypedOptions[string, string]{
+		RefreshDuration: time.Minute,
+		Remote:          store,
+		RemotePrefix:    "test:",
+		Fetcher: func(key string) (string, error) {
+			calls.Add(1)
+			return fmt.Sprintf("%s-%d", key, version.Load()), nil
+		},
+	}
+	c1 := NewTypedAsyncCache(op).(*typedAsyncCache[string, string])
+	defer c1.Close()
+	c2 := NewTypedAsyncCache(op).(*typedAsyncCache[string, string])
+	defer c2.Close()
+
+	v, err := c1.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a-0", v)
+	// c2 reads the value written by c1
+	v, err = c2.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a-0", v)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// only the lease holder fetches, the other replica follows the remote value
+	version.Store(1)
+	c1.refresh()
+	c2.refresh()
+	assert.Equal(t, int32(2), calls.Load())
+	v, _ = c2.Get("a")
+	assert.Equal(t, "a-1", v)
+
+	b, ok, err := store.Get(context.Background(), "test:a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `"a-1"`, string(b))
+}
+
+// serveRedis is a minimal stand-in of a Redis server handling GET, SET [PX ms] [NX] and DEL.
+func serveRedis(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	store := NewMemoryStore()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					reply, err := readReply(rd)
+					if err != nil {
+						return
+					}
+					var args []string
+					for _, arg := range reply.([]interface{}) {
+						args = append(args, string(arg.([]byte)))
+					}
+					ctx := context.Background()
+					switch args[0] {
+					case "GET":
+						if b, ok, _ := store.Get(ctx, args[1]); ok {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(b), b)
+						} else {
+							fmt.Fprint(conn, "$-1\r\n")
+						}
+					case "SET":
+						var ttl time.Duration
+						nx := false
+						for i := 3; i < len(args); i++ {
+							switch args[i] {
+							case "PX":
+								ms, _ := strconv.Atoi(args[i+1])
+								ttl = time.Duration(ms) * time.Millisecond
+								i++
+							case "NX":
+								nx = true
+							}
+						}
+						ok := true
+						if nx {
+							ok, _ = store.SetNX(ctx, args[1], []byte(args[2]), ttl)
+						} else {
+							store.Set(ctx, args[1], []byte(args[2]), ttl)
+						}
+						if ok {
+							fmt.Fprint(conn, "+OK\r\n")
+						} else {
+							fmt.Fprint(conn, "$-1\r\n")
+						}
+					case "DEL":
+						store.Del(ctx, args[1])
+						fmt.Fprint(conn, ":1\r\n")
+					default:
+						fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRedisStore(t *testing.T) {
+	s := NewRedisStore(RedisOptions{Addr: serveRedis(t)})
+	defer s.Close()
+	ctx := context.Background()
+
+	_, ok, err := s.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Set(ctx, "k", []byte("v"), time.Minute))
+	b, ok, err := s.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v", string(b))
+
+	ok, err = s.SetNX(ctx, "k", []byte("w"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = s.SetNX(ctx, "lease", []byte("w"), 20*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	time.Sleep(30 * time.Millisecond)
+	_, ok, _ = s.Get(ctx, "lease")
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Del(ctx, "k"))
+	_, ok, _ = s.Get(ctx, "k")
+	assert.False(t, ok)
+
+	var redisErr RedisError
+	_, err = s.do(ctx, "PING")
+	assert.ErrorAs(t, err, &redisErr)
+	// the connection is still usable after an error reply
+	assert.NoError(t, s.Set(ctx, "k", []byte("v"), 0))
+}
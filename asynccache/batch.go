@@ -0,0 +1,84 @@
+package asynccache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultBatchSize is the number of keys passed to BatchFetcher at once if BatchSize is not set.
+const DefaultBatchSize = 100
+
+// ErrMissingFromBatch is reported to ErrorHandler when BatchFetcher returns neither
+// a value nor an error for a requested key. The cached value of the key is kept.
+var ErrMissingFromBatch = errors.New("asynccache: key missing from batch result")
+
+// refreshBatch refreshes all cached entries through BatchFetcher, BatchSize keys at a time.
+func (c *typedAsyncCache[K, V]) refreshBatch() {
+	keys := make([]K, 0, c.opt.BatchSize)
+	entries := make([]*entry[V], 0, c.opt.BatchSize)
+	flush := func() {
+		if len(keys) == 0 {
+			return
+		}
+		vals, errs := c.batchFetch(keys)
+		for i, k := range keys {
+			newVal, err := batchResult(k, vals, errs)
+			c.update(k, entries[i], newVal, err)
+		}
+		// BatchFetcher may keep the slice, so do not reuse it.
+		keys = make([]K, 0, c.opt.BatchSize)
+		entries = make([]*entry[V], 0, c.opt.BatchSize)
+	}
+
+	now := time.Now()
+	c.data.Range(func(key, value interface{}) bool {
+		k, e, ok := c.loadEntry(key, value)
+		if !ok || e.negative(now) || c.followRemote(k, e) {
+			return true
+		}
+		keys = append(keys, k)
+		entries = append(entries, e)
+		if len(keys) >= c.opt.BatchSize {
+			flush()
+		}
+		return true
+	})
+	flush()
+}
+
+// batchFetch calls BatchFetcher and records its metrics.
+func (c *typedAsyncCache[K, V]) batchFetch(keys []K) (map[K]V, map[K]error) {
+	start := time.Now()
+	vals, errs := c.opt.BatchFetcher(keys)
+	c.measureFetch(start)
+	if c.opt.Remote != nil {
+		for _, k := range keys {
+			if v, err := batchResult(k, vals, errs); err == nil {
+				c.remoteSet(context.Background(), k, v)
+			}
+		}
+	}
+	if c.labels != nil {
+		var failed int
+		for _, k := range keys {
+			if _, err := batchResult(k, vals, errs); err != nil {
+				failed++
+			}
+		}
+		c.incrCounter(MetricKeyFetchErrors, float32(failed))
+	}
+	return vals, errs
+}
+
+func batchResult[K comparable, V any](key K, vals map[K]V, errs map[K]error) (V, error) {
+	if err, ok := errs[key]; ok && err != nil {
+		var zero V
+		return zero, err
+	}
+	if v, ok := vals[key]; ok {
+		return v, nil
+	}
+	var zero V
+	return zero, ErrMissingFromBatch
+}
@@ -0,0 +1,172 @@
+package asynccache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// EvictionPolicy decides which entry is dropped once MaxEntries is reached.
+type EvictionPolicy int
+
+const (
+	// EvictLRU drops the least recently used entry.
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU drops the least frequently used entry, ties are broken by recency.
+	EvictLFU
+)
+
+// evictor tracks key usage for a bounded cache.
+// It is not safe for concurrent use, callers hold asyncCache.mu.
+type evictor[K comparable] interface {
+	add(key K)
+	contains(key K) bool
+	touch(key K)
+	remove(key K)
+	victim() (K, bool)
+	len() int
+}
+
+func newEvictor[K comparable](policy EvictionPolicy) evictor[K] {
+	if policy == EvictLFU {
+		return &lfu[K]{items: make(map[K]*lfuItem[K])}
+	}
+	return &lru[K]{ll: list.New(), items: make(map[K]*list.Element)}
+}
+
+type lru[K comparable] struct {
+	ll    *list.List
+	items map[K]*list.Element
+}
+
+func (l *lru[K]) add(key K) {
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(key)
+}
+
+func (l *lru[K]) contains(key K) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+func (l *lru[K]) touch(key K) {
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+	}
+}
+
+func (l *lru[K]) remove(key K) {
+	if el, ok := l.items[key]; ok {
+		l.ll.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru[K]) victim() (K, bool) {
+	el := l.ll.Back()
+	if el == nil {
+		var zero K
+		return zero, false
+	}
+	return el.Value.(K), true
+}
+
+func (l *lru[K]) len() int {
+	return l.ll.Len()
+}
+
+type lfuItem[K comparable] struct {
+	key   K
+	freq  uint64
+	seq   uint64
+	index int
+}
+
+// lfuHeap is a min-heap ordered by frequency, then by last access.
+type lfuHeap[K comparable] []*lfuItem[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K]) Push(x interface{}) {
+	it := x.(*lfuItem[K])
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *lfuHeap[K]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return it
+}
+
+type lfu[K comparable] struct {
+	h     lfuHeap[K]
+	items map[K]*lfuItem[K]
+	seq   uint64
+}
+
+func (l *lfu[K]) add(key K) {
+	if _, ok := l.items[key]; ok {
+		l.touch(key)
+		return
+	}
+	l.seq++
+	it := &lfuItem[K]{key: key, freq: 1, seq: l.seq}
+	l.items[key] = it
+	heap.Push(&l.h, it)
+}
+
+func (l *lfu[K]) contains(key K) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+func (l *lfu[K]) touch(key K) {
+	it, ok := l.items[key]
+	if !ok {
+		return
+	}
+	l.seq++
+	it.freq++
+	it.seq = l.seq
+	heap.Fix(&l.h, it.index)
+}
+
+func (l *lfu[K]) remove(key K) {
+	it, ok := l.items[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&l.h, it.index)
+	delete(l.items, key)
+}
+
+func (l *lfu[K]) victim() (K, bool) {
+	if len(l.h) == 0 {
+		var zero K
+		return zero, false
+	}
+	return l.h[0].key, true
+}
+
+func (l *lfu[K]) len() int {
+	return len(l.h)
+}
@@ -0,0 +1,54 @@
+package asynccache
+
+import (
+	"time"
+
+	"github.com/dexerlab/utils-go/telemetry"
+	"github.com/hashicorp/go-metrics"
+)
+
+// Metric keys emitted by a cache with a Name, all labelled with MetricLabelNameCache.
+const (
+	MetricKeyAsyncCache   = "asynccache"
+	MetricLabelNameCache  = "cache"
+	MetricKeyHits         = "hits"
+	MetricKeyMisses       = "misses"
+	MetricKeyFetchErrors  = "fetch_errors"
+	MetricKeyRefreshes    = "refreshes"
+	MetricKeyExpirations  = "expirations"
+	MetricKeyEvictions    = "evictions"
+	MetricKeySize         = "size"
+	MetricKeyFetchLatency = "fetch_duration"
+)
+
+func (c *typedAsyncCache[K, V]) incrCounter(key string, val float32) {
+	if c.labels == nil || val == 0 {
+		return
+	}
+	telemetry.IncrCounterWithLabels([]string{MetricKeyAsyncCache, key}, val, c.labels)
+}
+
+func (c *typedAsyncCache[K, V]) measureFetch(start time.Time) {
+	if c.labels == nil {
+		return
+	}
+	telemetry.MeasureSinceWithLabels([]string{MetricKeyAsyncCache, MetricKeyFetchLatency}, start, c.labels)
+}
+
+func (c *typedAsyncCache[K, V]) reportSize() {
+	if c.labels == nil {
+		return
+	}
+	telemetry.SetGaugeWithLabels([]string{MetricKeyAsyncCache, MetricKeySize}, float32(c.size.Load()), c.labels)
+}
+
+// newLabels returns the labels of a cache, or nil if metrics are disabled.
+// The slice is full so that the telemetry wrappers never append into it.
+func newLabels(name string) []metrics.Label {
+	if name == "" {
+		return nil
+	}
+	labels := make([]metrics.Label, 1)
+	labels[0] = telemetry.NewLabel(MetricLabelNameCache, name)
+	return labels
+}
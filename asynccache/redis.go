@@ -0,0 +1,244 @@
+package asynccache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisOptions configures a RedisStore.
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// DialTimeout and IOTimeout default to 5 and 3 seconds.
+	DialTimeout time.Duration
+	IOTimeout   time.Duration
+}
+
+// RedisStore is a RemoteStore speaking the Redis protocol (RESP) over a single connection.
+// Commands are serialized, the connection is dialed again after a network error.
+type RedisStore struct {
+	opt RedisOptions
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// RedisError is an error reply of the server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// NewRedisStore creates a RedisStore, the connection is dialed on first use.
+func NewRedisStore(opt RedisOptions) *RedisStore {
+	if opt.DialTimeout <= 0 {
+		opt.DialTimeout = 5 * time.Second
+	}
+	if opt.IOTimeout <= 0 {
+		opt.IOTimeout = 3 * time.Second
+	}
+	return &RedisStore{opt: opt}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return b, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	_, err := s.do(ctx, setArgs(key, val, ttl)...)
+	return err
+}
+
+func (s *RedisStore) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	reply, err := s.do(ctx, append(setArgs(key, val, ttl), "NX")...)
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (s *RedisStore) Del(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", key)
+	return err
+}
+
+// Close closes the connection.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.rd = nil, nil
+	return err
+}
+
+func setArgs(key string, val []byte, ttl time.Duration) []string {
+	args := []string{"SET", key, string(val)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	return args
+}
+
+// do sends a command and reads its reply.
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := s.roundTrip(ctx, args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		s.conn.Close()
+		s.conn, s.rd = nil, nil
+	}
+	return reply, err
+}
+
+func (s *RedisStore) dial(ctx context.Context) error {
+	d := net.Dialer{Timeout: s.opt.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", s.opt.Addr)
+	if err != nil {
+		return err
+	}
+	s.conn, s.rd = conn, bufio.NewReader(conn)
+
+	if s.opt.Password != "" {
+		_, err = s.roundTrip(ctx, []string{"AUTH", s.opt.Password})
+	}
+	if err == nil && s.opt.DB != 0 {
+		_, err = s.roundTrip(ctx, []string{"SELECT", strconv.Itoa(s.opt.DB)})
+	}
+	if err != nil {
+		conn.Close()
+		s.conn, s.rd = nil, nil
+	}
+	return err
+}
+
+func (s *RedisStore) roundTrip(ctx context.Context, args []string) (interface{}, error) {
+	deadline := time.Now().Add(s.opt.IOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err := s.conn.Write(encodeCommand(args)); err != nil {
+		return nil, err
+	}
+	return readReply(s.rd)
+}
+
+// encodeCommand encodes args as a RESP array of bulk strings.
+func encodeCommand(args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readReply reads a RESP reply: simple strings are returned as string, integers as int64,
+// bulk strings as []byte, arrays as []interface{} and nil bulk strings or arrays as nil.
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err = io.ReadFull(rd, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(rd)
+			var redisErr RedisError
+			if errors.As(err, &redisErr) {
+				// keep reading the array so the connection stays in sync
+				items[i] = redisErr
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
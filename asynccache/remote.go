@@ -0,0 +1,195 @@
+package asynccache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RemoteStore is a second level cache shared by the replicas of a service.
+type RemoteStore interface {
+	// Get returns the value of key, ok is false if key does not exist.
+	Get(ctx context.Context, key string) (val []byte, ok bool, err error)
+	// Set sets the value of key, it expires after ttl if ttl is positive.
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	// SetNX sets the value of key only if it does not exist and reports whether it was set.
+	// It is used to hold refresh leases.
+	SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error)
+	// Del deletes key.
+	Del(ctx context.Context, key string) error
+}
+
+// replicaID identifies this process in the refresh leases it holds.
+var replicaID = []byte(hostname() + ":" + strconv.Itoa(os.Getpid()))
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+func (c *typedAsyncCache[K, V]) remoteKey(key K) string {
+	return c.opt.RemotePrefix + c.flightKey(key)
+}
+
+func (c *typedAsyncCache[K, V]) leaseKey(key K) string {
+	return c.opt.RemotePrefix + c.flightKey(key) + ":lease"
+}
+
+// remoteGet reads key from Remote, ok is false if it is missing or unreadable.
+func (c *typedAsyncCache[K, V]) remoteGet(ctx context.Context, key K) (val V, ok bool) {
+	b, ok, err := c.opt.Remote.Get(ctx, c.remoteKey(key))
+	if err != nil {
+		c.opt.ErrLogFunc(fmt.Sprintf("asynccache: remote get %v error: %v", key, err))
+		return val, false
+	}
+	if !ok {
+		return val, false
+	}
+	if val, err = c.opt.RemoteUnmarshal(b); err != nil {
+		c.opt.ErrLogFunc(fmt.Sprintf("asynccache: remote unmarshal %v error: %v", key, err))
+		return val, false
+	}
+	return val, true
+}
+
+// remoteSet writes a freshly fetched value to Remote, errors are only logged.
+func (c *typedAsyncCache[K, V]) remoteSet(ctx context.Context, key K, val V) {
+	if c.opt.Remote == nil {
+		return
+	}
+	b, err := c.opt.RemoteMarshal(val)
+	if err != nil {
+		c.opt.ErrLogFunc(fmt.Sprintf("asynccache: remote marshal %v error: %v", key, err))
+		return
+	}
+	if err = c.opt.Remote.Set(ctx, c.remoteKey(key), b, c.opt.RemoteTTL); err != nil {
+		c.opt.ErrLogFunc(fmt.Sprintf("asynccache: remote set %v error: %v", key, err))
+	}
+}
+
+// load is used for the first fetching of a key: the value of Remote is used if present.
+func (c *typedAsyncCache[K, V]) load(ctx context.Context, key K) (V, time.Duration, error) {
+	if c.opt.Remote != nil {
+		if v, ok := c.remoteGet(ctx, key); ok {
+			return v, 0, nil
+		}
+	}
+	return c.fetch(ctx, key)
+}
+
+// followRemote tries to take the refresh lease of key. If another replica holds it,
+// e is updated from Remote and true is returned, so the caller must not fetch key.
+// If Remote fails, false is returned and the key is fetched as if Remote was not set.
+func (c *typedAsyncCache[K, V]) followRemote(key K, e *entry[V]) bool {
+	if c.opt.Remote == nil {
+		return false
+	}
+	ctx := context.Background()
+	interval := e.Interval()
+	if interval <= 0 {
+		interval = c.opt.RefreshDuration
+	}
+	// the lease expires slightly before the next refresh, so that its holder can take it again
+	acquired, err := c.opt.Remote.SetNX(ctx, c.leaseKey(key), replicaID, interval-interval/10)
+	if err != nil {
+		c.opt.ErrLogFunc(fmt.Sprintf("asynccache: remote lease %v error: %v", key, err))
+		return false
+	}
+	if acquired {
+		return false
+	}
+	if v, ok := c.remoteGet(ctx, key); ok {
+		c.update(key, e, v, nil)
+	}
+	return true
+}
+
+func jsonMarshal[V any](v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func jsonUnmarshal[V any](b []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// MemoryStore is a RemoteStore kept in memory.
+// It is meant for tests and for caches shared inside a single process.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data map[string]memoryItem
+}
+
+type memoryItem struct {
+	val    []byte
+	expire time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string]memoryItem)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.load(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), it.val...), true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(key, val, ttl)
+	return nil
+}
+
+func (s *MemoryStore) SetNX(_ context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.load(key); ok {
+		return false, nil
+	}
+	s.store(key, val, ttl)
+	return true, nil
+}
+
+func (s *MemoryStore) Del(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// load returns the item of key, deleting it if it has expired. s.mu must be held.
+func (s *MemoryStore) load(key string) (memoryItem, bool) {
+	it, ok := s.data[key]
+	if !ok {
+		return it, false
+	}
+	if !it.expire.IsZero() && !time.Now().Before(it.expire) {
+		delete(s.data, key)
+		return it, false
+	}
+	return it, true
+}
+
+// store sets the item of key. s.mu must be held.
+func (s *MemoryStore) store(key string, val []byte, ttl time.Duration) {
+	it := memoryItem{val: append([]byte(nil), val...)}
+	if ttl > 0 {
+		it.expire = time.Now().Add(ttl)
+	}
+	s.data[key] = it
+}
@@ -0,0 +1,185 @@
+package asynccache
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// scheduleItem is a pending refresh of a single entry.
+// It is dropped when popped if the key no longer maps to the same entry.
+type scheduleItem[K comparable, V any] struct {
+	key      K
+	ety      *entry[V]
+	deadline time.Time
+}
+
+type scheduleHeap[K comparable, V any] []scheduleItem[K, V]
+
+func (h scheduleHeap[K, V]) Len() int           { return len(h) }
+func (h scheduleHeap[K, V]) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+func (h scheduleHeap[K, V]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *scheduleHeap[K, V]) Push(x interface{}) {
+	*h = append(*h, x.(scheduleItem[K, V]))
+}
+
+func (h *scheduleHeap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = scheduleItem[K, V]{}
+	*h = old[:n-1]
+	return it
+}
+
+// scheduler refreshes every entry of a cache on its own deadline
+// instead of refreshing all of them on the shared ticker.
+type scheduler[K comparable, V any] struct {
+	c *typedAsyncCache[K, V]
+
+	mu    sync.Mutex
+	items scheduleHeap[K, V]
+
+	wake chan struct{}
+	stop chan struct{}
+	sem  chan struct{}
+}
+
+func newScheduler[K comparable, V any](c *typedAsyncCache[K, V]) *scheduler[K, V] {
+	return &scheduler[K, V]{
+		c:    c,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		sem:  make(chan struct{}, c.opt.MaxRefreshConcurrency),
+	}
+}
+
+// schedule plans the next refresh of ety.
+func (s *scheduler[K, V]) schedule(key K, ety *entry[V]) {
+	interval := ety.Interval()
+	if interval <= 0 {
+		interval = s.c.opt.RefreshDuration
+	}
+	if s.c.opt.RefreshJitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(s.c.opt.RefreshJitter)))
+	}
+	deadline := time.Now().Add(interval)
+	if until := ety.negUntil.Load(); until > deadline.UnixNano() {
+		deadline = time.Unix(0, until)
+	}
+
+	s.mu.Lock()
+	heap.Push(&s.items, scheduleItem[K, V]{key: key, ety: ety, deadline: deadline})
+	first := s.items[0].ety == ety
+	s.mu.Unlock()
+
+	if first {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// due pops the items whose deadline has passed and returns how long to wait for the next one.
+func (s *scheduler[K, V]) due(now time.Time) ([]scheduleItem[K, V], time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []scheduleItem[K, V]
+	for len(s.items) > 0 && !s.items[0].deadline.After(now) {
+		it := heap.Pop(&s.items).(scheduleItem[K, V])
+		if v, ok := s.c.data.Load(it.key); ok && v.(*entry[V]) == it.ety {
+			items = append(items, it)
+		}
+	}
+	if len(s.items) == 0 {
+		return items, time.Hour
+	}
+	return items, s.items[0].deadline.Sub(now)
+}
+
+func (s *scheduler[K, V]) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		items, wait := s.due(time.Now())
+		if !s.dispatch(items) {
+			return
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// dispatch refreshes items with at most MaxRefreshConcurrency fetches in flight.
+// It returns false if the scheduler was stopped meanwhile.
+func (s *scheduler[K, V]) dispatch(items []scheduleItem[K, V]) bool {
+	size := 1
+	if s.c.opt.BatchFetcher != nil {
+		size = s.c.opt.BatchSize
+	}
+	for len(items) > 0 {
+		n := min(size, len(items))
+		chunk := items[:n]
+		items = items[n:]
+
+		select {
+		case s.sem <- struct{}{}:
+		case <-s.stop:
+			return false
+		}
+		go func() {
+			defer func() { <-s.sem }()
+			s.refresh(chunk)
+		}()
+	}
+	return true
+}
+
+func (s *scheduler[K, V]) refresh(items []scheduleItem[K, V]) {
+	fetched := make([]scheduleItem[K, V], 0, len(items))
+	for _, it := range items {
+		if !s.c.followRemote(it.key, it.ety) {
+			fetched = append(fetched, it)
+		}
+	}
+
+	if s.c.opt.BatchFetcher != nil && len(fetched) > 0 {
+		keys := make([]K, len(fetched))
+		for i, it := range fetched {
+			keys[i] = it.key
+		}
+		vals, errs := s.c.batchFetch(keys)
+		for _, it := range fetched {
+			newVal, err := batchResult(it.key, vals, errs)
+			s.c.update(it.key, it.ety, newVal, err)
+		}
+	} else {
+		for _, it := range fetched {
+			newVal, interval, err := s.c.fetch(context.Background(), it.key)
+			if err == nil {
+				it.ety.SetInterval(interval)
+			}
+			s.c.update(it.key, it.ety, newVal, err)
+		}
+	}
+
+	for _, it := range items {
+		if v, ok := s.c.data.Load(it.key); ok && v.(*entry[V]) == it.ety {
+			s.schedule(it.key, it.ety)
+		}
+	}
+	s.c.reportSize()
+}
+
+func (s *scheduler[K, V]) close() {
+	close(s.stop)
+}
@@ -0,0 +1,147 @@
+package asynccache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Codec encodes and decodes the entries of a cache snapshot.
+type Codec[K comparable, V any] interface {
+	Encode(w io.Writer, data map[K]V) error
+	Decode(r io.Reader) (map[K]V, error)
+}
+
+// snapshotEntry is the element written by JSONCodec.
+// A list of pairs is used because JSON objects only allow string keys.
+type snapshotEntry[K comparable, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// JSONCodec encodes snapshots as a JSON list of key/value pairs.
+type JSONCodec[K comparable, V any] struct{}
+
+func (JSONCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	entries := make([]snapshotEntry[K, V], 0, len(data))
+	for k, v := range data {
+		entries = append(entries, snapshotEntry[K, V]{Key: k, Value: v})
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+func (JSONCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	var entries []snapshotEntry[K, V]
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	data := make(map[K]V, len(entries))
+	for _, e := range entries {
+		data[e.Key] = e.Value
+	}
+	return data, nil
+}
+
+// GobCodec encodes snapshots with encoding/gob.
+// Concrete types stored in interface values must be registered with gob.Register.
+type GobCodec[K comparable, V any] struct{}
+
+func (GobCodec[K, V]) Encode(w io.Writer, data map[K]V) error {
+	return gob.NewEncoder(w).Encode(data)
+}
+
+func (GobCodec[K, V]) Decode(r io.Reader) (map[K]V, error) {
+	var data map[K]V
+	if err := gob.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Snapshot writes all entries that hold a successfully fetched value to w.
+func (c *typedAsyncCache[K, V]) Snapshot(w io.Writer) error {
+	data := make(map[K]V)
+	c.data.Range(func(key, value interface{}) bool {
+		k, e, ok := c.loadEntry(key, value)
+		if ok && e.err.Load() == nil {
+			data[k] = e.Load()
+		}
+		return true
+	})
+	return c.codec().Encode(w, data)
+}
+
+// Restore reads a snapshot from r and sets its entries as defaults, see SetDefault.
+func (c *typedAsyncCache[K, V]) Restore(r io.Reader) error {
+	data, err := c.codec().Decode(r)
+	if err != nil {
+		return err
+	}
+	for k, v := range data {
+		c.SetDefault(k, v)
+	}
+	return nil
+}
+
+func (c *typedAsyncCache[K, V]) codec() Codec[K, V] {
+	if c.opt.SnapshotCodec != nil {
+		return c.opt.SnapshotCodec
+	}
+	return JSONCodec[K, V]{}
+}
+
+// loadSnapshot restores SnapshotPath if it exists.
+func (c *typedAsyncCache[K, V]) loadSnapshot() {
+	f, err := os.Open(c.opt.SnapshotPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.opt.ErrLogFunc(fmt.Sprintf("asynccache: open snapshot %s error: %v", c.opt.SnapshotPath, err))
+		}
+		return
+	}
+	defer f.Close()
+	if err = c.Restore(f); err != nil {
+		c.opt.ErrLogFunc(fmt.Sprintf("asynccache: restore snapshot %s error: %v", c.opt.SnapshotPath, err))
+	}
+}
+
+// saveSnapshot writes the snapshot to a temporary file and renames it to SnapshotPath,
+// so that a crash never leaves a truncated snapshot behind.
+func (c *typedAsyncCache[K, V]) saveSnapshot() error {
+	dir := filepath.Dir(c.opt.SnapshotPath)
+	f, err := os.CreateTemp(dir, filepath.Base(c.opt.SnapshotPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err = c.Snapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.opt.SnapshotPath)
+}
+
+// flushSnapshot saves the snapshot every SnapshotInterval until stop is closed.
+func (c *typedAsyncCache[K, V]) flushSnapshot(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(c.opt.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.saveSnapshot(); err != nil {
+				c.opt.ErrLogFunc(fmt.Sprintf("asynccache: save snapshot %s error: %v", c.opt.SnapshotPath, err))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package asynccache
+
+import (
+	"errors"
+	"time"
+)
+
+// Age returns how long ago the value of key was last fetched successfully or set.
+func (c *typedAsyncCache[K, V]) Age(key K) (time.Duration, bool) {
+	v, ok := c.data.Load(key)
+	if !ok {
+		return 0, false
+	}
+	updated := v.(*entry[V]).updated.Load()
+	if updated == 0 {
+		return 0, false
+	}
+	return time.Since(time.Unix(0, updated)), true
+}
+
+// negativeTTL returns the TTL of the first NegativeTTL sentinel matching err, or 0.
+func (c *typedAsyncCache[K, V]) negativeTTL(err error) time.Duration {
+	if err == nil {
+		return 0
+	}
+	for sentinel, ttl := range c.opt.NegativeTTL {
+		if errors.Is(err, sentinel) {
+			return ttl
+		}
+	}
+	return 0
+}
+
+// markNegative turns e into a negative entry if err matches NegativeTTL.
+func (c *typedAsyncCache[K, V]) markNegative(e *entry[V], err error) bool {
+	ttl := c.negativeTTL(err)
+	if ttl <= 0 {
+		return false
+	}
+	e.negUntil.Store(time.Now().Add(ttl).UnixNano())
+	return true
+}
+
+// tooStale reports whether the last good value of e is older than MaxStaleness.
+func (c *typedAsyncCache[K, V]) tooStale(e *entry[V], now time.Time) bool {
+	if c.opt.MaxStaleness <= 0 {
+		return false
+	}
+	updated := e.updated.Load()
+	return updated != 0 && now.Sub(time.Unix(0, updated)) > c.opt.MaxStaleness
+}
+
+// negative reports whether e is a negative entry whose TTL has not passed yet.
+func (e *entry[V]) negative(now time.Time) bool {
+	until := e.negUntil.Load()
+	return until != 0 && now.UnixNano() < until
+}
+
+// negativeExpired reports whether e is a negative entry whose TTL has passed.
+func (e *entry[V]) negativeExpired(now time.Time) bool {
+	until := e.negUntil.Load()
+	return until != 0 && now.UnixNano() >= until
+}
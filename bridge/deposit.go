@@ -0,0 +1,87 @@
+package bridge
+
+import (
+	"database/sql"
+	"errors"
+	"math/big"
+
+	"github.com/dexerlab/utils-go/loader"
+)
+
+// ErrNotDeposit is returned when a log or an instruction is not an Owlto deposit.
+var ErrNotDeposit = errors.New("not an owlto deposit")
+
+// netcodeModulus is what the v1 contracts use to carry the network code of the target chain:
+// the last 4 digits of the amount.
+const netcodeModulus = 10000
+
+// DepositEvent is a deposit into an Owlto bridge contract, whatever the source chain.
+type DepositEvent struct {
+	ChainId     int32
+	TxHash      string
+	BlockNumber int64
+	// Index orders the deposits of a block: the log index on EVM, the instruction index on Solana.
+	Index     int64
+	Timestamp int64
+	Sender    string
+	// Token is the deposited token, the zero address on EVM and the system program on Solana for the gas token.
+	Token  string
+	Amount *big.Int
+	// TargetNetcode is the network code of the target chain, TargetChainId its chain id, 0 if it is unknown.
+	TargetNetcode int32
+	TargetChainId int32
+	TargetAddress string
+	Maker         string
+	Channel       int32
+}
+
+// SrcTx returns the deposit as a t_src_transaction row, token may be nil if it is unknown.
+func (e *DepositEvent) SrcTx(chain *loader.ChainInfo, token *loader.TokenInfo) *loader.SrcTx {
+	tx := &loader.SrcTx{
+		ChainId:           e.ChainId,
+		TxHash:            e.TxHash,
+		Sender:            e.Sender,
+		Receiver:          e.Maker,
+		TargetAddress:     sql.NullString{String: e.TargetAddress, Valid: e.TargetAddress != ""},
+		Token:             e.Token,
+		Value:             e.Amount.String(),
+		DstChainid:        sql.NullInt32{Int32: e.TargetChainId, Valid: e.TargetChainId != 0},
+		IsTestnet:         sql.NullInt32{Int32: int32(chain.IsTestnet), Valid: true},
+		TxTimestamp:       int32(e.Timestamp),
+		ThirdpartyChannel: e.Channel,
+	}
+	if token != nil {
+		tx.SrcTokenName = sql.NullString{String: token.TokenName, Valid: true}
+		tx.SrcTokenDecimal = token.Decimals
+	}
+	return tx
+}
+
+// Decoder turns the deposits of a chain into DepositEvents.
+// The bridge contracts are TransferContractAddress (v1) and DepositContractAddress of the chain,
+// no deposit is found for a contract that is not set.
+type Decoder struct {
+	chain  *loader.ChainInfo
+	chains *loader.ChainInfoManager
+}
+
+// NewDecoder decodes the deposits of chain, chains resolves the target chains and may be nil.
+func NewDecoder(chain *loader.ChainInfo, chains *loader.ChainInfoManager) *Decoder {
+	return &Decoder{chain: chain, chains: chains}
+}
+
+// target sets the target chain of e from its network code.
+func (d *Decoder) target(e *DepositEvent, netcode int32) {
+	e.TargetNetcode = netcode
+	if d.chains == nil {
+		return
+	}
+	if chain, ok := d.chains.GetChainInfoByNetcode(netcode); ok {
+		e.TargetChainId = chain.GetInt32ChainId()
+	}
+}
+
+// amountNetcode returns the network code carried by the amount of a v1 deposit.
+func amountNetcode(amount *big.Int) int32 {
+	return int32(new(big.Int).Mod(amount, big.NewInt(netcodeModulus)).Int64())
+}
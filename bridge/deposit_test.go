@@ -0,0 +1,198 @@
+package bridge
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	owltosol "github.com/dexerlab/utils-go/abi/owlto_sol_transfer"
+	svmdepositor "github.com/dexerlab/utils-go/abi/svm_depositor/generated/svm_depositor"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEvmLog(t *testing.T) {
+	transfer := common.HexToAddress("0x5e809A85Aa182A9921EDD10a4163745bb3e36284")
+	deposit := common.HexToAddress("0x0e83DEd9f80e1C92549615D96842F5cB64A08762")
+	chain := &loader.ChainInfo{
+		ChainId:                 "10",
+		TransferContractAddress: sql.NullString{String: transfer.Hex(), Valid: true},
+		DepositContractAddress:  sql.NullString{String: deposit.Hex(), Valid: true},
+	}
+	d := NewDecoder(chain, nil)
+	user := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	maker := common.HexToAddress("0x00000000000000000000000000000000000000c3")
+	topics := func(topic common.Hash) []common.Hash {
+		return []common.Hash{topic, common.BytesToHash(user[:]), common.BytesToHash(token[:]), common.BytesToHash(maker[:])}
+	}
+
+	data, err := owlto20ABI.Events["Deposit"].Inputs.NonIndexed().Pack(" 0xtarget ", big.NewInt(10000000000000009), big.NewInt(1700000000))
+	assert.NoError(t, err)
+	v1 := &types.Log{Address: transfer, Topics: topics(owlto20DepositTopic), Data: data, BlockNumber: 12, Index: 3, TxHash: common.HexToHash("0x01")}
+	e, err := d.DecodeEvmLog(v1)
+	assert.NoError(t, err)
+	assert.Equal(t, &DepositEvent{
+		ChainId:       10,
+		TxHash:        common.HexToHash("0x01").Hex(),
+		BlockNumber:   12,
+		Index:         3,
+		Timestamp:     1700000000,
+		Sender:        user.Hex(),
+		Token:         token.Hex(),
+		Amount:        big.NewInt(10000000000000009),
+		TargetNetcode: 9,
+		TargetAddress: "0xtarget",
+		Maker:         maker.Hex(),
+	}, e)
+
+	src := e.SrcTx(chain, &loader.TokenInfo{TokenName: "ETH", Decimals: 18})
+	assert.Equal(t, "10000000000000009", src.Value)
+	assert.Equal(t, maker.Hex(), src.Receiver)
+	assert.Equal(t, sql.NullString{String: "0xtarget", Valid: true}, src.TargetAddress)
+	assert.False(t, src.DstChainid.Valid)
+	assert.Equal(t, int32(18), src.SrcTokenDecimal)
+
+	data, err = depositorABI.Events["Deposit"].Inputs.NonIndexed().Pack("target", big.NewInt(500), big.NewInt(1), big.NewInt(7), big.NewInt(1700000001))
+	assert.NoError(t, err)
+	v2 := &types.Log{Address: deposit, Topics: topics(depositorDepositTopic), Data: data, Index: 4}
+	other := &types.Log{Address: user, Topics: topics(depositorDepositTopic), Data: data, Index: 5}
+	failed := &types.Receipt{Status: types.ReceiptStatusFailed, Logs: []*types.Log{v1, v2}}
+	events, err := d.DecodeEvmReceipt(failed)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = d.DecodeEvmReceipt(&types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{v1, v2, other}})
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, int32(1), events[1].TargetNetcode)
+	assert.Equal(t, int32(7), events[1].Channel)
+	assert.Equal(t, int64(500), events[1].Amount.Int64())
+
+	_, err = d.DecodeEvmLog(other)
+	assert.ErrorIs(t, err, ErrNotDeposit)
+
+	// no contract configured, nothing is a deposit
+	unset := NewDecoder(&loader.ChainInfo{ChainId: "10"}, nil)
+	_, err = unset.DecodeEvmLog(v1)
+	assert.ErrorIs(t, err, ErrNotDeposit)
+	_, err = unset.DecodeEvmLog(v2)
+	assert.ErrorIs(t, err, ErrNotDeposit)
+}
+
+func TestDecodeSolanaTx(t *testing.T) {
+	transferProgram := solana.MustPublicKeyFromBase58("4wDy2RiANrkvUe8ikBNP6ddRvXNYVkmhgjA4Ep4ENfE9")
+	depositProgram := solana.MustPublicKeyFromBase58("CcmRPmaqx7Akc4T9pQgVGTqD3wrgdoG8CjbkmxDk9BsB")
+	router := solana.MustPublicKeyFromBase58("JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4")
+	chain := &loader.ChainInfo{
+		ChainId:                 "501",
+		TransferContractAddress: sql.NullString{String: transferProgram.String(), Valid: true},
+		DepositContractAddress:  sql.NullString{String: depositProgram.String(), Valid: true},
+	}
+	from := solana.NewWallet().PublicKey()
+	fromAta := solana.NewWallet().PublicKey()
+	toAta := solana.NewWallet().PublicKey()
+	maker := solana.NewWallet().PublicKey()
+	mint := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+
+	splInst := owltosol.NewTransferSplTokensInstruction(
+		owltosol.TransferData{Amount: 250000003, TargetAddr: "0xtarget"},
+		from, fromAta, toAta, solana.TokenProgramID).Build()
+	splData, err := splInst.Data()
+	assert.NoError(t, err)
+	lamportsInst := svmdepositor.NewTransferLamportsInstruction(
+		svmdepositor.TransferData{Amount: 1_000_000, TargetAddr: "bc1target", Maker: maker, Destination: 2, Channel: 5},
+		from, maker, solana.SystemProgramID).Build()
+	lamportsData, err := lamportsInst.Data()
+	assert.NoError(t, err)
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(transferProgram, splInst.Accounts(), splData),
+		solana.NewInstruction(router, solana.AccountMetaSlice{solana.Meta(depositProgram), solana.Meta(maker), solana.Meta(solana.SystemProgramID)}, nil),
+	}, solana.Hash{}, solana.TransactionPayer(from))
+	assert.NoError(t, err)
+	tx.Signatures = []solana.Signature{{1}}
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	var envelope rpc.TransactionResultEnvelope
+	assert.NoError(t, json.Unmarshal([]byte(`["`+base64.StdEncoding.EncodeToString(raw)+`","base64"]`), &envelope))
+
+	index := func(key solana.PublicKey) uint16 {
+		idx, err := tx.Message.GetAccountIndex(key)
+		assert.NoError(t, err)
+		return idx
+	}
+	blockTime := solana.UnixTimeSeconds(1700000000)
+	result := &rpc.GetTransactionResult{
+		Slot:        300,
+		BlockTime:   &blockTime,
+		Transaction: &envelope,
+		Meta: &rpc.TransactionMeta{
+			PreTokenBalances: []rpc.TokenBalance{
+				{AccountIndex: index(fromAta), Mint: mint, Owner: &from},
+				{AccountIndex: index(toAta), Mint: mint, Owner: &maker},
+			},
+			InnerInstructions: []rpc.InnerInstruction{{
+				Index: 1,
+				Instructions: []rpc.CompiledInstruction{{
+					ProgramIDIndex: index(depositProgram),
+					Accounts:       []uint16{index(from), index(maker), index(solana.SystemProgramID)},
+					Data:           lamportsData,
+				}},
+			}},
+		},
+	}
+
+	events, err := NewDecoder(chain, nil).DecodeSolanaTx(result)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, &DepositEvent{
+		ChainId:       501,
+		TxHash:        solana.Signature{1}.String(),
+		BlockNumber:   300,
+		Index:         0,
+		Timestamp:     1700000000,
+		Sender:        from.String(),
+		Token:         mint.String(),
+		Amount:        big.NewInt(250000003),
+		TargetNetcode: 3,
+		TargetAddress: "0xtarget",
+		Maker:         maker.String(),
+	}, events[0])
+	assert.Equal(t, int64(2), events[1].Index)
+	assert.Equal(t, solana.SystemProgramID.String(), events[1].Token)
+	assert.Equal(t, maker.String(), events[1].Maker)
+	assert.Equal(t, int32(2), events[1].TargetNetcode)
+	assert.Equal(t, int32(5), events[1].Channel)
+
+	// an emit_cpi event of the transfer program next to the transfer is skipped
+	emitCpi := rpc.CompiledInstruction{
+		ProgramIDIndex: index(transferProgram),
+		Data:           append([]byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}, 1, 2, 3),
+	}
+	result.Meta.InnerInstructions = append(result.Meta.InnerInstructions, rpc.InnerInstruction{
+		Index: 0, Instructions: []rpc.CompiledInstruction{emitCpi},
+	})
+	events, err = NewDecoder(chain, nil).DecodeSolanaTx(result)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(0), events[0].Index)
+	assert.Equal(t, int64(250000003), events[0].Amount.Int64())
+
+	// a truncated transfer is an error
+	emitCpi.Data = splData[:10]
+	result.Meta.InnerInstructions[1].Instructions[0] = emitCpi
+	_, err = NewDecoder(chain, nil).DecodeSolanaTx(result)
+	assert.Error(t, err)
+	result.Meta.InnerInstructions = result.Meta.InnerInstructions[:1]
+
+	result.Meta.Err = map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}
+	events, err = NewDecoder(chain, nil).DecodeSolanaTx(result)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,104 @@
+package bridge
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dexerlab/utils-go/abi/depositor"
+	owlto20 "github.com/dexerlab/utils-go/abi/owlto"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	owlto20ABI, _   = owlto20.Owlto20MetaData.GetAbi()
+	depositorABI, _ = depositor.DepositorMetaData.GetAbi()
+
+	// the filterers only parse logs, they are bound to no contract and no backend
+	owlto20Filterer, _   = owlto20.NewOwlto20Filterer(common.Address{}, nil)
+	depositorFilterer, _ = depositor.NewDepositorFilterer(common.Address{}, nil)
+
+	owlto20DepositTopic   = owlto20ABI.Events["Deposit"].ID
+	depositorDepositTopic = depositorABI.Events["Deposit"].ID
+)
+
+// DepositTopics are the topics of the Deposit events, to filter the logs of the bridge contracts.
+var DepositTopics = []common.Hash{owlto20DepositTopic, depositorDepositTopic}
+
+// DecodeEvmLog decodes the Deposit event of Owlto20 or Depositor, ErrNotDeposit if l is something else.
+func (d *Decoder) DecodeEvmLog(l *types.Log) (*DepositEvent, error) {
+	if len(l.Topics) == 0 || l.Removed {
+		return nil, ErrNotDeposit
+	}
+	switch l.Topics[0] {
+	case owlto20DepositTopic:
+		if !isContract(l.Address, d.chain.TransferContractAddress.String) {
+			return nil, ErrNotDeposit
+		}
+		ev, err := owlto20Filterer.ParseDeposit(*l)
+		if err != nil {
+			return nil, err
+		}
+		e := d.evmEvent(l, ev.User, ev.Token, ev.Maker, ev.Target)
+		e.Amount = ev.Amount
+		e.Timestamp = ev.Timestamp.Int64()
+		d.target(e, amountNetcode(ev.Amount))
+		return e, nil
+	case depositorDepositTopic:
+		if !isContract(l.Address, d.chain.DepositContractAddress.String) {
+			return nil, ErrNotDeposit
+		}
+		ev, err := depositorFilterer.ParseDeposit(*l)
+		if err != nil {
+			return nil, err
+		}
+		e := d.evmEvent(l, ev.User, ev.Token, ev.Maker, ev.Target)
+		e.Amount = ev.Amount
+		e.Timestamp = ev.Timestamp.Int64()
+		e.Channel = int32(ev.Channel.Int64())
+		d.target(e, int32(ev.Destination.Int64()))
+		return e, nil
+	}
+	return nil, ErrNotDeposit
+}
+
+// DecodeEvmReceipt decodes the deposits of a successful transaction.
+func (d *Decoder) DecodeEvmReceipt(receipt *types.Receipt) ([]*DepositEvent, error) {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, nil
+	}
+	var events []*DepositEvent
+	for _, l := range receipt.Logs {
+		e, err := d.DecodeEvmLog(l)
+		if errors.Is(err, ErrNotDeposit) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (d *Decoder) evmEvent(l *types.Log, user common.Address, token common.Address, maker common.Address, target string) *DepositEvent {
+	return &DepositEvent{
+		ChainId:       d.chain.GetInt32ChainId(),
+		TxHash:        l.TxHash.Hex(),
+		BlockNumber:   int64(l.BlockNumber),
+		Index:         int64(l.Index),
+		Sender:        user.Hex(),
+		Token:         token.Hex(),
+		TargetAddress: strings.TrimSpace(target),
+		Maker:         maker.Hex(),
+	}
+}
+
+// isContract reports whether addr is the configured contract, no address matches when it is not configured.
+func isContract(addr common.Address, contract string) bool {
+	contract = strings.TrimSpace(contract)
+	if contract == "" {
+		return false
+	}
+	return strings.EqualFold(addr.Hex(), contract)
+}
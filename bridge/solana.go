@@ -0,0 +1,255 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	owltosol "github.com/dexerlab/utils-go/abi/owlto_sol_transfer"
+	svmdepositor "github.com/dexerlab/utils-go/abi/svm_depositor/generated/svm_depositor"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// solanaInstruction is an instruction of a transaction with its accounts resolved.
+type solanaInstruction struct {
+	programID solana.PublicKey
+	accounts  []uint16 // indexes in keys
+	data      []byte
+}
+
+// solanaTx is a transaction being decoded.
+type solanaTx struct {
+	result *rpc.GetTransactionResult
+	keys   solana.PublicKeySlice
+}
+
+// DecodeSolanaTx decodes the deposits of a successful transaction, from its top level and inner instructions.
+// The other instructions of the Owlto programs, such as the emit_cpi events, are skipped,
+// an error is only returned for a malformed deposit.
+// The Owlto programs are told apart by their program id, the deposits of a chain whose
+// TransferContractAddress and DepositContractAddress are not set are not found.
+func (d *Decoder) DecodeSolanaTx(result *rpc.GetTransactionResult) ([]*DepositEvent, error) {
+	if result == nil || result.Transaction == nil || result.Meta == nil || result.Meta.Err != nil {
+		return nil, nil
+	}
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.Signatures) == 0 {
+		return nil, fmt.Errorf("transaction without signature")
+	}
+	stx := &solanaTx{result: result, keys: append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)}
+	stx.keys = append(stx.keys, result.Meta.LoadedAddresses.Writable...)
+	stx.keys = append(stx.keys, result.Meta.LoadedAddresses.ReadOnly...)
+
+	transferProgram, _ := solana.PublicKeyFromBase58(strings.TrimSpace(d.chain.TransferContractAddress.String))
+	depositProgram, _ := solana.PublicKeyFromBase58(strings.TrimSpace(d.chain.DepositContractAddress.String))
+
+	var events []*DepositEvent
+	var index int64
+	decode := func(programIDIndex uint16, accounts []uint16, data []byte) error {
+		defer func() { index++ }()
+		if int(programIDIndex) >= len(stx.keys) {
+			return fmt.Errorf("program id index %d out of %d keys", programIDIndex, len(stx.keys))
+		}
+		inst := &solanaInstruction{programID: stx.keys[programIDIndex], accounts: accounts, data: data}
+		var e *DepositEvent
+		var err error
+		switch {
+		case inst.programID.IsZero():
+			return nil
+		case inst.programID == transferProgram:
+			e, err = d.decodeOwltoSolTransfer(stx, inst)
+		case inst.programID == depositProgram:
+			e, err = d.decodeSvmDepositor(stx, inst)
+		default:
+			return nil
+		}
+		if errors.Is(err, ErrNotDeposit) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e.ChainId = d.chain.GetInt32ChainId()
+		e.TxHash = tx.Signatures[0].String()
+		e.BlockNumber = int64(result.Slot)
+		e.Index = index
+		if result.BlockTime != nil {
+			e.Timestamp = int64(*result.BlockTime)
+		}
+		events = append(events, e)
+		return nil
+	}
+
+	for i, inst := range tx.Message.Instructions {
+		if err := decode(inst.ProgramIDIndex, inst.Accounts, inst.Data); err != nil {
+			return nil, err
+		}
+		for _, inner := range result.Meta.InnerInstructions {
+			if int(inner.Index) != i {
+				continue
+			}
+			for _, innerInst := range inner.Instructions {
+				if err := decode(innerInst.ProgramIDIndex, innerInst.Accounts, innerInst.Data); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return events, nil
+}
+
+func (d *Decoder) decodeOwltoSolTransfer(stx *solanaTx, inst *solanaInstruction) (*DepositEvent, error) {
+	if !hasDiscriminator(inst.data, owltosol.Instruction_TransferLamports, owltosol.Instruction_TransferSplTokens) {
+		return nil, ErrNotDeposit
+	}
+	metas, err := stx.accountMetas(inst)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := owltosol.DecodeInstruction(metas, inst.data)
+	if err != nil {
+		return nil, err
+	}
+	var e *DepositEvent
+	var data *owltosol.TransferData
+	switch v := decoded.Impl.(type) {
+	case *owltosol.TransferLamports:
+		if err := checkAccounts("transferLamports", metas, 3); err != nil {
+			return nil, err
+		}
+		data = v.TransferData
+		e = &DepositEvent{
+			Sender: v.GetFromAccount().PublicKey.String(),
+			Token:  solana.SystemProgramID.String(),
+			Maker:  v.GetToAccount().PublicKey.String(),
+		}
+	case *owltosol.TransferSplTokens:
+		if err := checkAccounts("transferSplTokens", metas, 4); err != nil {
+			return nil, err
+		}
+		data = v.TransferData
+		mint, _, ok := stx.tokenAccount(inst.accounts[1])
+		if !ok {
+			return nil, fmt.Errorf("no token balance for source account %s", v.GetFromAtaAccount().PublicKey)
+		}
+		_, owner, ok := stx.tokenAccount(inst.accounts[2])
+		if !ok {
+			return nil, fmt.Errorf("no token balance for target account %s", v.GetToAtaAccount().PublicKey)
+		}
+		e = &DepositEvent{
+			Sender: v.GetFromAccount().PublicKey.String(),
+			Token:  mint.String(),
+			Maker:  owner.String(),
+		}
+	default:
+		return nil, ErrNotDeposit
+	}
+	if data == nil {
+		return nil, ErrNotDeposit
+	}
+	e.Amount = new(big.Int).SetUint64(data.Amount)
+	e.TargetAddress = strings.TrimSpace(data.TargetAddr)
+	d.target(e, amountNetcode(e.Amount))
+	return e, nil
+}
+
+func (d *Decoder) decodeSvmDepositor(stx *solanaTx, inst *solanaInstruction) (*DepositEvent, error) {
+	if !hasDiscriminator(inst.data, svmdepositor.Instruction_TransferLamports, svmdepositor.Instruction_TransferSplTokens) {
+		return nil, ErrNotDeposit
+	}
+	metas, err := stx.accountMetas(inst)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := svmdepositor.DecodeInstruction(metas, inst.data)
+	if err != nil {
+		return nil, err
+	}
+	var e *DepositEvent
+	var data *svmdepositor.TransferData
+	switch v := decoded.Impl.(type) {
+	case *svmdepositor.TransferLamports:
+		if err := checkAccounts("transferLamports", metas, 3); err != nil {
+			return nil, err
+		}
+		data = v.TransferData
+		e = &DepositEvent{
+			Sender: v.GetFromAccount().PublicKey.String(),
+			Token:  solana.SystemProgramID.String(),
+		}
+	case *svmdepositor.TransferSplTokens:
+		if err := checkAccounts("transferSplTokens", metas, 4); err != nil {
+			return nil, err
+		}
+		data = v.TransferData
+		e = &DepositEvent{Sender: v.GetFromAccount().PublicKey.String()}
+		if data != nil {
+			e.Token = solana.PublicKeyFromBytes(data.Token[:]).String()
+		}
+	default:
+		return nil, ErrNotDeposit
+	}
+	if data == nil {
+		return nil, ErrNotDeposit
+	}
+	e.Amount = new(big.Int).SetUint64(data.Amount)
+	e.TargetAddress = strings.TrimSpace(data.TargetAddr)
+	e.Maker = solana.PublicKeyFromBytes(data.Maker[:]).String()
+	e.Channel = int32(data.Channel)
+	d.target(e, int32(data.Destination))
+	return e, nil
+}
+
+// hasDiscriminator reports whether the anchor instruction data starts with one of ids.
+func hasDiscriminator(data []byte, ids ...bin.TypeID) bool {
+	if len(data) < 8 {
+		return false
+	}
+	for _, id := range ids {
+		if bin.TypeID([8]byte(data[:8])) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (stx *solanaTx) accountMetas(inst *solanaInstruction) ([]*solana.AccountMeta, error) {
+	metas := make([]*solana.AccountMeta, len(inst.accounts))
+	for i, idx := range inst.accounts {
+		if int(idx) >= len(stx.keys) {
+			return nil, fmt.Errorf("account index %d out of %d keys", idx, len(stx.keys))
+		}
+		metas[i] = solana.Meta(stx.keys[idx])
+	}
+	return metas, nil
+}
+
+func checkAccounts(name string, metas []*solana.AccountMeta, want int) error {
+	if len(metas) < want {
+		return fmt.Errorf("%s with %d accounts instead of %d", name, len(metas), want)
+	}
+	return nil
+}
+
+// tokenAccount returns the mint and the owner of the token account at index idx of the keys.
+func (stx *solanaTx) tokenAccount(idx uint16) (solana.PublicKey, solana.PublicKey, bool) {
+	for _, balances := range [][]rpc.TokenBalance{stx.result.Meta.PreTokenBalances, stx.result.Meta.PostTokenBalances} {
+		for _, b := range balances {
+			if b.AccountIndex != idx {
+				continue
+			}
+			var owner solana.PublicKey
+			if b.Owner != nil {
+				owner = *b.Owner
+			}
+			return b.Mint, owner, true
+		}
+	}
+	return solana.PublicKey{}, solana.PublicKey{}, false
+}
@@ -0,0 +1,217 @@
+package clmm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bigInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(s)
+	}
+	return v
+}
+
+// encodePriceSqrt is the helper of the v3-core tests: floor(sqrt(reserve1 / reserve0) * 2^96).
+func encodePriceSqrt(reserve1, reserve0 int64) *big.Int {
+	ratio := new(big.Float).SetPrec(512).Quo(new(big.Float).SetPrec(512).SetInt64(reserve1), new(big.Float).SetPrec(512).SetInt64(reserve0))
+	ratio.Sqrt(ratio).Mul(ratio, new(big.Float).SetPrec(512).SetInt(Q96))
+	v, _ := ratio.Int(nil)
+	return v
+}
+
+var e18 = bigInt("1000000000000000000")
+
+func TestTickMath(t *testing.T) {
+	for _, c := range []struct {
+		tick  int32
+		ratio string
+	}{
+		{MinTick, "4295128739"},
+		{MinTick + 1, "4295343490"},
+		{0, "79228162514264337593543950336"},
+		{MaxTick - 1, "1461373636630004318706518188784493106690254656249"},
+		{MaxTick, "1461446703485210103287273052203988822378723970342"},
+	} {
+		ratio, err := GetSqrtRatioAtTick(c.tick)
+		assert.NoError(t, err)
+		assert.Equal(t, c.ratio, ratio.String(), "tick %d", c.tick)
+	}
+	_, err := GetSqrtRatioAtTick(MaxTick + 1)
+	assert.Error(t, err)
+
+	for _, tick := range []int32{MinTick, -200000, -50, -1, 0, 1, 60, 123456, MaxTick - 1} {
+		ratio, _ := GetSqrtRatioAtTick(tick)
+		got, err := GetTickAtSqrtRatio(ratio)
+		assert.NoError(t, err)
+		assert.Equal(t, tick, got)
+		if tick > MinTick {
+			got, _ = GetTickAtSqrtRatio(new(big.Int).Sub(ratio, one))
+			assert.Equal(t, tick-1, got)
+		}
+	}
+	tick, err := GetTickAtSqrtRatio(new(big.Int).Sub(MaxSqrtRatio, one))
+	assert.NoError(t, err)
+	assert.Equal(t, MaxTick-1, tick)
+	_, err = GetTickAtSqrtRatio(MaxSqrtRatio)
+	assert.Error(t, err)
+}
+
+func TestSqrtPriceMath(t *testing.T) {
+	price := encodePriceSqrt(1, 1)
+	tenth := new(big.Int).Quo(e18, big.NewInt(10))
+
+	next, err := GetNextSqrtPriceFromInput(price, e18, tenth, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "87150978765690771352898345369", next.String())
+	next, err = GetNextSqrtPriceFromInput(price, e18, tenth, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "72025602285694852357767227579", next.String())
+	next, err = GetNextSqrtPriceFromOutput(price, e18, tenth, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "88031291682515930659493278152", next.String())
+	next, err = GetNextSqrtPriceFromOutput(price, e18, tenth, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "71305346262837903834189555302", next.String())
+	_, err = GetNextSqrtPriceFromOutput(price, big.NewInt(1), big.NewInt(4), false)
+	assert.ErrorIs(t, err, ErrNotEnoughReserve)
+
+	up := encodePriceSqrt(121, 100)
+	assert.Equal(t, "90909090909090910", GetAmount0Delta(price, up, e18, true).String())
+	assert.Equal(t, "90909090909090909", GetAmount0Delta(price, up, e18, false).String())
+	assert.Equal(t, "100000000000000000", GetAmount1Delta(price, up, e18, true).String())
+	assert.Equal(t, "99999999999999999", GetAmount1Delta(price, up, e18, false).String())
+
+	// swap computation
+	sqrtP := bigInt("1025574284609383690408304870162715216695788925244")
+	liquidity := bigInt("50015962439936049619261659728067971248")
+	sqrtQ, err := GetNextSqrtPriceFromInput(sqrtP, liquidity, big.NewInt(406), true)
+	assert.NoError(t, err)
+	assert.Equal(t, "1025574284609383582644711336373707553698163132913", sqrtQ.String())
+	assert.Equal(t, "406", GetAmount0Delta(sqrtQ, sqrtP, liquidity, true).String())
+}
+
+func TestComputeSwapStep(t *testing.T) {
+	for _, c := range []struct {
+		name                          string
+		price, target, liquidity, amt *big.Int
+		fee                           uint32
+		in, out, feeAmount            string
+		next                          string // empty when it is the target
+	}{
+		{"exact in capped at target", encodePriceSqrt(1, 1), encodePriceSqrt(101, 100), new(big.Int).Mul(e18, big.NewInt(2)), e18, 600,
+			"9975124224178055", "9925619580021728", "5988667735148", ""},
+		{"exact out capped at target", encodePriceSqrt(1, 1), encodePriceSqrt(101, 100), new(big.Int).Mul(e18, big.NewInt(2)), new(big.Int).Neg(e18), 600,
+			"9975124224178055", "9925619580021728", "5988667735148", ""},
+		{"exact in fully spent", encodePriceSqrt(1, 1), encodePriceSqrt(1000, 100), new(big.Int).Mul(e18, big.NewInt(2)), e18, 600,
+			"999400000000000000", "666399946655997866", "600000000000000", "-"},
+		{"exact out fully received", encodePriceSqrt(1, 1), encodePriceSqrt(10000, 100), new(big.Int).Mul(e18, big.NewInt(2)), new(big.Int).Neg(e18), 600,
+			"2000000000000000000", "1000000000000000000", "1200720432259356", "-"},
+		{"amount out capped", bigInt("417332158212080721273783715441582"), bigInt("1452870262520218020823638996"), bigInt("159344665391607089467575320103"), big.NewInt(-1), 1,
+			"1", "1", "1", "417332158212080721273783715441581"},
+		{"entire input taken as fee", big.NewInt(2413), bigInt("79887613182836312"), bigInt("1985041575832132834610021537970"), big.NewInt(10), 1872,
+			"0", "0", "10", "2413"},
+	} {
+		next, in, out, fee, err := ComputeSwapStep(c.price, c.target, c.liquidity, c.amt, c.fee)
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.in, in.String(), c.name)
+		assert.Equal(t, c.out, out.String(), c.name)
+		assert.Equal(t, c.feeAmount, fee.String(), c.name)
+		switch c.next {
+		case "":
+			assert.Equal(t, c.target, next, c.name)
+		case "-":
+			assert.NotEqual(t, c.target, next, c.name)
+		default:
+			assert.Equal(t, c.next, next.String(), c.name)
+		}
+	}
+
+	// the contract reverts on a fee of 100%
+	_, _, _, _, err := ComputeSwapStep(encodePriceSqrt(1, 1), encodePriceSqrt(101, 100), e18, e18, FeeDenominator)
+	assert.Error(t, err)
+	_, _, _, _, err = ComputeSwapStep(encodePriceSqrt(1, 1), encodePriceSqrt(101, 100), e18, new(big.Int).Neg(e18), FeeDenominator+1)
+	assert.Error(t, err)
+}
+
+func TestLiquidityAmounts(t *testing.T) {
+	price := encodePriceSqrt(1, 1)
+	lower, upper := encodePriceSqrt(100, 110), encodePriceSqrt(110, 100)
+	liquidity, err := LiquidityForAmounts(price, lower, upper, big.NewInt(100), big.NewInt(200))
+	assert.NoError(t, err)
+	assert.Equal(t, "2148", liquidity.String())
+	liquidity, err = LiquidityForAmounts(encodePriceSqrt(99, 110), lower, upper, big.NewInt(100), big.NewInt(200))
+	assert.NoError(t, err)
+	assert.Equal(t, "1048", liquidity.String())
+	liquidity, err = LiquidityForAmounts(encodePriceSqrt(111, 100), lower, upper, big.NewInt(100), big.NewInt(200))
+	assert.NoError(t, err)
+	assert.Equal(t, "2097", liquidity.String())
+
+	_, err = LiquidityForAmount0(lower, lower, big.NewInt(100))
+	assert.ErrorIs(t, err, ErrEmptyRange)
+	_, err = LiquidityForAmount1(upper, upper, big.NewInt(100))
+	assert.ErrorIs(t, err, ErrEmptyRange)
+	_, err = LiquidityForAmounts(price, upper, upper, big.NewInt(100), big.NewInt(200))
+	assert.ErrorIs(t, err, ErrEmptyRange)
+
+	amount0, amount1 := AmountsForLiquidity(price, lower, upper, big.NewInt(2148))
+	assert.Equal(t, "99", amount0.String())
+	assert.Equal(t, "99", amount1.String())
+}
+
+func TestPoolSwap(t *testing.T) {
+	// two positions: [-600, 600] and [0, 1200], like the pools of the v3-core tests with a tick spacing of 60
+	l1, l2 := new(big.Int).Mul(e18, big.NewInt(2)), e18
+	pool := &Pool{
+		SqrtPriceX96: encodePriceSqrt(1, 1),
+		Liquidity:    new(big.Int).Add(l1, l2),
+		Fee:          3000,
+		TickSpacing:  60,
+		Ticks: []Tick{
+			{Index: -600, LiquidityNet: l1},
+			{Index: 0, LiquidityNet: l2},
+			{Index: 600, LiquidityNet: new(big.Int).Neg(l1)},
+			{Index: 1200, LiquidityNet: new(big.Int).Neg(l2)},
+		},
+	}
+
+	// a small swap down crosses tick 0 right away, then matches a single step in [-600, 0]
+	amountIn := big.NewInt(1_000_000_000_000)
+	out, r, err := pool.QuoteExactInput(true, amountIn)
+	assert.NoError(t, err)
+	_, in, stepOut, fee, _ := ComputeSwapStep(pool.SqrtPriceX96, new(big.Int).Add(MinSqrtRatio, one), l1, amountIn, 3000)
+	assert.Equal(t, stepOut, out)
+	assert.Equal(t, amountIn, new(big.Int).Add(in, fee))
+	assert.Equal(t, amountIn, r.Amount0)
+	assert.Equal(t, int32(-1), r.Tick)
+
+	assert.Equal(t, l1, r.Liquidity)
+
+	// selling token0 down through tick -600 leaves no liquidity
+	out, r, err = pool.QuoteExactInput(true, new(big.Int).Quo(e18, big.NewInt(2)))
+	assert.NoError(t, err)
+	assert.Zero(t, r.Liquidity.Sign())
+	assert.Equal(t, MinTick, r.Tick)
+	assert.Equal(t, new(big.Int).Neg(out), r.Amount1)
+
+	// buying token0 up through tick 600 leaves the first position
+	in, r, err = pool.QuoteExactOutput(false, new(big.Int).Quo(e18, big.NewInt(10)))
+	assert.NoError(t, err)
+	assert.Equal(t, l2, r.Liquidity)
+	assert.Greater(t, r.Tick, int32(600))
+	assert.Equal(t, new(big.Int).Neg(new(big.Int).Quo(e18, big.NewInt(10))).String(), r.Amount0.String())
+	// and the same input gives the output back, give or take the rounding
+	out, _, err = pool.QuoteExactInput(false, in)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1e17, float64(out.Int64()), 2)
+
+	// all the liquidity is taken when asking for more than the pool has
+	_, r, err = pool.QuoteExactOutput(false, new(big.Int).Mul(e18, big.NewInt(100)))
+	assert.NoError(t, err)
+	assert.Zero(t, r.Liquidity.Sign())
+	_, err = pool.Swap(true, e18, new(big.Int).Add(pool.SqrtPriceX96, one))
+	assert.ErrorIs(t, err, ErrPriceLimit)
+}
@@ -0,0 +1,65 @@
+package clmm
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrEmptyRange is returned for a range whose sqrt ratios are equal, where the contracts revert.
+var ErrEmptyRange = errors.New("empty sqrt ratio range")
+
+// LiquidityForAmount0 returns the liquidity of amount0 in the range [sqrtRatioA, sqrtRatioB].
+func LiquidityForAmount0(sqrtRatioA, sqrtRatioB, amount0 *big.Int) (*big.Int, error) {
+	sqrtRatioA, sqrtRatioB = sortRatios(sqrtRatioA, sqrtRatioB)
+	if sqrtRatioA.Cmp(sqrtRatioB) == 0 {
+		return nil, ErrEmptyRange
+	}
+	intermediate := MulDiv(sqrtRatioA, sqrtRatioB, Q96)
+	return MulDiv(amount0, intermediate, new(big.Int).Sub(sqrtRatioB, sqrtRatioA)), nil
+}
+
+// LiquidityForAmount1 returns the liquidity of amount1 in the range [sqrtRatioA, sqrtRatioB].
+func LiquidityForAmount1(sqrtRatioA, sqrtRatioB, amount1 *big.Int) (*big.Int, error) {
+	sqrtRatioA, sqrtRatioB = sortRatios(sqrtRatioA, sqrtRatioB)
+	if sqrtRatioA.Cmp(sqrtRatioB) == 0 {
+		return nil, ErrEmptyRange
+	}
+	return MulDiv(amount1, Q96, new(big.Int).Sub(sqrtRatioB, sqrtRatioA)), nil
+}
+
+// LiquidityForAmounts returns the most liquidity that amount0 and amount1 can provide in the range
+// [sqrtRatioA, sqrtRatioB] at the price sqrtRatioX96.
+func LiquidityForAmounts(sqrtRatioX96, sqrtRatioA, sqrtRatioB, amount0, amount1 *big.Int) (*big.Int, error) {
+	sqrtRatioA, sqrtRatioB = sortRatios(sqrtRatioA, sqrtRatioB)
+	switch {
+	case sqrtRatioX96.Cmp(sqrtRatioA) <= 0:
+		return LiquidityForAmount0(sqrtRatioA, sqrtRatioB, amount0)
+	case sqrtRatioX96.Cmp(sqrtRatioB) < 0:
+		liquidity0, err := LiquidityForAmount0(sqrtRatioX96, sqrtRatioB, amount0)
+		if err != nil {
+			return nil, err
+		}
+		liquidity1, err := LiquidityForAmount1(sqrtRatioA, sqrtRatioX96, amount1)
+		if err != nil {
+			return nil, err
+		}
+		if liquidity0.Cmp(liquidity1) < 0 {
+			return liquidity0, nil
+		}
+		return liquidity1, nil
+	}
+	return LiquidityForAmount1(sqrtRatioA, sqrtRatioB, amount1)
+}
+
+// AmountsForLiquidity returns the amounts of token0 and token1 of liquidity in the range
+// [sqrtRatioA, sqrtRatioB] at the price sqrtRatioX96, rounded down.
+func AmountsForLiquidity(sqrtRatioX96, sqrtRatioA, sqrtRatioB, liquidity *big.Int) (*big.Int, *big.Int) {
+	sqrtRatioA, sqrtRatioB = sortRatios(sqrtRatioA, sqrtRatioB)
+	switch {
+	case sqrtRatioX96.Cmp(sqrtRatioA) <= 0:
+		return GetAmount0Delta(sqrtRatioA, sqrtRatioB, liquidity, false), new(big.Int)
+	case sqrtRatioX96.Cmp(sqrtRatioB) < 0:
+		return GetAmount0Delta(sqrtRatioX96, sqrtRatioB, liquidity, false), GetAmount1Delta(sqrtRatioA, sqrtRatioX96, liquidity, false)
+	}
+	return new(big.Int), GetAmount1Delta(sqrtRatioA, sqrtRatioB, liquidity, false)
+}
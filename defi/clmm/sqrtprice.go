@@ -0,0 +1,148 @@
+package clmm
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrPriceOverflow    = errors.New("sqrt price out of range")
+	ErrNotEnoughReserve = errors.New("output larger than the liquidity")
+)
+
+var one = big.NewInt(1)
+
+// MulDiv returns floor(a*b/denominator).
+func MulDiv(a, b, denominator *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Quo(r, denominator)
+}
+
+// MulDivRoundingUp returns ceil(a*b/denominator).
+func MulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
+	return divRoundingUp(new(big.Int).Mul(a, b), denominator)
+}
+
+func divRoundingUp(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, one)
+	}
+	return q
+}
+
+func sortRatios(a, b *big.Int) (*big.Int, *big.Int) {
+	if a.Cmp(b) > 0 {
+		return b, a
+	}
+	return a, b
+}
+
+// GetAmount0Delta returns the amount of token0 between two prices for liquidity:
+// liquidity / sqrtA - liquidity / sqrtB.
+func GetAmount0Delta(sqrtRatioA, sqrtRatioB, liquidity *big.Int, roundUp bool) *big.Int {
+	sqrtRatioA, sqrtRatioB = sortRatios(sqrtRatioA, sqrtRatioB)
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	numerator2 := new(big.Int).Sub(sqrtRatioB, sqrtRatioA)
+	if roundUp {
+		return divRoundingUp(MulDivRoundingUp(numerator1, numerator2, sqrtRatioB), sqrtRatioA)
+	}
+	r := MulDiv(numerator1, numerator2, sqrtRatioB)
+	return r.Quo(r, sqrtRatioA)
+}
+
+// GetAmount1Delta returns the amount of token1 between two prices for liquidity:
+// liquidity * (sqrtB - sqrtA).
+func GetAmount1Delta(sqrtRatioA, sqrtRatioB, liquidity *big.Int, roundUp bool) *big.Int {
+	sqrtRatioA, sqrtRatioB = sortRatios(sqrtRatioA, sqrtRatioB)
+	diff := new(big.Int).Sub(sqrtRatioB, sqrtRatioA)
+	if roundUp {
+		return MulDivRoundingUp(liquidity, diff, Q96)
+	}
+	return MulDiv(liquidity, diff, Q96)
+}
+
+// GetAmount0DeltaSigned is GetAmount0Delta for a liquidity change, rounded up when liquidity is added
+// and negative when it is removed.
+func GetAmount0DeltaSigned(sqrtRatioA, sqrtRatioB, liquidity *big.Int) *big.Int {
+	if liquidity.Sign() < 0 {
+		return new(big.Int).Neg(GetAmount0Delta(sqrtRatioA, sqrtRatioB, new(big.Int).Neg(liquidity), false))
+	}
+	return GetAmount0Delta(sqrtRatioA, sqrtRatioB, liquidity, true)
+}
+
+// GetAmount1DeltaSigned is GetAmount1Delta for a liquidity change, rounded up when liquidity is added
+// and negative when it is removed.
+func GetAmount1DeltaSigned(sqrtRatioA, sqrtRatioB, liquidity *big.Int) *big.Int {
+	if liquidity.Sign() < 0 {
+		return new(big.Int).Neg(GetAmount1Delta(sqrtRatioA, sqrtRatioB, new(big.Int).Neg(liquidity), false))
+	}
+	return GetAmount1Delta(sqrtRatioA, sqrtRatioB, liquidity, true)
+}
+
+// getNextSqrtPriceFromAmount0RoundingUp moves the price by amount of token0, added to or removed from the pool.
+// The 256 bits overflows of the contract are reproduced, as they change the rounding.
+func getNextSqrtPriceFromAmount0RoundingUp(sqrtPX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if amount.Sign() == 0 {
+		return new(big.Int).Set(sqrtPX96), nil
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	product := new(big.Int).Mul(amount, sqrtPX96)
+	if add {
+		if product.Cmp(maxUint256) <= 0 {
+			denominator := new(big.Int).Add(numerator1, product)
+			if denominator.Cmp(maxUint256) <= 0 {
+				return MulDivRoundingUp(numerator1, sqrtPX96, denominator), nil
+			}
+		}
+		denominator := new(big.Int).Quo(numerator1, sqrtPX96)
+		return divRoundingUp(numerator1, denominator.Add(denominator, amount)), nil
+	}
+	if product.Cmp(maxUint256) > 0 || numerator1.Cmp(product) <= 0 {
+		return nil, ErrNotEnoughReserve
+	}
+	next := MulDivRoundingUp(numerator1, sqrtPX96, new(big.Int).Sub(numerator1, product))
+	if next.BitLen() > 160 {
+		return nil, ErrPriceOverflow
+	}
+	return next, nil
+}
+
+// getNextSqrtPriceFromAmount1RoundingDown moves the price by amount of token1, added to or removed from the pool.
+func getNextSqrtPriceFromAmount1RoundingDown(sqrtPX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	shifted := new(big.Int).Lsh(amount, 96)
+	if add {
+		next := new(big.Int).Add(sqrtPX96, shifted.Quo(shifted, liquidity))
+		if next.BitLen() > 160 {
+			return nil, ErrPriceOverflow
+		}
+		return next, nil
+	}
+	quotient := divRoundingUp(shifted, liquidity)
+	if sqrtPX96.Cmp(quotient) <= 0 {
+		return nil, ErrNotEnoughReserve
+	}
+	return quotient.Sub(sqrtPX96, quotient), nil
+}
+
+// GetNextSqrtPriceFromInput returns the price after amountIn of token0 (zeroForOne) or token1 is swapped in.
+func GetNextSqrtPriceFromInput(sqrtPX96, liquidity, amountIn *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPX96.Sign() <= 0 || liquidity.Sign() <= 0 {
+		return nil, ErrPriceOverflow
+	}
+	if zeroForOne {
+		return getNextSqrtPriceFromAmount0RoundingUp(sqrtPX96, liquidity, amountIn, true)
+	}
+	return getNextSqrtPriceFromAmount1RoundingDown(sqrtPX96, liquidity, amountIn, true)
+}
+
+// GetNextSqrtPriceFromOutput returns the price after amountOut of token1 (zeroForOne) or token0 is swapped out.
+func GetNextSqrtPriceFromOutput(sqrtPX96, liquidity, amountOut *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPX96.Sign() <= 0 || liquidity.Sign() <= 0 {
+		return nil, ErrPriceOverflow
+	}
+	if zeroForOne {
+		return getNextSqrtPriceFromAmount1RoundingDown(sqrtPX96, liquidity, amountOut, false)
+	}
+	return getNextSqrtPriceFromAmount0RoundingUp(sqrtPX96, liquidity, amountOut, false)
+}
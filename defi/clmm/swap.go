@@ -0,0 +1,264 @@
+package clmm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// FeeDenominator is the denominator of the fees in pips: 3000 is 0.3%.
+const FeeDenominator = 1_000_000
+
+var ErrPriceLimit = errors.New("invalid sqrt price limit")
+
+var feeDenominator = big.NewInt(FeeDenominator)
+
+// ComputeSwapStep swaps within a single tick range, from sqrtRatioCurrent towards sqrtRatioTarget.
+// amountRemaining is positive for an exact input and negative for an exact output, like in V3.
+// It returns the price reached, the amounts in and out and the fee, which is on top of amountIn.
+// A fee of FeeDenominator or more is an error, the contract reverts on it.
+func ComputeSwapStep(sqrtRatioCurrent, sqrtRatioTarget, liquidity, amountRemaining *big.Int, feePips uint32) (
+	sqrtRatioNext, amountIn, amountOut, feeAmount *big.Int, err error) {
+	if feePips >= FeeDenominator {
+		err = fmt.Errorf("fee %d pips", feePips)
+		return
+	}
+	zeroForOne := sqrtRatioCurrent.Cmp(sqrtRatioTarget) >= 0
+	exactIn := amountRemaining.Sign() >= 0
+	fee := big.NewInt(int64(feePips))
+	feeComplement := new(big.Int).Sub(feeDenominator, fee)
+
+	if exactIn {
+		amountRemainingLessFee := MulDiv(amountRemaining, feeComplement, feeDenominator)
+		if zeroForOne {
+			amountIn = GetAmount0Delta(sqrtRatioTarget, sqrtRatioCurrent, liquidity, true)
+		} else {
+			amountIn = GetAmount1Delta(sqrtRatioCurrent, sqrtRatioTarget, liquidity, true)
+		}
+		if amountRemainingLessFee.Cmp(amountIn) >= 0 {
+			sqrtRatioNext = sqrtRatioTarget
+		} else if sqrtRatioNext, err = GetNextSqrtPriceFromInput(sqrtRatioCurrent, liquidity, amountRemainingLessFee, zeroForOne); err != nil {
+			return
+		}
+	} else {
+		if zeroForOne {
+			amountOut = GetAmount1Delta(sqrtRatioTarget, sqrtRatioCurrent, liquidity, false)
+		} else {
+			amountOut = GetAmount0Delta(sqrtRatioCurrent, sqrtRatioTarget, liquidity, false)
+		}
+		if new(big.Int).Neg(amountRemaining).Cmp(amountOut) >= 0 {
+			sqrtRatioNext = sqrtRatioTarget
+		} else if sqrtRatioNext, err = GetNextSqrtPriceFromOutput(sqrtRatioCurrent, liquidity, new(big.Int).Neg(amountRemaining), zeroForOne); err != nil {
+			return
+		}
+	}
+
+	reached := sqrtRatioTarget.Cmp(sqrtRatioNext) == 0
+	if zeroForOne {
+		if !reached || !exactIn {
+			amountIn = GetAmount0Delta(sqrtRatioNext, sqrtRatioCurrent, liquidity, true)
+		}
+		if !reached || exactIn {
+			amountOut = GetAmount1Delta(sqrtRatioNext, sqrtRatioCurrent, liquidity, false)
+		}
+	} else {
+		if !reached || !exactIn {
+			amountIn = GetAmount1Delta(sqrtRatioCurrent, sqrtRatioNext, liquidity, true)
+		}
+		if !reached || exactIn {
+			amountOut = GetAmount0Delta(sqrtRatioCurrent, sqrtRatioNext, liquidity, false)
+		}
+	}
+
+	// the output can't exceed the amount asked
+	if !exactIn && amountOut.Cmp(new(big.Int).Neg(amountRemaining)) > 0 {
+		amountOut = new(big.Int).Neg(amountRemaining)
+	}
+	if exactIn && !reached {
+		// the price didn't reach the target, the rest of the input is the fee
+		feeAmount = new(big.Int).Sub(amountRemaining, amountIn)
+	} else {
+		feeAmount = MulDivRoundingUp(amountIn, fee, feeComplement)
+	}
+	return
+}
+
+// Tick is an initialized tick of a pool, LiquidityNet is the liquidity added when the price crosses it upwards.
+type Tick struct {
+	Index        int32
+	LiquidityNet *big.Int
+}
+
+// Pool is the state of a V3 or V4 pool, enough to simulate its swaps. Ticks are the initialized ticks,
+// sorted by index, as read from the tick bitmap and the ticks of the pool.
+type Pool struct {
+	SqrtPriceX96 *big.Int
+	Tick         int32
+	Liquidity    *big.Int
+	Fee          uint32 // in pips, the LP fee for V4
+	TickSpacing  int32
+	Ticks        []Tick
+}
+
+// SwapResult is the outcome of a simulated swap, amounts being from the pool side like the Swap event:
+// positive for what the pool receives, negative for what it pays.
+type SwapResult struct {
+	Amount0      *big.Int
+	Amount1      *big.Int
+	SqrtPriceX96 *big.Int
+	Tick         int32
+	Liquidity    *big.Int
+	Fee          *big.Int // in the input token, included in its amount
+}
+
+// Swap simulates UniswapV3Pool.swap: amountSpecified is positive for an exact input and negative for
+// an exact output, sqrtPriceLimitX96 may be nil for no limit. The pool is left unchanged.
+func (p *Pool) Swap(zeroForOne bool, amountSpecified, sqrtPriceLimitX96 *big.Int) (*SwapResult, error) {
+	if amountSpecified.Sign() == 0 {
+		return nil, errors.New("zero amount")
+	}
+	if sqrtPriceLimitX96 == nil {
+		if zeroForOne {
+			sqrtPriceLimitX96 = new(big.Int).Add(MinSqrtRatio, one)
+		} else {
+			sqrtPriceLimitX96 = new(big.Int).Sub(MaxSqrtRatio, one)
+		}
+	}
+	if zeroForOne {
+		if sqrtPriceLimitX96.Cmp(p.SqrtPriceX96) >= 0 || sqrtPriceLimitX96.Cmp(MinSqrtRatio) <= 0 {
+			return nil, ErrPriceLimit
+		}
+	} else if sqrtPriceLimitX96.Cmp(p.SqrtPriceX96) <= 0 || sqrtPriceLimitX96.Cmp(MaxSqrtRatio) >= 0 {
+		return nil, ErrPriceLimit
+	}
+	if p.TickSpacing <= 0 {
+		return nil, fmt.Errorf("tick spacing %d", p.TickSpacing)
+	}
+
+	exactIn := amountSpecified.Sign() > 0
+	remaining := new(big.Int).Set(amountSpecified)
+	calculated := new(big.Int)
+	sqrtPrice := new(big.Int).Set(p.SqrtPriceX96)
+	tick := p.Tick
+	liquidity := new(big.Int).Set(p.Liquidity)
+	fees := new(big.Int)
+
+	for remaining.Sign() != 0 && sqrtPrice.Cmp(sqrtPriceLimitX96) != 0 {
+		sqrtPriceStart := sqrtPrice
+		tickNext, initialized := p.nextInitializedTickWithinOneWord(tick, zeroForOne)
+		tickNext = min(max(tickNext, MinTick), MaxTick)
+		sqrtPriceNext, _ := GetSqrtRatioAtTick(tickNext)
+
+		target := sqrtPriceNext
+		if (zeroForOne && sqrtPriceNext.Cmp(sqrtPriceLimitX96) < 0) || (!zeroForOne && sqrtPriceNext.Cmp(sqrtPriceLimitX96) > 0) {
+			target = sqrtPriceLimitX96
+		}
+		next, amountIn, amountOut, feeAmount, err := ComputeSwapStep(sqrtPrice, target, liquidity, remaining, p.Fee)
+		if err != nil {
+			return nil, err
+		}
+		sqrtPrice = next
+		fees.Add(fees, feeAmount)
+		if exactIn {
+			remaining.Sub(remaining, amountIn)
+			remaining.Sub(remaining, feeAmount)
+			calculated.Sub(calculated, amountOut)
+		} else {
+			remaining.Add(remaining, amountOut)
+			calculated.Add(calculated, amountIn)
+			calculated.Add(calculated, feeAmount)
+		}
+
+		if sqrtPrice.Cmp(sqrtPriceNext) == 0 {
+			if initialized {
+				liquidityNet := p.liquidityNet(tickNext)
+				if zeroForOne {
+					liquidity.Sub(liquidity, liquidityNet)
+				} else {
+					liquidity.Add(liquidity, liquidityNet)
+				}
+				if liquidity.Sign() < 0 {
+					return nil, fmt.Errorf("negative liquidity after tick %d", tickNext)
+				}
+			}
+			if zeroForOne {
+				tick = tickNext - 1
+			} else {
+				tick = tickNext
+			}
+		} else if sqrtPrice.Cmp(sqrtPriceStart) != 0 {
+			if tick, err = GetTickAtSqrtRatio(sqrtPrice); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r := &SwapResult{SqrtPriceX96: sqrtPrice, Tick: tick, Liquidity: liquidity, Fee: fees}
+	specified := new(big.Int).Sub(amountSpecified, remaining)
+	if zeroForOne == exactIn {
+		r.Amount0, r.Amount1 = specified, calculated
+	} else {
+		r.Amount0, r.Amount1 = calculated, specified
+	}
+	return r, nil
+}
+
+// QuoteExactInput returns the output of swapping amountIn, and the state after the swap.
+// The output is less than asked when the liquidity runs out.
+func (p *Pool) QuoteExactInput(zeroForOne bool, amountIn *big.Int) (*big.Int, *SwapResult, error) {
+	r, err := p.Swap(zeroForOne, amountIn, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if zeroForOne {
+		return new(big.Int).Neg(r.Amount1), r, nil
+	}
+	return new(big.Int).Neg(r.Amount0), r, nil
+}
+
+// QuoteExactOutput returns the input needed to receive amountOut, and the state after the swap.
+func (p *Pool) QuoteExactOutput(zeroForOne bool, amountOut *big.Int) (*big.Int, *SwapResult, error) {
+	r, err := p.Swap(zeroForOne, new(big.Int).Neg(amountOut), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if zeroForOne {
+		return r.Amount0, r, nil
+	}
+	return r.Amount1, r, nil
+}
+
+// nextInitializedTickWithinOneWord reproduces TickBitmap.nextInitializedTickWithinOneWord on the sorted ticks:
+// the search stops at the end of the 256 ticks word, so that the swap steps are the ones of the contract.
+func (p *Pool) nextInitializedTickWithinOneWord(tick int32, lte bool) (int32, bool) {
+	compressed := tick / p.TickSpacing
+	if tick < 0 && tick%p.TickSpacing != 0 {
+		compressed--
+	}
+	if lte {
+		// the greatest initialized tick <= compressed in the word
+		bound := (compressed >> 8) << 8
+		i := sort.Search(len(p.Ticks), func(i int) bool { return p.Ticks[i].Index > compressed*p.TickSpacing }) - 1
+		if i >= 0 && p.Ticks[i].Index >= bound*p.TickSpacing {
+			return p.Ticks[i].Index, true
+		}
+		return bound * p.TickSpacing, false
+	}
+	// the smallest initialized tick > compressed in the next word
+	compressed++
+	bound := (compressed>>8)<<8 + 255
+	i := sort.Search(len(p.Ticks), func(i int) bool { return p.Ticks[i].Index >= compressed*p.TickSpacing })
+	if i < len(p.Ticks) && p.Ticks[i].Index <= bound*p.TickSpacing {
+		return p.Ticks[i].Index, true
+	}
+	return bound * p.TickSpacing, false
+}
+
+func (p *Pool) liquidityNet(tick int32) *big.Int {
+	i := sort.Search(len(p.Ticks), func(i int) bool { return p.Ticks[i].Index >= tick })
+	if i < len(p.Ticks) && p.Ticks[i].Index == tick {
+		return p.Ticks[i].LiquidityNet
+	}
+	return new(big.Int)
+}
@@ -0,0 +1,111 @@
+// Package clmm is the math of the concentrated liquidity pools of Uniswap V3 and V4, ported from
+// TickMath, FullMath, SqrtPriceMath, SwapMath and LiquidityAmounts with the same rounding, so that
+// swaps can be quoted offline to the wei.
+package clmm
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	MinTick int32 = -887272
+	MaxTick int32 = 887272
+)
+
+var (
+	// MinSqrtRatio is GetSqrtRatioAtTick(MinTick).
+	MinSqrtRatio = big.NewInt(4295128739)
+	// MaxSqrtRatio is GetSqrtRatioAtTick(MaxTick).
+	MaxSqrtRatio, _ = new(big.Int).SetString("1461446703485210103287273052203988822378723970342", 10)
+
+	Q96  = new(big.Int).Lsh(big.NewInt(1), 96)
+	Q128 = new(big.Int).Lsh(big.NewInt(1), 128)
+
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// the factors of GetSqrtRatioAtTick, 1/sqrt(1.0001)^(2^i) in Q128.128
+var sqrtRatioFactors = func() []*big.Int {
+	hexes := []string{
+		"fffcb933bd6fad37aa2d162d1a594001",
+		"fff97272373d413259a46990580e213a",
+		"fff2e50f5f656932ef12357cf3c7fdcc",
+		"ffe5caca7e10e4e61c3624eaa0941cd0",
+		"ffcb9843d60f6159c9db58835c926644",
+		"ff973b41fa98c081472e6896dfb254c0",
+		"ff2ea16466c96a3843ec78b326b52861",
+		"fe5dee046a99a2a811c461f1969c3053",
+		"fcbe86c7900a88aedcffc83b479aa3a4",
+		"f987a7253ac413176f2b074cf7815e54",
+		"f3392b0822b70005940c7a398e4b70f3",
+		"e7159475a2c29b7443b29c7fa6e889d9",
+		"d097f3bdfd2022b8845ad8f792aa5825",
+		"a9f746462d870fdf8a65dc1f90e061e5",
+		"70d869a156d2a1b890bb3df62baf32f7",
+		"31be135f97d08fd981231505542fcfa6",
+		"9aa508b5b7a84e1c677de54f3e99bc9",
+		"5d6af8dedb81196699c329225ee604",
+		"2216e584f5fa1ea926041bedfe98",
+		"48a170391f7dc42444e8fa2",
+	}
+	factors := make([]*big.Int, len(hexes))
+	for i, h := range hexes {
+		factors[i], _ = new(big.Int).SetString(h, 16)
+	}
+	return factors
+}()
+
+// GetSqrtRatioAtTick returns sqrt(1.0001^tick) in Q64.96.
+func GetSqrtRatioAtTick(tick int32) (*big.Int, error) {
+	if tick < MinTick || tick > MaxTick {
+		return nil, fmt.Errorf("tick %d out of [%d, %d]", tick, MinTick, MaxTick)
+	}
+	absTick := int64(tick)
+	if absTick < 0 {
+		absTick = -absTick
+	}
+
+	ratio := new(big.Int)
+	if absTick&1 != 0 {
+		ratio.Set(sqrtRatioFactors[0])
+	} else {
+		ratio.Set(Q128)
+	}
+	for i := 1; i < len(sqrtRatioFactors); i++ {
+		if absTick&(1<<i) != 0 {
+			ratio.Mul(ratio, sqrtRatioFactors[i])
+			ratio.Rsh(ratio, 128)
+		}
+	}
+	if tick > 0 {
+		ratio.Div(maxUint256, ratio)
+	}
+
+	// from Q128.128 to Q64.96, rounding up so that GetTickAtSqrtRatio(GetSqrtRatioAtTick(tick)) == tick
+	rem := new(big.Int)
+	ratio.DivMod(ratio, new(big.Int).Lsh(big.NewInt(1), 32), rem)
+	if rem.Sign() != 0 {
+		ratio.Add(ratio, big.NewInt(1))
+	}
+	return ratio, nil
+}
+
+// GetTickAtSqrtRatio returns the greatest tick whose ratio is less than or equal to sqrtPriceX96,
+// which must be in [MinSqrtRatio, MaxSqrtRatio).
+func GetTickAtSqrtRatio(sqrtPriceX96 *big.Int) (int32, error) {
+	if sqrtPriceX96.Cmp(MinSqrtRatio) < 0 || sqrtPriceX96.Cmp(MaxSqrtRatio) >= 0 {
+		return 0, fmt.Errorf("sqrt ratio %s out of [%s, %s)", sqrtPriceX96, MinSqrtRatio, MaxSqrtRatio)
+	}
+	lo, hi := MinTick, MaxTick
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		ratio, _ := GetSqrtRatioAtTick(mid)
+		if ratio.Cmp(sqrtPriceX96) <= 0 {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,339 @@
+package kline
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dexerlab/utils-go/dal/pmodel"
+	"github.com/pkg/errors"
+)
+
+// ErrTooLate is returned for the trades older than the candles evicted from the Aggregator.
+var ErrTooLate = errors.New("trade older than the evicted candles")
+
+// Candle has the columns shared by the kline tables, Ts being the start of the candle.
+// Following t_kline_*, a trade with a negative amount0 is a buy and one with a positive amount0 a sell.
+// A candle without trades carries the close of the previous candle forward.
+type Candle pmodel.TKline5M
+
+// empty reports whether the candle has no trades.
+func (c *Candle) empty() bool {
+	return c.Buys == 0 && c.Sells == 0
+}
+
+// carry returns the candle at start carrying the close of c forward.
+func (c *Candle) carry(start time.Time) *Candle {
+	return &Candle{
+		Ts:      start,
+		PoolID:  c.PoolID,
+		Openu:   c.Closeu,
+		Highu:   c.Closeu,
+		Lowu:    c.Closeu,
+		Closeu:  c.Closeu,
+		Open01:  c.Close01,
+		High01:  c.Close01,
+		Low01:   c.Close01,
+		Close01: c.Close01,
+	}
+}
+
+// merge adds the volumes of o to c.
+func (c *Candle) merge(o *Candle) {
+	c.Buy0 += o.Buy0
+	c.Sell0 += o.Sell0
+	c.Buy1 += o.Buy1
+	c.Sell1 += o.Sell1
+	c.Buyu += o.Buyu
+	c.Sellu += o.Sellu
+	c.Buys += o.Buys
+	c.Sells += o.Sells
+}
+
+// candle is a candle being built with the times of the first and last trades of each price,
+// so that late trades move the open and the close only when they should.
+type candle struct {
+	Candle
+	firstu, lastu   time.Time
+	first01, last01 time.Time
+}
+
+// add adds the volumes of t, and its prices unless they are 0, which is a price that is not known.
+func (c *candle) add(t *pmodel.TTrade) {
+	if t.Priceu != 0 {
+		addPrice(&c.Openu, &c.Highu, &c.Lowu, &c.Closeu, &c.firstu, &c.lastu, t.Priceu, t.Ts)
+	}
+	if t.Price01 != 0 {
+		addPrice(&c.Open01, &c.High01, &c.Low01, &c.Close01, &c.first01, &c.last01, t.Price01, t.Ts)
+	}
+	c.Buy0 += max(-t.Amount0, 0)
+	c.Sell0 += max(t.Amount0, 0)
+	c.Buy1 += max(-t.Amount1, 0)
+	c.Sell1 += max(t.Amount1, 0)
+	switch {
+	case t.Amount0 < 0:
+		c.Buyu += t.Amountu
+		c.Buys++
+	case t.Amount0 > 0:
+		c.Sellu += t.Amountu
+		c.Sells++
+	}
+}
+
+func addPrice(open, high, low, close *float64, first, last *time.Time, price float64, ts time.Time) {
+	if first.IsZero() {
+		*open, *high, *low, *close = price, price, price, price
+		*first, *last = ts, ts
+		return
+	}
+	*high = max(*high, price)
+	*low = min(*low, price)
+	if ts.Before(*first) {
+		*open, *first = price, ts
+	}
+	if !ts.Before(*last) {
+		*close, *last = price, ts
+	}
+}
+
+type seriesKey struct {
+	interval Interval
+	poolID   int64
+}
+
+// series are the candles of a pool for an interval, keyed by their start in unix nanoseconds.
+type series struct {
+	candles map[int64]*candle
+	dirty   map[int64]bool
+	filled  time.Time // start of the last candle flushed
+	evicted *candle   // latest evicted candle, carried forward when nothing follows it
+}
+
+// Aggregator builds the candles of every interval from TTrade rows, in memory.
+// Trades may come late and out of order: Flush returns the candles they changed,
+// to be upserted on (ts, pool_id). Candles without trades are filled by carrying the
+// previous close forward. An Aggregator is not safe for concurrent use.
+type Aggregator struct {
+	intervals     []Interval
+	series        map[seriesKey]*series
+	evictedBefore time.Time
+}
+
+// NewAggregator aggregates the trades for intervals, all the intervals if none is given.
+func NewAggregator(intervals ...Interval) *Aggregator {
+	if len(intervals) == 0 {
+		intervals = Intervals
+	}
+	return &Aggregator{intervals: intervals, series: make(map[seriesKey]*series)}
+}
+
+// Add adds trades to the candles. The trades older than the last Evict are dropped with ErrTooLate,
+// the others are added anyway.
+func (a *Aggregator) Add(trades ...*pmodel.TTrade) error {
+	late := 0
+	for _, t := range trades {
+		if t.Ts.Before(a.evictedBefore) {
+			late++
+			continue
+		}
+		for _, i := range a.intervals {
+			s := a.get(i, t.PoolID)
+			start := i.Start(t.Ts)
+			key := start.UnixNano()
+			c, ok := s.candles[key]
+			if !ok {
+				c = &candle{Candle: Candle{Ts: start, PoolID: t.PoolID}}
+				s.candles[key] = c
+			}
+			c.add(t)
+			s.dirty[key] = true
+		}
+	}
+	if late > 0 {
+		return errors.Wrap(ErrTooLate, fmt.Sprintf("%d trades before %s", late, a.evictedBefore.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+func (a *Aggregator) get(i Interval, poolID int64) *series {
+	key := seriesKey{interval: i, poolID: poolID}
+	s, ok := a.series[key]
+	if !ok {
+		s = &series{candles: make(map[int64]*candle), dirty: make(map[int64]bool)}
+		a.series[key] = s
+	}
+	return s
+}
+
+// Candle returns the candle of the pool containing ts, false if the pool had no trade before its end.
+func (a *Aggregator) Candle(i Interval, poolID int64, ts time.Time) (*Candle, bool) {
+	s, ok := a.series[seriesKey{interval: i, poolID: poolID}]
+	if !ok {
+		return nil, false
+	}
+	start := i.Start(ts)
+	if c, ok := s.candles[start.UnixNano()]; ok {
+		c := c.Candle
+		return &c, true
+	}
+	if prev := s.before(start); prev != nil {
+		return prev.carry(start), true
+	}
+	return nil, false
+}
+
+// Flush returns, by interval, the candles changed since the last Flush and the candles filling
+// the gaps up to the one containing until, sorted by pool and time.
+func (a *Aggregator) Flush(until time.Time) map[Interval][]*Candle {
+	flushed := make(map[Interval][]*Candle)
+	for key, s := range a.series {
+		flushed[key.interval] = append(flushed[key.interval], s.flush(key.interval, until)...)
+	}
+	for _, candles := range flushed {
+		sortCandles(candles)
+	}
+	return flushed
+}
+
+func (s *series) flush(i Interval, until time.Time) []*Candle {
+	var from time.Time
+	if !s.filled.IsZero() {
+		from = i.Next(s.filled)
+	}
+	end := i.Start(until)
+	for key := range s.dirty {
+		start := time.Unix(0, key).UTC()
+		if from.IsZero() || start.Before(from) {
+			from = start
+		}
+		if start.After(end) {
+			end = start
+		}
+	}
+	if from.IsZero() {
+		return nil
+	}
+
+	var out []*Candle
+	prev := s.before(from)
+	changed := false
+	for start := from; !start.After(end); start = i.Next(start) {
+		key := start.UnixNano()
+		if c, ok := s.candles[key]; ok {
+			changed = s.dirty[key]
+			if changed {
+				cc := c.Candle
+				out = append(out, &cc)
+			}
+			prev = &c.Candle
+			continue
+		}
+		if prev != nil && (changed || start.After(s.filled)) {
+			out = append(out, prev.carry(start))
+		}
+	}
+	if end.After(s.filled) {
+		s.filled = end
+	}
+	clear(s.dirty)
+	return out
+}
+
+// before returns the latest candle with trades starting before start, nil if there is none.
+func (s *series) before(start time.Time) *Candle {
+	var prev *candle
+	for _, c := range s.candles {
+		if c.Ts.Before(start) && (prev == nil || c.Ts.After(prev.Ts)) {
+			prev = c
+		}
+	}
+	if prev == nil && s.evicted != nil && s.evicted.Ts.Before(start) {
+		prev = s.evicted
+	}
+	if prev == nil {
+		return nil
+	}
+	return &prev.Candle
+}
+
+// Evict drops the candles ending before before, keeping the ones not flushed yet.
+// Trades older than before are refused afterwards, as the candles they belong to are gone.
+func (a *Aggregator) Evict(before time.Time) {
+	if before.After(a.evictedBefore) {
+		a.evictedBefore = before
+	}
+	for key, s := range a.series {
+		for k, c := range s.candles {
+			if s.dirty[k] || key.interval.Next(c.Ts).After(before) {
+				continue
+			}
+			if s.evicted == nil || c.Ts.After(s.evicted.Ts) {
+				s.evicted = c
+			}
+			delete(s.candles, k)
+		}
+	}
+}
+
+// Rollup aggregates the candles of the interval small into candles of the interval large,
+// such as 5m candles into 1h candles. The carried forward candles only count when they are
+// all that a large candle has.
+func Rollup(small, large Interval, candles []*Candle) ([]*Candle, error) {
+	if !large.Contains(small) {
+		return nil, fmt.Errorf("can't roll %s candles up to %s", small, large)
+	}
+	sorted := append([]*Candle{}, candles...)
+	sortCandles(sorted)
+
+	var out []*Candle
+	var cur *Candle
+	tradedu, traded01 := false, false
+	for _, c := range sorted {
+		start := large.Start(c.Ts)
+		if cur == nil || cur.PoolID != c.PoolID || !cur.Ts.Equal(start) {
+			cur = c.carry(start)
+			cur.Openu, cur.Open01 = c.Openu, c.Open01
+			tradedu, traded01 = false, false
+			out = append(out, cur)
+		}
+		if c.empty() {
+			if !tradedu {
+				cur.Highu, cur.Lowu, cur.Closeu = c.Closeu, c.Closeu, c.Closeu
+			}
+			if !traded01 {
+				cur.High01, cur.Low01, cur.Close01 = c.Close01, c.Close01, c.Close01
+			}
+			continue
+		}
+		// the candles whose trades have no price have a price of 0
+		if c.Closeu != 0 {
+			rollupPrice(&cur.Openu, &cur.Highu, &cur.Lowu, &cur.Closeu, tradedu, c.Openu, c.Highu, c.Lowu, c.Closeu)
+			tradedu = true
+		}
+		if c.Close01 != 0 {
+			rollupPrice(&cur.Open01, &cur.High01, &cur.Low01, &cur.Close01, traded01, c.Open01, c.High01, c.Low01, c.Close01)
+			traded01 = true
+		}
+		cur.merge(c)
+	}
+	return out, nil
+}
+
+func rollupPrice(open, high, low, close *float64, traded bool, o, h, l, c float64) {
+	if !traded {
+		*open, *high, *low = o, h, l
+	} else {
+		*high = max(*high, h)
+		*low = min(*low, l)
+	}
+	*close = c
+}
+
+func sortCandles(candles []*Candle) {
+	sort.Slice(candles, func(i, j int) bool {
+		if candles[i].PoolID != candles[j].PoolID {
+			return candles[i].PoolID < candles[j].PoolID
+		}
+		return candles[i].Ts.Before(candles[j].Ts)
+	})
+}
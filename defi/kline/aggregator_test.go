@@ -0,0 +1,139 @@
+package kline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dexerlab/utils-go/dal/pmodel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalStart(t *testing.T) {
+	ts := time.Date(2024, time.February, 29, 13, 47, 12, 0, time.UTC) // a thursday
+	assert.Equal(t, time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC), Interval5M.Start(ts))
+	assert.Equal(t, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), Interval4H.Start(ts))
+	assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), Interval1W.Start(ts))
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Interval1Mo.Start(ts))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Interval1Mo.Next(Interval1Mo.Start(ts)))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Interval1Y.Start(ts))
+	assert.Equal(t, "t_kline_1mo", Interval1Mo.Table())
+	assert.IsType(t, &pmodel.TKline1H{}, Interval1H.Row(&Candle{}))
+
+	assert.True(t, Interval1H.Contains(Interval5M))
+	assert.True(t, Interval1Mo.Contains(Interval1D))
+	assert.False(t, Interval1Mo.Contains(Interval1W))
+	assert.True(t, Interval1Y.Contains(Interval1Mo))
+	assert.False(t, Interval5M.Contains(Interval1H))
+}
+
+func TestAggregator(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	trade := func(min int, priceu, amount0 float64) *pmodel.TTrade {
+		return &pmodel.TTrade{Ts: t0.Add(time.Duration(min) * time.Minute), PoolID: 7, Priceu: priceu, Price01: priceu / 2, Amount0: amount0, Amount1: -amount0 * priceu, Amountu: abs(amount0 * priceu)}
+	}
+	a := NewAggregator(Interval5M, Interval1H)
+	assert.NoError(t, a.Add(trade(1, 10, -1), trade(3, 12, 2), trade(2, 8, -1)))
+
+	c, ok := a.Candle(Interval5M, 7, t0)
+	assert.True(t, ok)
+	assert.Equal(t, &Candle{
+		Ts: t0, PoolID: 7,
+		Openu: 10, Highu: 12, Lowu: 8, Closeu: 12,
+		Open01: 5, High01: 6, Low01: 4, Close01: 6,
+		Buy0: 2, Sell0: 2, Buy1: 24, Sell1: 18,
+		Buyu: 18, Sellu: 24, Buys: 2, Sells: 1,
+	}, c)
+
+	// a gap of two candles is carried forward
+	assert.NoError(t, a.Add(trade(16, 20, 1)))
+	flushed := a.Flush(t0.Add(20 * time.Minute))
+	assert.Len(t, flushed[Interval5M], 5)
+	assert.Equal(t, 12.0, flushed[Interval5M][1].Openu)
+	assert.Equal(t, 12.0, flushed[Interval5M][2].Closeu)
+	assert.Zero(t, flushed[Interval5M][2].Buys)
+	assert.Equal(t, 20.0, flushed[Interval5M][3].Closeu)
+	assert.Equal(t, t0.Add(20*time.Minute), flushed[Interval5M][4].Ts)
+	assert.Len(t, flushed[Interval1H], 1)
+	assert.Equal(t, 20.0, flushed[Interval1H][0].Closeu)
+
+	// nothing changed, only the new empty candles
+	flushed = a.Flush(t0.Add(30 * time.Minute))
+	assert.Len(t, flushed[Interval5M], 2)
+	assert.Equal(t, 20.0, flushed[Interval5M][1].Openu)
+	assert.Empty(t, flushed[Interval1H])
+
+	// a late trade changes its candle and the empty candles following it
+	assert.NoError(t, a.Add(trade(6, 15, -1)))
+	flushed = a.Flush(t0.Add(30 * time.Minute))
+	assert.Len(t, flushed[Interval5M], 2)
+	assert.Equal(t, t0.Add(5*time.Minute), flushed[Interval5M][0].Ts)
+	assert.Equal(t, 15.0, flushed[Interval5M][1].Closeu)
+	assert.Equal(t, 20.0, flushed[Interval1H][0].Closeu)
+	assert.Equal(t, int64(3), flushed[Interval1H][0].Buys)
+
+	a.Evict(t0.Add(10 * time.Minute))
+	_, ok = a.Candle(Interval5M, 7, t0)
+	assert.False(t, ok)
+	c, ok = a.Candle(Interval5M, 7, t0.Add(12*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 15.0, c.Closeu)
+	assert.ErrorIs(t, a.Add(trade(9, 1, 1)), ErrTooLate)
+}
+
+func TestRollup(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	trades := []*pmodel.TTrade{
+		{Ts: t0.Add(7 * time.Minute), PoolID: 1, Priceu: 3, Price01: 3, Amount0: -1, Amountu: 3},
+		{Ts: t0.Add(21 * time.Minute), PoolID: 1, Priceu: 1, Price01: 1, Amount0: 2, Amountu: 2},
+		{Ts: t0.Add(48 * time.Minute), PoolID: 1, Priceu: 4, Price01: 4, Amount0: -3, Amountu: 12},
+		{Ts: t0.Add(67 * time.Minute), PoolID: 1, Priceu: 5, Price01: 5, Amount0: 1, Amountu: 5},
+		{Ts: t0.Add(3 * time.Minute), PoolID: 2, Priceu: 9, Price01: 9, Amount0: 1, Amountu: 9},
+	}
+	a := NewAggregator(Interval5M, Interval1H)
+	assert.NoError(t, a.Add(trades...))
+	flushed := a.Flush(t0.Add(70 * time.Minute))
+
+	// the carried forward 5m candles before the first trade of the hour don't set its open
+	rolled, err := Rollup(Interval5M, Interval1H, flushed[Interval5M])
+	assert.NoError(t, err)
+	assert.Equal(t, flushed[Interval1H], rolled)
+
+	_, err = Rollup(Interval1W, Interval1Mo, nil)
+	assert.Error(t, err)
+}
+
+func abs(x float64) float64 {
+	return max(x, -x)
+}
+
+func TestAggregatorZeroPrice(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAggregator(Interval5M)
+	// the first and the last trades have no usd price, the second no price01
+	assert.NoError(t, a.Add(
+		&pmodel.TTrade{Ts: t0, PoolID: 7, Price01: 2, Amount0: -1},
+		&pmodel.TTrade{Ts: t0.Add(time.Minute), PoolID: 7, Priceu: 10, Amount0: 1, Amountu: 10},
+		&pmodel.TTrade{Ts: t0.Add(2 * time.Minute), PoolID: 7, Priceu: 12, Price01: 3, Amount0: -1, Amountu: 12},
+		&pmodel.TTrade{Ts: t0.Add(3 * time.Minute), PoolID: 7, Price01: 1, Amount0: 2},
+	))
+	c, ok := a.Candle(Interval5M, 7, t0)
+	assert.True(t, ok)
+	assert.Equal(t, &Candle{
+		Ts: t0, PoolID: 7,
+		Openu: 10, Highu: 12, Lowu: 10, Closeu: 12,
+		Open01: 2, High01: 3, Low01: 1, Close01: 1,
+		Buy0: 2, Sell0: 3, Buyu: 12, Sellu: 10, Buys: 2, Sells: 2,
+	}, c)
+
+	// a candle without usd price doesn't change the usd prices of the rollup
+	rolled, err := Rollup(Interval5M, Interval1H, []*Candle{
+		c,
+		{Ts: t0.Add(5 * time.Minute), PoolID: 7, Open01: 4, High01: 4, Low01: 4, Close01: 4, Buy0: 1, Buys: 1},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rolled, 1)
+	assert.Equal(t, 10.0, rolled[0].Lowu)
+	assert.Equal(t, 12.0, rolled[0].Closeu)
+	assert.Equal(t, 4.0, rolled[0].Close01)
+	assert.Equal(t, int64(3), rolled[0].Buys)
+}
@@ -0,0 +1,128 @@
+package kline
+
+import (
+	"time"
+
+	"github.com/dexerlab/utils-go/dal/pmodel"
+)
+
+// Interval is the period of a kline table.
+type Interval int
+
+const (
+	Interval5M Interval = iota
+	Interval15M
+	Interval30M
+	Interval1H
+	Interval4H
+	Interval12H
+	Interval1D
+	Interval1W
+	Interval1Mo
+	Interval1Y
+)
+
+// Intervals are all the intervals, from the smallest to the largest.
+var Intervals = []Interval{
+	Interval5M, Interval15M, Interval30M, Interval1H, Interval4H,
+	Interval12H, Interval1D, Interval1W, Interval1Mo, Interval1Y,
+}
+
+var intervalNames = []string{"5m", "15m", "30m", "1h", "4h", "12h", "1d", "1w", "1mo", "1y"}
+
+// durations of the intervals with a fixed length, 0 for months and years.
+var intervalDurations = []time.Duration{
+	5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour, 4 * time.Hour,
+	12 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 0, 0,
+}
+
+func (i Interval) String() string {
+	if i < 0 || int(i) >= len(intervalNames) {
+		return "unknown"
+	}
+	return intervalNames[i]
+}
+
+// Table returns the kline table of the interval, like t_kline_5m.
+func (i Interval) Table() string {
+	return "t_kline_" + i.String()
+}
+
+// Start returns the start of the candle containing ts. Candles are aligned to the UTC calendar
+// like SAMPLE BY ... ALIGN TO CALENDAR: weeks start on Monday, months and years on their first day.
+func (i Interval) Start(ts time.Time) time.Time {
+	ts = ts.UTC()
+	switch i {
+	case Interval1W:
+		day := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Interval1Mo:
+		return time.Date(ts.Year(), ts.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case Interval1Y:
+		return time.Date(ts.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return ts.Truncate(intervalDurations[i])
+}
+
+// Next returns the start of the candle following the one starting at start.
+func (i Interval) Next(start time.Time) time.Time {
+	switch i {
+	case Interval1Mo:
+		return start.AddDate(0, 1, 0)
+	case Interval1Y:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.Add(intervalDurations[i])
+}
+
+// Contains reports whether every candle of the interval small fits in a candle of i,
+// which is what Rollup needs.
+func (i Interval) Contains(small Interval) bool {
+	if small >= i {
+		return false
+	}
+	switch i {
+	case Interval1Mo:
+		return small <= Interval1D
+	case Interval1Y:
+		return small <= Interval1D || small == Interval1Mo
+	}
+	return intervalDurations[i]%intervalDurations[small] == 0
+}
+
+// Row returns the candle as a row of the kline table of the interval, such as *pmodel.TKline1H.
+func (i Interval) Row(c *Candle) any {
+	switch i {
+	case Interval5M:
+		row := pmodel.TKline5M(*c)
+		return &row
+	case Interval15M:
+		row := pmodel.TKline15M(*c)
+		return &row
+	case Interval30M:
+		row := pmodel.TKline30M(*c)
+		return &row
+	case Interval1H:
+		row := pmodel.TKline1H(*c)
+		return &row
+	case Interval4H:
+		row := pmodel.TKline4H(*c)
+		return &row
+	case Interval12H:
+		row := pmodel.TKline12H(*c)
+		return &row
+	case Interval1D:
+		row := pmodel.TKline1D(*c)
+		return &row
+	case Interval1W:
+		row := pmodel.TKline1W(*c)
+		return &row
+	case Interval1Mo:
+		row := pmodel.TKline1Mo(*c)
+		return &row
+	case Interval1Y:
+		row := pmodel.TKline1Y(*c)
+		return &row
+	}
+	return nil
+}
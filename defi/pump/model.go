@@ -0,0 +1,52 @@
+package pump
+
+import (
+	"math"
+	"time"
+
+	"github.com/dexerlab/utils-go/dal/pmodel"
+)
+
+// TTrade returns the trade as a t_trade row of pool poolID, token0 being the base mint and token1 the quote mint.
+// The amounts are what entered the pool, negative for what left it, quotePriceu is the usd price of the quote mint.
+func (t *Trade) TTrade(poolID int64, baseDecimals, quoteDecimals int32, quotePriceu float64) *pmodel.TTrade {
+	base := toFloat(t.BaseAmount, baseDecimals)
+	quote := toFloat(t.QuoteAmount, quoteDecimals)
+	row := &pmodel.TTrade{
+		Ts:      time.Unix(t.Timestamp, 0).UTC(),
+		PoolID:  poolID,
+		Amount0: base,
+		Amount1: -quote,
+		Amountu: quote * quotePriceu,
+	}
+	if t.IsBuy {
+		row.Amount0, row.Amount1 = -base, quote
+	}
+	if base != 0 {
+		row.Price01 = quote / base
+		row.Priceu = row.Price01 * quotePriceu
+	}
+	return row
+}
+
+// TLiquidityModify returns the liquidity change as a t_liquidity_modify row of pool poolID,
+// with the same conventions as Trade.TTrade.
+func (l *Liquidity) TLiquidityModify(poolID int64, baseDecimals, quoteDecimals int32, quotePriceu float64) *pmodel.TLiquidityModify {
+	base := toFloat(l.BaseAmount, baseDecimals)
+	quote := toFloat(l.QuoteAmount, quoteDecimals)
+	row := &pmodel.TLiquidityModify{
+		Ts:      time.Unix(l.Timestamp, 0).UTC(),
+		PoolID:  poolID,
+		Amount0: base,
+		Amount1: quote,
+		Amountu: 2 * quote * quotePriceu,
+	}
+	if !l.IsDeposit {
+		row.Amount0, row.Amount1 = -base, -quote
+	}
+	return row
+}
+
+func toFloat(amount uint64, decimals int32) float64 {
+	return float64(amount) / math.Pow10(int(decimals))
+}
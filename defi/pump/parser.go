@@ -0,0 +1,465 @@
+package pump
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+
+	pumpfun "github.com/dexerlab/utils-go/abi/swap/pump/fun"
+	pumpswap "github.com/dexerlab/utils-go/abi/swap/pump/swap"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// eventIxTag prefixes the data of the instructions Anchor programs invoke on themselves to emit events (emit_cpi!).
+var eventIxTag = []byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}
+
+// Trade is a buy or a sell on a pump.fun bonding curve or a PumpSwap pool.
+type Trade struct {
+	Program   solana.PublicKey // pumpfun.ProgramID or pumpswap.ProgramID
+	Signature string
+	Slot      uint64
+	Index     int // order of the event in the transaction
+	Timestamp int64
+	// Pool is the bonding curve on pump.fun.
+	Pool      solana.PublicKey
+	Mint      solana.PublicKey // base mint
+	QuoteMint solana.PublicKey // wrapped SOL on pump.fun
+	User      solana.PublicKey
+	IsBuy     bool
+	// BaseAmount and QuoteAmount are what the curve or the pool traded, fees excluded.
+	BaseAmount  uint64
+	QuoteAmount uint64
+	// Fee goes to the protocol and the liquidity providers, CreatorFee to the coin creator, both in quote.
+	Fee        uint64
+	CreatorFee uint64
+}
+
+// Create is the launch of a coin on pump.fun.
+type Create struct {
+	Signature            string
+	Slot                 uint64
+	Index                int
+	Timestamp            int64
+	Mint                 solana.PublicKey
+	BondingCurve         solana.PublicKey
+	User                 solana.PublicKey
+	Creator              solana.PublicKey
+	Name                 string
+	Symbol               string
+	Uri                  string
+	TokenTotalSupply     uint64
+	VirtualTokenReserves uint64
+	VirtualSolReserves   uint64
+}
+
+// Migration is a bonding curve reaching its end, Pool is zero until its liquidity moves to PumpSwap.
+type Migration struct {
+	Signature    string
+	Slot         uint64
+	Index        int
+	Timestamp    int64
+	Mint         solana.PublicKey
+	BondingCurve solana.PublicKey
+	Pool         solana.PublicKey
+	User         solana.PublicKey
+	MintAmount   uint64
+	SolAmount    uint64
+	Fee          uint64
+}
+
+// Liquidity is a pool creation, a deposit or a withdrawal on PumpSwap.
+type Liquidity struct {
+	Signature   string
+	Slot        uint64
+	Index       int
+	Timestamp   int64
+	Pool        solana.PublicKey
+	Mint        solana.PublicKey
+	QuoteMint   solana.PublicKey
+	User        solana.PublicKey
+	IsDeposit   bool
+	BaseAmount  uint64
+	QuoteAmount uint64
+	LpAmount    uint64
+}
+
+// Parsed is what ParseTransaction found in a transaction, in the order of the events.
+type Parsed struct {
+	Trades      []*Trade
+	Creates     []*Create
+	Migrations  []*Migration
+	Liquidities []*Liquidity
+}
+
+// invocation is an instruction of pump.fun or PumpSwap and the events it emitted.
+type invocation struct {
+	program  solana.PublicKey
+	accounts []solana.PublicKey
+	events   [][]byte
+}
+
+// ParseTransaction decodes the pump.fun and PumpSwap events of a successful transaction,
+// whether they are called directly or through another program.
+// The events are read from the self-CPI instructions, or from the "Program data:" logs
+// of the transactions older than emit_cpi!.
+func ParseTransaction(result *rpc.GetTransactionResult) (*Parsed, error) {
+	parsed := &Parsed{}
+	if result == nil || result.Transaction == nil || result.Meta == nil || result.Meta.Err != nil {
+		return parsed, nil
+	}
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.Signatures) == 0 {
+		return nil, fmt.Errorf("transaction without signature")
+	}
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	keys = append(keys, result.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, result.Meta.LoadedAddresses.ReadOnly...)
+
+	invocations, cpiEvents, err := collectInvocations(tx, result.Meta, keys)
+	if err != nil {
+		return nil, err
+	}
+	if !cpiEvents {
+		collectLogEvents(invocations, result.Meta.LogMessages)
+	}
+
+	p := &parser{
+		parsed:    parsed,
+		signature: tx.Signatures[0].String(),
+		slot:      result.Slot,
+	}
+	for _, inv := range invocations {
+		for _, data := range inv.events {
+			p.decode(inv, data)
+		}
+	}
+	return parsed, nil
+}
+
+// collectInvocations lists the pump.fun and PumpSwap instructions in execution order
+// and attaches the self-CPI events to the instruction that emitted them.
+func collectInvocations(tx *solana.Transaction, meta *rpc.TransactionMeta, keys solana.PublicKeySlice) ([]*invocation, bool, error) {
+	var invocations []*invocation
+	cpiEvents := false
+	last := make(map[solana.PublicKey]*invocation)
+	visit := func(programIDIndex uint16, accounts []uint16, data []byte) error {
+		if int(programIDIndex) >= len(keys) {
+			return fmt.Errorf("program id index %d out of %d keys", programIDIndex, len(keys))
+		}
+		program := keys[programIDIndex]
+		if program != pumpfun.ProgramID && program != pumpswap.ProgramID {
+			return nil
+		}
+		if bytes.HasPrefix(data, eventIxTag) {
+			cpiEvents = true
+			if inv := last[program]; inv != nil {
+				inv.events = append(inv.events, data[len(eventIxTag):])
+			}
+			return nil
+		}
+		inv := &invocation{program: program, accounts: make([]solana.PublicKey, len(accounts))}
+		for i, idx := range accounts {
+			if int(idx) >= len(keys) {
+				return fmt.Errorf("account index %d out of %d keys", idx, len(keys))
+			}
+			inv.accounts[i] = keys[idx]
+		}
+		invocations = append(invocations, inv)
+		last[program] = inv
+		return nil
+	}
+
+	for i, inst := range tx.Message.Instructions {
+		if err := visit(inst.ProgramIDIndex, inst.Accounts, inst.Data); err != nil {
+			return nil, false, err
+		}
+		for _, inner := range meta.InnerInstructions {
+			if int(inner.Index) != i {
+				continue
+			}
+			for _, innerInst := range inner.Instructions {
+				if err := visit(innerInst.ProgramIDIndex, innerInst.Accounts, innerInst.Data); err != nil {
+					return nil, false, err
+				}
+			}
+		}
+	}
+	return invocations, cpiEvents, nil
+}
+
+// collectLogEvents attaches the "Program data:" logs to the instructions that wrote them,
+// the n-th invocation of a program in the logs being its n-th instruction.
+func collectLogEvents(invocations []*invocation, logs []string) {
+	byProgram := make(map[solana.PublicKey][]*invocation)
+	for _, inv := range invocations {
+		byProgram[inv.program] = append(byProgram[inv.program], inv)
+	}
+	seen := make(map[solana.PublicKey]int)
+	var stack []*invocation // nil for the other programs
+	for _, line := range logs {
+		switch {
+		case strings.HasPrefix(line, "Program data: "):
+			if len(stack) == 0 || stack[len(stack)-1] == nil {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "Program data: "))
+			if err != nil {
+				continue
+			}
+			inv := stack[len(stack)-1]
+			inv.events = append(inv.events, data)
+		case strings.HasPrefix(line, "Program ") && strings.Contains(line, " invoke ["):
+			program, err := solana.PublicKeyFromBase58(strings.Fields(line)[1])
+			var inv *invocation
+			if err == nil {
+				if invs := byProgram[program]; seen[program] < len(invs) {
+					inv = invs[seen[program]]
+					seen[program]++
+				}
+			}
+			stack = append(stack, inv)
+		case strings.HasPrefix(line, "Program ") && (strings.HasSuffix(line, " success") || strings.Contains(line, " failed")):
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+type parser struct {
+	parsed    *Parsed
+	signature string
+	slot      uint64
+	index     int
+}
+
+// decode appends the event in data, the events it does not know are skipped.
+func (p *parser) decode(inv *invocation, data []byte) {
+	var event any
+	var err error
+	if inv.program == pumpfun.ProgramID {
+		event, err = parseEvent(pumpfun.ParseAnyEvent, data)
+	} else {
+		event, err = parseEvent(pumpswap.ParseAnyEvent, data)
+	}
+	if err != nil {
+		return
+	}
+	index := p.index
+	p.index++
+
+	switch ev := event.(type) {
+	case *pumpfun.TradeEvent:
+		p.parsed.Trades = append(p.parsed.Trades, &Trade{
+			Program:     pumpfun.ProgramID,
+			Signature:   p.signature,
+			Slot:        p.slot,
+			Index:       index,
+			Timestamp:   ev.Timestamp,
+			Pool:        BondingCurveAddress(ev.Mint),
+			Mint:        ev.Mint,
+			QuoteMint:   solana.WrappedSol,
+			User:        ev.User,
+			IsBuy:       ev.IsBuy,
+			BaseAmount:  ev.TokenAmount,
+			QuoteAmount: ev.SolAmount,
+			Fee:         ev.Fee,
+			CreatorFee:  ev.CreatorFee,
+		})
+	case *pumpfun.CreateEvent:
+		p.parsed.Creates = append(p.parsed.Creates, &Create{
+			Signature:            p.signature,
+			Slot:                 p.slot,
+			Index:                index,
+			Timestamp:            ev.Timestamp,
+			Mint:                 ev.Mint,
+			BondingCurve:         ev.BondingCurve,
+			User:                 ev.User,
+			Creator:              ev.Creator,
+			Name:                 ev.Name,
+			Symbol:               ev.Symbol,
+			Uri:                  ev.Uri,
+			TokenTotalSupply:     ev.TokenTotalSupply,
+			VirtualTokenReserves: ev.VirtualTokenReserves,
+			VirtualSolReserves:   ev.VirtualSolReserves,
+		})
+	case *pumpfun.CompleteEvent:
+		p.parsed.Migrations = append(p.parsed.Migrations, &Migration{
+			Signature:    p.signature,
+			Slot:         p.slot,
+			Index:        index,
+			Timestamp:    ev.Timestamp,
+			Mint:         ev.Mint,
+			BondingCurve: ev.BondingCurve,
+			User:         ev.User,
+		})
+	case *pumpfun.CompletePumpAmmMigrationEvent:
+		p.parsed.Migrations = append(p.parsed.Migrations, &Migration{
+			Signature:    p.signature,
+			Slot:         p.slot,
+			Index:        index,
+			Timestamp:    ev.Timestamp,
+			Mint:         ev.Mint,
+			BondingCurve: ev.BondingCurve,
+			Pool:         ev.Pool,
+			User:         ev.User,
+			MintAmount:   ev.MintAmount,
+			SolAmount:    ev.SolAmount,
+			Fee:          ev.PoolMigrationFee,
+		})
+	case *pumpswap.BuyEvent:
+		p.parsed.Trades = append(p.parsed.Trades, &Trade{
+			Program:     pumpswap.ProgramID,
+			Signature:   p.signature,
+			Slot:        p.slot,
+			Index:       index,
+			Timestamp:   ev.Timestamp,
+			Pool:        ev.Pool,
+			Mint:        account(inv, 3),
+			QuoteMint:   account(inv, 4),
+			User:        ev.User,
+			IsBuy:       true,
+			BaseAmount:  ev.BaseAmountOut,
+			QuoteAmount: ev.QuoteAmountIn,
+			Fee:         ev.LpFee + ev.ProtocolFee,
+			CreatorFee:  ev.CoinCreatorFee,
+		})
+	case *pumpswap.SellEvent:
+		p.parsed.Trades = append(p.parsed.Trades, &Trade{
+			Program:     pumpswap.ProgramID,
+			Signature:   p.signature,
+			Slot:        p.slot,
+			Index:       index,
+			Timestamp:   ev.Timestamp,
+			Pool:        ev.Pool,
+			Mint:        account(inv, 3),
+			QuoteMint:   account(inv, 4),
+			User:        ev.User,
+			BaseAmount:  ev.BaseAmountIn,
+			QuoteAmount: ev.QuoteAmountOut,
+			Fee:         ev.LpFee + ev.ProtocolFee,
+			CreatorFee:  ev.CoinCreatorFee,
+		})
+	case *pumpswap.CreatePoolEvent:
+		p.parsed.Liquidities = append(p.parsed.Liquidities, &Liquidity{
+			Signature:   p.signature,
+			Slot:        p.slot,
+			Index:       index,
+			Timestamp:   ev.Timestamp,
+			Pool:        ev.Pool,
+			Mint:        ev.BaseMint,
+			QuoteMint:   ev.QuoteMint,
+			User:        ev.Creator,
+			IsDeposit:   true,
+			BaseAmount:  ev.BaseAmountIn,
+			QuoteAmount: ev.QuoteAmountIn,
+			LpAmount:    ev.LpTokenAmountOut,
+		})
+	case *pumpswap.DepositEvent:
+		p.parsed.Liquidities = append(p.parsed.Liquidities, &Liquidity{
+			Signature:   p.signature,
+			Slot:        p.slot,
+			Index:       index,
+			Timestamp:   ev.Timestamp,
+			Pool:        ev.Pool,
+			Mint:        account(inv, 3),
+			QuoteMint:   account(inv, 4),
+			User:        ev.User,
+			IsDeposit:   true,
+			BaseAmount:  ev.BaseAmountIn,
+			QuoteAmount: ev.QuoteAmountIn,
+			LpAmount:    ev.LpTokenAmountOut,
+		})
+	case *pumpswap.WithdrawEvent:
+		p.parsed.Liquidities = append(p.parsed.Liquidities, &Liquidity{
+			Signature:   p.signature,
+			Slot:        p.slot,
+			Index:       index,
+			Timestamp:   ev.Timestamp,
+			Pool:        ev.Pool,
+			Mint:        account(inv, 3),
+			QuoteMint:   account(inv, 4),
+			User:        ev.User,
+			BaseAmount:  ev.BaseAmountOut,
+			QuoteAmount: ev.QuoteAmountOut,
+			LpAmount:    ev.LpTokenAmountIn,
+		})
+	default:
+		p.index--
+	}
+}
+
+// legacyLayouts are the events emitted before upgrades of the programs, by the first field each upgrade added.
+var legacyLayouts = map[[8]byte][]string{
+	pumpfun.Event_TradeEvent:  {"FeeRecipient", "TrackVolume", "IxName", "MayhemMode"},
+	pumpfun.Event_CreateEvent: {"Creator", "TokenProgram", "IsMayhemMode"},
+	pumpswap.Event_BuyEvent:   {"CoinCreator", "TrackVolume", "MinBaseAmountOut", "IxName"},
+	pumpswap.Event_SellEvent:  {"CoinCreator"},
+}
+
+// parseEvent decodes an event with parse. The events of one of the legacyLayouts are decoded with zeros
+// in place of the fields added since, any other event too short for parse is an error.
+func parseEvent(parse func([]byte) (any, error), data []byte) (any, error) {
+	event, err := parse(data)
+	if err == nil {
+		return event, nil
+	}
+	if len(data) < 8 {
+		return nil, err
+	}
+	cuts, ok := legacyLayouts[[8]byte(data[:8])]
+	if !ok {
+		return nil, err
+	}
+	padded := append(bytes.Clone(data), make([]byte, 512)...)
+	legacy, err2 := parse(padded)
+	if err2 != nil {
+		return nil, err
+	}
+	for _, field := range cuts {
+		if n, ok := encodedLenBefore(legacy, field); ok && n == len(data)-8 {
+			return legacy, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown layout of %d bytes", err, len(data))
+}
+
+// encodedLenBefore returns the length of the borsh encoding of the fields of event before field.
+func encodedLenBefore(event any, field string) (int, bool) {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	var buf bytes.Buffer
+	enc := bin.NewBorshEncoder(&buf)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Name == field {
+			return buf.Len(), true
+		}
+		if err := enc.Encode(v.Field(i).Interface()); err != nil {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// account returns the i-th account of the instruction, the zero key if it has fewer accounts.
+func account(inv *invocation, i int) solana.PublicKey {
+	if i < len(inv.accounts) {
+		return inv.accounts[i]
+	}
+	return solana.PublicKey{}
+}
+
+// BondingCurveAddress returns the pump.fun bonding curve of mint.
+func BondingCurveAddress(mint solana.PublicKey) solana.PublicKey {
+	addr, _, _ := solana.FindProgramAddress([][]byte{[]byte("bonding-curve"), mint[:]}, pumpfun.ProgramID)
+	return addr
+}
@@ -0,0 +1,166 @@
+package pump
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pumpfun "github.com/dexerlab/utils-go/abi/swap/pump/fun"
+	pumpswap "github.com/dexerlab/utils-go/abi/swap/pump/swap"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func eventData(t *testing.T, discriminator [8]byte, event interface{ Marshal() ([]byte, error) }) []byte {
+	body, err := event.Marshal()
+	assert.NoError(t, err)
+	return append(discriminator[:], body...)
+}
+
+func TestParseTransaction(t *testing.T) {
+	router := solana.MustPublicKeyFromBase58("JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4")
+	user := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	pool := solana.NewWallet().PublicKey()
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+
+	trade := eventData(t, pumpfun.Event_TradeEvent, pumpfun.TradeEvent{
+		Mint: mint, SolAmount: 1_000_000_000, TokenAmount: 35_000_000_000_000, IsBuy: true,
+		User: user, Timestamp: 1700000000, Fee: 9_500_000, CreatorFee: 500_000,
+	})
+	sell := eventData(t, pumpswap.Event_SellEvent, pumpswap.SellEvent{
+		Timestamp: 1700000001, BaseAmountIn: 2_000_000, QuoteAmountOut: 3_000_000,
+		LpFee: 600, ProtocolFee: 150, CoinCreatorFee: 50, Pool: pool, User: user,
+	})
+	swapAccounts := solana.AccountMetaSlice{solana.Meta(pool), solana.Meta(user), solana.Meta(router), solana.Meta(mint), solana.Meta(usdc)}
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(router, solana.AccountMetaSlice{solana.Meta(pumpfun.ProgramID), solana.Meta(mint)}, nil),
+		solana.NewInstruction(pumpswap.ProgramID, swapAccounts, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(user))
+	assert.NoError(t, err)
+	tx.Signatures = []solana.Signature{{1}}
+	raw, err := tx.MarshalBinary()
+	assert.NoError(t, err)
+	var envelope rpc.TransactionResultEnvelope
+	assert.NoError(t, json.Unmarshal([]byte(`["`+base64.StdEncoding.EncodeToString(raw)+`","base64"]`), &envelope))
+
+	index := func(key solana.PublicKey) uint16 {
+		idx, err := tx.Message.GetAccountIndex(key)
+		assert.NoError(t, err)
+		return idx
+	}
+	result := &rpc.GetTransactionResult{
+		Slot:        300,
+		Transaction: &envelope,
+		Meta: &rpc.TransactionMeta{
+			InnerInstructions: []rpc.InnerInstruction{
+				{Index: 0, Instructions: []rpc.CompiledInstruction{
+					{ProgramIDIndex: index(pumpfun.ProgramID), Accounts: []uint16{index(mint)}, Data: []byte{2}},
+					{ProgramIDIndex: index(pumpfun.ProgramID), Data: append(append([]byte{}, eventIxTag...), trade...)},
+				}},
+				{Index: 1, Instructions: []rpc.CompiledInstruction{
+					{ProgramIDIndex: index(pumpswap.ProgramID), Data: append(append([]byte{}, eventIxTag...), sell...)},
+				}},
+			},
+		},
+	}
+
+	parsed, err := ParseTransaction(result)
+	assert.NoError(t, err)
+	assert.Len(t, parsed.Trades, 2)
+	assert.Equal(t, &Trade{
+		Program:     pumpfun.ProgramID,
+		Signature:   solana.Signature{1}.String(),
+		Slot:        300,
+		Index:       0,
+		Timestamp:   1700000000,
+		Pool:        BondingCurveAddress(mint),
+		Mint:        mint,
+		QuoteMint:   solana.WrappedSol,
+		User:        user,
+		IsBuy:       true,
+		BaseAmount:  35_000_000_000_000,
+		QuoteAmount: 1_000_000_000,
+		Fee:         9_500_000,
+		CreatorFee:  500_000,
+	}, parsed.Trades[0])
+	swap := parsed.Trades[1]
+	assert.Equal(t, 1, swap.Index)
+	assert.Equal(t, pool, swap.Pool)
+	assert.Equal(t, mint, swap.Mint)
+	assert.Equal(t, usdc, swap.QuoteMint)
+	assert.False(t, swap.IsBuy)
+	assert.Equal(t, uint64(750), swap.Fee)
+	assert.Equal(t, uint64(50), swap.CreatorFee)
+
+	row := parsed.Trades[0].TTrade(7, 6, 9, 150)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), row.Ts)
+	assert.Equal(t, -35_000_000.0, row.Amount0)
+	assert.Equal(t, 1.0, row.Amount1)
+	assert.Equal(t, 150.0, row.Amountu)
+	assert.InDelta(t, 150.0/35_000_000, row.Priceu, 1e-15)
+	row = swap.TTrade(7, 6, 6, 1)
+	assert.Equal(t, 2.0, row.Amount0)
+	assert.Equal(t, -3.0, row.Amount1)
+	assert.Equal(t, 1.5, row.Price01)
+
+	// before emit_cpi!, the events are in the logs
+	result.Meta.InnerInstructions = []rpc.InnerInstruction{{Index: 0, Instructions: []rpc.CompiledInstruction{
+		{ProgramIDIndex: index(pumpfun.ProgramID), Accounts: []uint16{index(mint)}, Data: []byte{2}},
+	}}}
+	result.Meta.LogMessages = []string{
+		"Program " + router.String() + " invoke [1]",
+		"Program " + pumpfun.ProgramID.String() + " invoke [2]",
+		"Program data: " + base64.StdEncoding.EncodeToString(trade),
+		"Program " + pumpfun.ProgramID.String() + " success",
+		"Program data: " + base64.StdEncoding.EncodeToString(sell),
+		"Program " + router.String() + " success",
+		"Program " + pumpswap.ProgramID.String() + " invoke [1]",
+		// before the coin creator fees
+		"Program data: " + base64.StdEncoding.EncodeToString(sell[:len(sell)-48]),
+		"Program " + pumpswap.ProgramID.String() + " success",
+	}
+	parsed, err = ParseTransaction(result)
+	assert.NoError(t, err)
+	assert.Len(t, parsed.Trades, 2)
+	assert.Equal(t, BondingCurveAddress(mint), parsed.Trades[0].Pool)
+	assert.Equal(t, uint64(2_000_000), parsed.Trades[1].BaseAmount)
+	assert.Equal(t, usdc, parsed.Trades[1].QuoteMint)
+
+	result.Meta.Err = map[string]interface{}{"InstructionError": []interface{}{1, "Custom"}}
+	parsed, err = ParseTransaction(result)
+	assert.NoError(t, err)
+	assert.Empty(t, parsed.Trades)
+}
+
+func TestParseEvent(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	trade := eventData(t, pumpfun.Event_TradeEvent, pumpfun.TradeEvent{
+		Mint: mint, SolAmount: 1_000_000_000, IsBuy: true, RealSolReserves: 5, RealTokenReserves: 6,
+		Creator: solana.NewWallet().PublicKey(), IxName: "buy",
+	})
+
+	event, err := parseEvent(pumpfun.ParseAnyEvent, trade)
+	assert.NoError(t, err)
+	assert.Equal(t, "buy", event.(*pumpfun.TradeEvent).IxName)
+
+	// the first layout ends with the real reserves
+	event, err = parseEvent(pumpfun.ParseAnyEvent, trade[:8+121])
+	assert.NoError(t, err)
+	assert.Equal(t, mint, event.(*pumpfun.TradeEvent).Mint)
+	assert.Equal(t, uint64(6), event.(*pumpfun.TradeEvent).RealTokenReserves)
+	assert.True(t, event.(*pumpfun.TradeEvent).Creator.IsZero())
+
+	// the layout before the mayhem mode, then truncated events
+	_, err = parseEvent(pumpfun.ParseAnyEvent, trade[:len(trade)-1])
+	assert.NoError(t, err)
+	_, err = parseEvent(pumpfun.ParseAnyEvent, trade[:8+121-4])
+	assert.Error(t, err)
+	_, err = parseEvent(pumpfun.ParseAnyEvent, trade[:8+130])
+	assert.Error(t, err)
+	_, err = parseEvent(pumpfun.ParseAnyEvent, trade[:len(trade)-2])
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package pump
+
+import (
+	"errors"
+	"math/big"
+
+	pumpfun "github.com/dexerlab/utils-go/abi/swap/pump/fun"
+)
+
+const bpsDenominator = 10_000
+
+var (
+	ErrCurveComplete = errors.New("bonding curve complete")
+	ErrZeroAmount    = errors.New("zero amount")
+)
+
+// Curve is what a bonding curve trade depends on: the BondingCurve account of the mint, the Global
+// account and the FeeConfig account, which may be nil before the fee tiers.
+type Curve struct {
+	BondingCurve *pumpfun.BondingCurve
+	Global       *pumpfun.Global
+	FeeConfig    *pumpfun.FeeConfig
+}
+
+// CurveQuote is a trade on a bonding curve. AmountIn and AmountOut are what the user pays and receives,
+// fees included. PriceImpact includes the fees. BondingCurve is the curve after the trade.
+type CurveQuote struct {
+	AmountIn     uint64
+	AmountOut    uint64
+	Fee          uint64 // protocol fee, in lamports
+	CreatorFee   uint64 // in lamports
+	PriceImpact  float64
+	BondingCurve pumpfun.BondingCurve
+}
+
+// MarketCap returns the market cap of the mint in lamports, at the price of the curve.
+func (c *Curve) MarketCap() *big.Int {
+	bc := c.BondingCurve
+	if bc.VirtualTokenReserves == 0 {
+		return new(big.Int)
+	}
+	return mulDiv(bc.VirtualSolReserves, bc.TokenTotalSupply, bc.VirtualTokenReserves)
+}
+
+// Fees returns the fees of the next trade: the tier of the market cap when there is a FeeConfig,
+// the Global fees before. The creator fee is 0 for the curves without creator.
+func (c *Curve) Fees() pumpfun.Fees {
+	var fees pumpfun.Fees
+	if c.FeeConfig != nil && len(c.FeeConfig.FeeTiers) > 0 {
+		tiers := c.FeeConfig.FeeTiers
+		marketCap := c.MarketCap()
+		fees = tiers[0].Fees
+		for i := len(tiers) - 1; i >= 0; i-- {
+			if marketCap.Cmp(tiers[i].MarketCapLamportsThreshold.BigInt()) >= 0 {
+				fees = tiers[i].Fees
+				break
+			}
+		}
+	} else if c.Global != nil {
+		fees = pumpfun.Fees{ProtocolFeeBps: c.Global.FeeBasisPoints, CreatorFeeBps: c.Global.CreatorFeeBasisPoints}
+	}
+	if c.BondingCurve.Creator.IsZero() {
+		fees.CreatorFeeBps = 0
+	}
+	return fees
+}
+
+// QuoteBuy quotes buying with solAmount lamports, fees included, like the pump.fun sdk.
+// The tokens are capped to the real reserves, which completes the curve.
+func (c *Curve) QuoteBuy(solAmount uint64) (*CurveQuote, error) {
+	if err := c.check(solAmount); err != nil {
+		return nil, err
+	}
+	bc := c.BondingCurve
+	fees := c.Fees()
+	// the fees are taken on top of what goes into the curve
+	solIn := mulDiv(solAmount-1, bpsDenominator, bpsDenominator+fees.ProtocolFeeBps+fees.CreatorFeeBps).Uint64()
+	tokens := mulDiv(solIn, bc.VirtualTokenReserves, bc.VirtualSolReserves+solIn).Uint64()
+	if tokens >= bc.RealTokenReserves {
+		return c.QuoteBuyExactOut(bc.RealTokenReserves)
+	}
+	return c.buy(solIn, tokens, fees), nil
+}
+
+// QuoteBuyExactOut quotes buying tokenAmount tokens, capped to the real reserves of the curve.
+func (c *Curve) QuoteBuyExactOut(tokenAmount uint64) (*CurveQuote, error) {
+	if err := c.check(tokenAmount); err != nil {
+		return nil, err
+	}
+	bc := c.BondingCurve
+	tokens := min(tokenAmount, bc.RealTokenReserves)
+	if tokens >= bc.VirtualTokenReserves {
+		return nil, ErrCurveComplete
+	}
+	solIn := mulDiv(tokens, bc.VirtualSolReserves, bc.VirtualTokenReserves-tokens).Uint64() + 1
+	return c.buy(solIn, tokens, c.Fees()), nil
+}
+
+// QuoteSell quotes selling tokenAmount tokens, the output being net of the fees.
+func (c *Curve) QuoteSell(tokenAmount uint64) (*CurveQuote, error) {
+	if err := c.check(tokenAmount); err != nil {
+		return nil, err
+	}
+	bc := c.BondingCurve
+	fees := c.Fees()
+	solOut := mulDiv(tokenAmount, bc.VirtualSolReserves, bc.VirtualTokenReserves+tokenAmount).Uint64()
+	if solOut > bc.RealSolReserves {
+		return nil, errors.New("sell larger than the real sol reserves")
+	}
+	q := &CurveQuote{
+		AmountIn:     tokenAmount,
+		Fee:          feeOf(solOut, fees.ProtocolFeeBps),
+		CreatorFee:   feeOf(solOut, fees.CreatorFeeBps),
+		BondingCurve: *bc,
+	}
+	q.AmountOut = solOut - min(solOut, q.Fee+q.CreatorFee)
+	q.BondingCurve.VirtualSolReserves -= solOut
+	q.BondingCurve.VirtualTokenReserves += tokenAmount
+	q.BondingCurve.RealSolReserves -= solOut
+	q.BondingCurve.RealTokenReserves += tokenAmount
+	q.PriceImpact = priceImpact(tokenAmount, q.AmountOut, bc.VirtualTokenReserves, bc.VirtualSolReserves)
+	return q, nil
+}
+
+func (c *Curve) buy(solIn, tokens uint64, fees pumpfun.Fees) *CurveQuote {
+	bc := c.BondingCurve
+	q := &CurveQuote{
+		AmountOut:    tokens,
+		Fee:          feeOf(solIn, fees.ProtocolFeeBps),
+		CreatorFee:   feeOf(solIn, fees.CreatorFeeBps),
+		BondingCurve: *bc,
+	}
+	q.AmountIn = solIn + q.Fee + q.CreatorFee
+	q.BondingCurve.VirtualSolReserves += solIn
+	q.BondingCurve.VirtualTokenReserves -= tokens
+	q.BondingCurve.RealSolReserves += solIn
+	q.BondingCurve.RealTokenReserves -= tokens
+	q.BondingCurve.Complete = q.BondingCurve.RealTokenReserves == 0
+	q.PriceImpact = priceImpact(q.AmountIn, tokens, bc.VirtualSolReserves, bc.VirtualTokenReserves)
+	return q
+}
+
+func (c *Curve) check(amount uint64) error {
+	if amount == 0 {
+		return ErrZeroAmount
+	}
+	if c.BondingCurve.Complete || c.BondingCurve.VirtualTokenReserves == 0 {
+		return ErrCurveComplete
+	}
+	return nil
+}
+
+// priceImpact returns 1 - amountOut / (amountIn * reserveOut / reserveIn).
+func priceImpact(amountIn, amountOut, reserveIn, reserveOut uint64) float64 {
+	num := new(big.Int).Mul(new(big.Int).SetUint64(amountOut), new(big.Int).SetUint64(reserveIn))
+	den := new(big.Int).Mul(new(big.Int).SetUint64(amountIn), new(big.Int).SetUint64(reserveOut))
+	if den.Sign() == 0 {
+		return 0
+	}
+	ratio, _ := new(big.Rat).SetFrac(num, den).Float64()
+	return 1 - ratio
+}
+
+// feeOf returns the fee of amount at bps, rounded up like the program.
+func feeOf(amount, bps uint64) uint64 {
+	num := new(big.Int).Mul(new(big.Int).SetUint64(amount), new(big.Int).SetUint64(bps))
+	num.Add(num, big.NewInt(bpsDenominator-1))
+	return num.Quo(num, big.NewInt(bpsDenominator)).Uint64()
+}
+
+func mulDiv(a, b, denominator uint64) *big.Int {
+	r := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return r.Quo(r, new(big.Int).SetUint64(denominator))
+}
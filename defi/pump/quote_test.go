@@ -0,0 +1,67 @@
+package pump
+
+import (
+	"testing"
+
+	pumpfun "github.com/dexerlab/utils-go/abi/swap/pump/fun"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCurve() *Curve {
+	return &Curve{
+		BondingCurve: &pumpfun.BondingCurve{
+			VirtualTokenReserves: 1_073_000_000_000_000,
+			VirtualSolReserves:   30_000_000_000,
+			RealTokenReserves:    793_100_000_000_000,
+			TokenTotalSupply:     1_000_000_000_000_000,
+			Creator:              solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"),
+		},
+		Global: &pumpfun.Global{FeeBasisPoints: 95, CreatorFeeBasisPoints: 5},
+	}
+}
+
+func TestCurveQuote(t *testing.T) {
+	c := newCurve()
+
+	q, err := c.QuoteBuy(1_000_000_000)
+	assert.NoError(t, err)
+	// 1 sol less 1% of fees goes into the curve
+	assert.Equal(t, uint64(990_099_008), q.BondingCurve.VirtualSolReserves-c.BondingCurve.VirtualSolReserves)
+	assert.Equal(t, uint64(34_281_150_096_027), q.AmountOut)
+	assert.Equal(t, uint64(9_405_941), q.Fee)
+	assert.Equal(t, uint64(495_050), q.CreatorFee)
+	assert.LessOrEqual(t, q.AmountIn, uint64(1_000_000_000))
+	assert.InDelta(t, 0.04, q.PriceImpact, 0.01)
+	assert.False(t, q.BondingCurve.Complete)
+
+	// selling the tokens back returns less than paid
+	back := &Curve{BondingCurve: &q.BondingCurve, Global: c.Global}
+	s, err := back.QuoteSell(q.AmountOut)
+	assert.NoError(t, err)
+	assert.Less(t, s.AmountOut, q.AmountIn)
+	assert.Equal(t, c.BondingCurve.VirtualTokenReserves, s.BondingCurve.VirtualTokenReserves)
+
+	// buying more than the reserves completes the curve
+	q, err = c.QuoteBuy(1_000_000_000_000)
+	assert.NoError(t, err)
+	assert.Equal(t, c.BondingCurve.RealTokenReserves, q.AmountOut)
+	assert.True(t, q.BondingCurve.Complete)
+	_, err = (&Curve{BondingCurve: &q.BondingCurve}).QuoteBuy(1)
+	assert.ErrorIs(t, err, ErrCurveComplete)
+}
+
+func TestCurveFeeTiers(t *testing.T) {
+	c := newCurve()
+	c.FeeConfig = &pumpfun.FeeConfig{FeeTiers: []pumpfun.FeeTier{
+		{Fees: pumpfun.Fees{ProtocolFeeBps: 93, CreatorFeeBps: 30}},
+		{MarketCapLamportsThreshold: bin.Uint128{Lo: 100_000_000_000}, Fees: pumpfun.Fees{ProtocolFeeBps: 5, CreatorFeeBps: 5}},
+	}}
+	// the market cap is about 28 sol
+	assert.Equal(t, uint64(93), c.Fees().ProtocolFeeBps)
+	c.BondingCurve.VirtualSolReserves = 120_000_000_000
+	assert.Equal(t, uint64(5), c.Fees().ProtocolFeeBps)
+	c.BondingCurve.Creator = solana.PublicKey{}
+	assert.Equal(t, uint64(0), c.Fees().CreatorFeeBps)
+}
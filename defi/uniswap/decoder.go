@@ -0,0 +1,386 @@
+package uniswap
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	uniswapv2 "github.com/dexerlab/utils-go/abi/swap/uniswap/v2"
+	uniswapv3 "github.com/dexerlab/utils-go/abi/swap/uniswap/v3"
+	uniswapv4 "github.com/dexerlab/utils-go/abi/swap/uniswap/v4"
+	"github.com/dexerlab/utils-go/dal/pmodel"
+	"github.com/dexerlab/utils-go/defi"
+	"github.com/dexerlab/utils-go/defi/clmm"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrNotPoolEvent is returned for the logs that are not a Swap, Mint, Burn, Sync, Initialize or ModifyLiquidity.
+	ErrNotPoolEvent = errors.New("not a uniswap pool event")
+	// ErrUnknownPool is returned when the pool or one of its tokens is not in t_pool_dynamic / t_token_dynamic.
+	ErrUnknownPool = errors.New("unknown pool")
+	// ErrNoPoolPrice is returned for a V4 ModifyLiquidity of a pool whose price was never seen.
+	ErrNoPoolPrice = errors.New("pool price unknown")
+)
+
+var (
+	poolV2ABI, _    = uniswapv2.PoolV2MetaData.GetAbi()
+	poolV3ABI, _    = uniswapv3.PoolV3MetaData.GetAbi()
+	poolMgrV4ABI, _ = uniswapv4.PoolmgrV4MetaData.GetAbi()
+
+	// the filterers only parse logs, they are bound to no contract and no backend
+	poolV2Filterer, _    = uniswapv2.NewPoolV2Filterer(common.Address{}, nil)
+	poolV3Filterer, _    = uniswapv3.NewPoolV3Filterer(common.Address{}, nil)
+	poolMgrV4Filterer, _ = uniswapv4.NewPoolmgrV4Filterer(common.Address{}, nil)
+
+	v2SwapTopic         = poolV2ABI.Events["Swap"].ID
+	v2MintTopic         = poolV2ABI.Events["Mint"].ID
+	v2BurnTopic         = poolV2ABI.Events["Burn"].ID
+	v2SyncTopic         = poolV2ABI.Events["Sync"].ID
+	v3SwapTopic         = poolV3ABI.Events["Swap"].ID
+	v3MintTopic         = poolV3ABI.Events["Mint"].ID
+	v3BurnTopic         = poolV3ABI.Events["Burn"].ID
+	v3InitializeTopic   = poolV3ABI.Events["Initialize"].ID
+	v4SwapTopic         = poolMgrV4ABI.Events["Swap"].ID
+	v4InitializeTopic   = poolMgrV4ABI.Events["Initialize"].ID
+	v4ModifyLiquidTopic = poolMgrV4ABI.Events["ModifyLiquidity"].ID
+)
+
+// PoolTopics are the topics of the events the Decoder knows, to filter the logs of the pools and the V4 PoolManager.
+var PoolTopics = []common.Hash{
+	v2SwapTopic, v2MintTopic, v2BurnTopic, v2SyncTopic,
+	v3SwapTopic, v3MintTopic, v3BurnTopic, v3InitializeTopic,
+	v4SwapTopic, v4InitializeTopic, v4ModifyLiquidTopic,
+}
+
+// DexSource resolves pools and tokens, it is implemented by loader.DexManager.
+type DexSource interface {
+	GetPoolDyn(chainid int64, address string, cache bool, cache404 bool) (loader.PoolDyn, bool, error)
+	GetTokenDynByID(id int64, cache bool, cache404 bool) (loader.TokenDyn, bool, error)
+}
+
+// PoolState is the state of a pool as reported by its events, Reserve0 and Reserve1 for V2,
+// SqrtPriceX96, Tick and Liquidity for V3 and V4.
+type PoolState struct {
+	Reserve0     *big.Int
+	Reserve1     *big.Int
+	SqrtPriceX96 *big.Int
+	Tick         int32
+	Liquidity    *big.Int
+}
+
+// Decoded is what a pool event produced: a trade, a liquidity change, and the state of the pool
+// after the event for Sync, Initialize and the V3/V4 swaps. Trade and Liquidity are both nil for
+// the events that move nothing, such as a Burn collecting fees.
+type Decoded struct {
+	PoolID    int64
+	Trade     *pmodel.TTrade
+	Liquidity *pmodel.TLiquidityModify
+	State     *PoolState
+}
+
+// Decoder turns the Uniswap V2/V3/V4 events of a chain into t_trade and t_liquidity_modify rows.
+// Amounts are from the pool side: positive when the token enters the pool, scaled by the token decimals.
+// Price01 is token1 per token0, Priceu the usd price of token0 and Amountu the usd value of the event.
+// The V2/V3 pools are known by their address, the V4 pools by their PoolId in hex.
+// A Decoder remembers the last price of the pools and is not safe for concurrent use.
+type Decoder struct {
+	chainid int64
+	dex     DexSource
+	states  map[int64]*PoolState
+}
+
+func NewDecoder(chainid int64, dex DexSource) *Decoder {
+	return &Decoder{chainid: chainid, dex: dex, states: make(map[int64]*PoolState)}
+}
+
+// SetPoolState seeds the state of a pool, typically read from the chain when the decoding starts
+// after the pool initialization. V4 ModifyLiquidity needs the price of the pool.
+func (d *Decoder) SetPoolState(poolID int64, state *PoolState) {
+	d.states[poolID] = state
+}
+
+// pool is a pool with its tokens resolved.
+type pool struct {
+	id     int64
+	token0 loader.TokenDyn
+	token1 loader.TokenDyn
+}
+
+// DecodeLog decodes a pool event, the row timestamps are l.BlockTimestamp.
+func (d *Decoder) DecodeLog(l *types.Log) (*Decoded, error) {
+	if len(l.Topics) == 0 || l.Removed {
+		return nil, ErrNotPoolEvent
+	}
+	switch l.Topics[0] {
+	case v2SwapTopic, v2MintTopic, v2BurnTopic, v2SyncTopic:
+		return d.decodeV2(l)
+	case v3SwapTopic, v3MintTopic, v3BurnTopic, v3InitializeTopic:
+		return d.decodeV3(l)
+	case v4SwapTopic, v4InitializeTopic, v4ModifyLiquidTopic:
+		return d.decodeV4(l)
+	}
+	return nil, ErrNotPoolEvent
+}
+
+// DecodeReceipt decodes the pool events of a successful transaction, the events of unknown pools are skipped.
+func (d *Decoder) DecodeReceipt(receipt *types.Receipt) ([]*Decoded, error) {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, nil
+	}
+	var decoded []*Decoded
+	for _, l := range receipt.Logs {
+		dec, err := d.DecodeLog(l)
+		if errors.Is(err, ErrNotPoolEvent) || errors.Is(err, ErrUnknownPool) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, dec)
+	}
+	return decoded, nil
+}
+
+func (d *Decoder) decodeV2(l *types.Log) (*Decoded, error) {
+	p, err := d.pool(l.Address.Hex())
+	if err != nil {
+		return nil, err
+	}
+	dec := &Decoded{PoolID: p.id}
+	switch l.Topics[0] {
+	case v2SwapTopic:
+		ev, err := poolV2Filterer.ParseSwap(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v2 swap")
+		}
+		amount0 := new(big.Int).Sub(ev.Amount0In, ev.Amount0Out)
+		amount1 := new(big.Int).Sub(ev.Amount1In, ev.Amount1Out)
+		dec.Trade = p.trade(l, amount0, amount1)
+	case v2MintTopic:
+		ev, err := poolV2Filterer.ParseMint(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v2 mint")
+		}
+		dec.Liquidity = p.liquidity(l, ev.Amount0, ev.Amount1, d.price01(p))
+	case v2BurnTopic:
+		ev, err := poolV2Filterer.ParseBurn(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v2 burn")
+		}
+		dec.Liquidity = p.liquidity(l, new(big.Int).Neg(ev.Amount0), new(big.Int).Neg(ev.Amount1), d.price01(p))
+	case v2SyncTopic:
+		ev, err := poolV2Filterer.ParseSync(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v2 sync")
+		}
+		dec.State = &PoolState{Reserve0: ev.Reserve0, Reserve1: ev.Reserve1}
+		d.states[p.id] = dec.State
+	}
+	return dec, nil
+}
+
+func (d *Decoder) decodeV3(l *types.Log) (*Decoded, error) {
+	p, err := d.pool(l.Address.Hex())
+	if err != nil {
+		return nil, err
+	}
+	dec := &Decoded{PoolID: p.id}
+	switch l.Topics[0] {
+	case v3SwapTopic:
+		ev, err := poolV3Filterer.ParseSwap(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v3 swap")
+		}
+		dec.Trade = p.trade(l, ev.Amount0, ev.Amount1)
+		dec.State = &PoolState{SqrtPriceX96: ev.SqrtPriceX96, Tick: int32(ev.Tick.Int64()), Liquidity: ev.Liquidity}
+		d.states[p.id] = dec.State
+	case v3MintTopic:
+		ev, err := poolV3Filterer.ParseMint(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v3 mint")
+		}
+		dec.Liquidity = p.liquidity(l, ev.Amount0, ev.Amount1, d.price01(p))
+	case v3BurnTopic:
+		ev, err := poolV3Filterer.ParseBurn(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v3 burn")
+		}
+		if ev.Amount.Sign() == 0 {
+			return dec, nil
+		}
+		dec.Liquidity = p.liquidity(l, new(big.Int).Neg(ev.Amount0), new(big.Int).Neg(ev.Amount1), d.price01(p))
+	case v3InitializeTopic:
+		ev, err := poolV3Filterer.ParseInitialize(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v3 initialize")
+		}
+		dec.State = &PoolState{SqrtPriceX96: ev.SqrtPriceX96, Tick: int32(ev.Tick.Int64()), Liquidity: new(big.Int)}
+		d.states[p.id] = dec.State
+	}
+	return dec, nil
+}
+
+func (d *Decoder) decodeV4(l *types.Log) (*Decoded, error) {
+	if len(l.Topics) < 2 {
+		return nil, ErrNotPoolEvent
+	}
+	p, err := d.pool(hexutil.Encode(l.Topics[1][:]))
+	if err != nil {
+		return nil, err
+	}
+	dec := &Decoded{PoolID: p.id}
+	switch l.Topics[0] {
+	case v4SwapTopic:
+		ev, err := poolMgrV4Filterer.ParseSwap(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v4 swap")
+		}
+		// the PoolManager reports the balance changes of the swapper
+		dec.Trade = p.trade(l, new(big.Int).Neg(ev.Amount0), new(big.Int).Neg(ev.Amount1))
+		dec.State = &PoolState{SqrtPriceX96: ev.SqrtPriceX96, Tick: int32(ev.Tick.Int64()), Liquidity: ev.Liquidity}
+		d.states[p.id] = dec.State
+	case v4InitializeTopic:
+		ev, err := poolMgrV4Filterer.ParseInitialize(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v4 initialize")
+		}
+		dec.State = &PoolState{SqrtPriceX96: ev.SqrtPriceX96, Tick: int32(ev.Tick.Int64()), Liquidity: new(big.Int)}
+		d.states[p.id] = dec.State
+	case v4ModifyLiquidTopic:
+		ev, err := poolMgrV4Filterer.ParseModifyLiquidity(*l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse v4 modify liquidity")
+		}
+		if ev.LiquidityDelta.Sign() == 0 {
+			return dec, nil
+		}
+		state, ok := d.states[p.id]
+		if !ok || state.SqrtPriceX96 == nil {
+			return nil, ErrNoPoolPrice
+		}
+		amount0, amount1, err := liquidityAmounts(state.SqrtPriceX96, state.Tick, int32(ev.TickLower.Int64()), int32(ev.TickUpper.Int64()), ev.LiquidityDelta)
+		if err != nil {
+			return nil, errors.Wrap(err, "v4 modify liquidity")
+		}
+		dec.Liquidity = p.liquidity(l, amount0, amount1, d.price01(p))
+	}
+	return dec, nil
+}
+
+// pool resolves the pool at address and its tokens, ErrUnknownPool if one of them is not known.
+func (d *Decoder) pool(address string) (*pool, error) {
+	dyn, ok, err := d.dex.GetPoolDyn(d.chainid, address, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || dyn.ID <= 0 {
+		return nil, ErrUnknownPool
+	}
+	token0, ok, err := d.dex.GetTokenDynByID(dyn.Token0ID, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnknownPool
+	}
+	token1, ok, err := d.dex.GetTokenDynByID(dyn.Token1ID, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUnknownPool
+	}
+	return &pool{id: dyn.ID, token0: token0, token1: token1}, nil
+}
+
+// price01 returns the price of the pool from its last state, 0 if it is unknown.
+func (d *Decoder) price01(p *pool) float64 {
+	state, ok := d.states[p.id]
+	if !ok {
+		return 0
+	}
+	switch {
+	case state.SqrtPriceX96 != nil:
+		price := defi.SqrtPriceX96ToPrice(state.SqrtPriceX96, true)
+		return price.Shift(p.token0.Decimals - p.token1.Decimals).InexactFloat64()
+	case state.Reserve0 != nil && state.Reserve0.Sign() > 0:
+		return scale(state.Reserve1, p.token1.Decimals) / scale(state.Reserve0, p.token0.Decimals)
+	}
+	return 0
+}
+
+func (p *pool) trade(l *types.Log, amount0, amount1 *big.Int) *pmodel.TTrade {
+	row := &pmodel.TTrade{
+		Ts:      blockTime(l),
+		PoolID:  p.id,
+		Amount0: scale(amount0, p.token0.Decimals),
+		Amount1: scale(amount1, p.token1.Decimals),
+	}
+	if row.Amount0 != 0 {
+		row.Price01 = math.Abs(row.Amount1 / row.Amount0)
+	}
+	switch {
+	case p.token1.Priceu > 0:
+		row.Priceu = row.Price01 * p.token1.Priceu
+		row.Amountu = math.Abs(row.Amount1) * p.token1.Priceu
+	case p.token0.Priceu > 0:
+		row.Priceu = p.token0.Priceu
+		row.Amountu = math.Abs(row.Amount0) * p.token0.Priceu
+	}
+	return row
+}
+
+// liquidity returns the liquidity change, price01 values the token without a usd price.
+func (p *pool) liquidity(l *types.Log, amount0, amount1 *big.Int, price01 float64) *pmodel.TLiquidityModify {
+	row := &pmodel.TLiquidityModify{
+		Ts:      blockTime(l),
+		PoolID:  p.id,
+		Amount0: scale(amount0, p.token0.Decimals),
+		Amount1: scale(amount1, p.token1.Decimals),
+	}
+	priceu0, priceu1 := p.token0.Priceu, p.token1.Priceu
+	if priceu0 <= 0 && priceu1 > 0 {
+		priceu0 = price01 * priceu1
+	}
+	if priceu1 <= 0 && priceu0 > 0 && price01 > 0 {
+		priceu1 = priceu0 / price01
+	}
+	row.Amountu = math.Abs(row.Amount0)*priceu0 + math.Abs(row.Amount1)*priceu1
+	return row
+}
+
+// liquidityAmounts returns the token amounts of a liquidity change in the range [tickLower, tickUpper)
+// of a pool at tick and sqrtPriceX96, rounded like the pool and negative when liquidity is removed.
+func liquidityAmounts(sqrtPriceX96 *big.Int, tick, tickLower, tickUpper int32, liquidity *big.Int) (*big.Int, *big.Int, error) {
+	sqrtRatioLower, err := clmm.GetSqrtRatioAtTick(tickLower)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "tick lower")
+	}
+	sqrtRatioUpper, err := clmm.GetSqrtRatioAtTick(tickUpper)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "tick upper")
+	}
+	switch {
+	case tick < tickLower:
+		return clmm.GetAmount0DeltaSigned(sqrtRatioLower, sqrtRatioUpper, liquidity), new(big.Int), nil
+	case tick < tickUpper:
+		return clmm.GetAmount0DeltaSigned(sqrtPriceX96, sqrtRatioUpper, liquidity),
+			clmm.GetAmount1DeltaSigned(sqrtRatioLower, sqrtPriceX96, liquidity), nil
+	default:
+		return new(big.Int), clmm.GetAmount1DeltaSigned(sqrtRatioLower, sqrtRatioUpper, liquidity), nil
+	}
+}
+
+func scale(amount *big.Int, decimals int32) float64 {
+	return decimal.NewFromBigInt(amount, -decimals).InexactFloat64()
+}
+
+func blockTime(l *types.Log) time.Time {
+	return time.Unix(int64(l.BlockTimestamp), 0).UTC()
+}
@@ -0,0 +1,143 @@
+package uniswap
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDex struct {
+	pools  map[string]loader.PoolDyn
+	tokens map[int64]loader.TokenDyn
+}
+
+func (f *fakeDex) GetPoolDyn(chainid int64, address string, cache bool, cache404 bool) (loader.PoolDyn, bool, error) {
+	dyn, ok := f.pools[address]
+	return dyn, ok, nil
+}
+
+func (f *fakeDex) GetTokenDynByID(id int64, cache bool, cache404 bool) (loader.TokenDyn, bool, error) {
+	dyn, ok := f.tokens[id]
+	return dyn, ok, nil
+}
+
+func TestDecoder(t *testing.T) {
+	v2Pool := common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	v3Pool := common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640")
+	v4Pool := common.HexToHash("0x21c67e77068de97969ba93d4aab21826d33ca12bb9f565d8496e8fda8a82ca27")
+	// token 1 is usdc (6 decimals, $1), token 2 is weth (18 decimals, no price yet)
+	dex := &fakeDex{
+		pools: map[string]loader.PoolDyn{
+			v2Pool.Hex():              {ID: 10, Token0ID: 1, Token1ID: 2},
+			v3Pool.Hex():              {ID: 11, Token0ID: 1, Token1ID: 2},
+			hexutil.Encode(v4Pool[:]): {ID: 12, Token0ID: 1, Token1ID: 2},
+		},
+		tokens: map[int64]loader.TokenDyn{
+			1: {ID: 1, Priceu: 1, Decimals: 6},
+			2: {ID: 2, Decimals: 18},
+		},
+	}
+	d := NewDecoder(1, dex)
+	e18 := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	pack := func(event string, topics []common.Hash, address common.Address, args ...interface{}) *types.Log {
+		var ev = poolV2ABI.Events[event]
+		switch topics[0] {
+		case v3SwapTopic, v3MintTopic, v3BurnTopic, v3InitializeTopic:
+			ev = poolV3ABI.Events[event]
+		case v4SwapTopic, v4InitializeTopic, v4ModifyLiquidTopic:
+			ev = poolMgrV4ABI.Events[event]
+		}
+		data, err := ev.Inputs.NonIndexed().Pack(args...)
+		assert.NoError(t, err)
+		return &types.Log{Address: address, Topics: topics, Data: data, BlockTimestamp: 1700000000}
+	}
+	user := common.BytesToHash(common.HexToAddress("0xa1").Bytes())
+
+	// v2: sync at 2000 usdc/eth, then a swap of 2000 usdc for 1 eth, then a mint
+	dec, err := d.DecodeLog(pack("Sync", []common.Hash{v2SyncTopic}, v2Pool, big.NewInt(2000_000000_000), new(big.Int).Mul(big.NewInt(1000), e18)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), dec.PoolID)
+	assert.Equal(t, big.NewInt(2000_000000_000), dec.State.Reserve0)
+	dec, err = d.DecodeLog(pack("Swap", []common.Hash{v2SwapTopic, user, user}, v2Pool, big.NewInt(2000_000000), big.NewInt(0), big.NewInt(0), e18))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), dec.Trade.Ts)
+	assert.Equal(t, 2000.0, dec.Trade.Amount0)
+	assert.Equal(t, -1.0, dec.Trade.Amount1)
+	assert.Equal(t, 0.0005, dec.Trade.Price01)
+	assert.Equal(t, 1.0, dec.Trade.Priceu)
+	assert.Equal(t, 2000.0, dec.Trade.Amountu)
+	dec, err = d.DecodeLog(pack("Mint", []common.Hash{v2MintTopic, user}, v2Pool, big.NewInt(2000_000000), e18))
+	assert.NoError(t, err)
+	assert.Equal(t, 2000.0, dec.Liquidity.Amount0)
+	assert.Equal(t, 1.0, dec.Liquidity.Amount1)
+	assert.InDelta(t, 4000.0, dec.Liquidity.Amountu, 1e-9)
+
+	// v3: a swap selling 1 eth
+	sqrtPrice, _ := new(big.Int).SetString("1771595571142957166518320255467520", 10) // ~2000 usdc/eth
+	dec, err = d.DecodeLog(pack("Swap", []common.Hash{v3SwapTopic, user, user}, v3Pool, big.NewInt(-2000_000000), e18, sqrtPrice, big.NewInt(1), big.NewInt(195000)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), dec.PoolID)
+	assert.Equal(t, -2000.0, dec.Trade.Amount0)
+	assert.Equal(t, 1.0, dec.Trade.Amount1)
+	assert.Equal(t, int32(195000), dec.State.Tick)
+	dec, err = d.DecodeLog(pack("Burn", []common.Hash{v3BurnTopic, user, common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2))}, v3Pool, big.NewInt(0), big.NewInt(0), big.NewInt(0)))
+	assert.NoError(t, err)
+	assert.Nil(t, dec.Liquidity)
+
+	// v4: the swapper pays 1 eth and receives 2000 usdc
+	id := common.BytesToHash(v4Pool[:])
+	_, err = d.DecodeLog(pack("ModifyLiquidity", []common.Hash{v4ModifyLiquidTopic, id, user}, common.Address{}, big.NewInt(-887220), big.NewInt(887220), e18, [32]byte{}))
+	assert.ErrorIs(t, err, ErrNoPoolPrice)
+	dec, err = d.DecodeLog(pack("Swap", []common.Hash{v4SwapTopic, id, user}, common.Address{}, big.NewInt(2000_000000), new(big.Int).Neg(e18), sqrtPrice, e18, big.NewInt(195000), big.NewInt(500)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), dec.PoolID)
+	assert.Equal(t, -2000.0, dec.Trade.Amount0)
+	assert.Equal(t, 1.0, dec.Trade.Amount1)
+	dec, err = d.DecodeLog(pack("ModifyLiquidity", []common.Hash{v4ModifyLiquidTopic, id, user}, common.Address{}, big.NewInt(-887220), big.NewInt(887220), big.NewInt(1e12), [32]byte{}))
+	assert.NoError(t, err)
+	// full range: amount0 = L/sqrtP and amount1 = L*sqrtP in raw units
+	assert.InDelta(t, 44.72, dec.Liquidity.Amount0, 0.01)
+	assert.InDelta(t, 22.36, dec.Liquidity.Amount1*1000, 0.01)
+	assert.InDelta(t, 89.44, dec.Liquidity.Amountu, 0.01)
+
+	unknown := pack("Sync", []common.Hash{v2SyncTopic}, common.HexToAddress("0x01"), big.NewInt(1), big.NewInt(1))
+	_, err = d.DecodeLog(unknown)
+	assert.ErrorIs(t, err, ErrUnknownPool)
+	decoded, err := d.DecodeReceipt(&types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{unknown, {Topics: []common.Hash{{1}}}}})
+	assert.NoError(t, err)
+	assert.Empty(t, decoded)
+}
+
+func TestLiquidityAmounts(t *testing.T) {
+	sqrtPrice := new(big.Int).Lsh(big.NewInt(1), 96)
+	added, removed := big.NewInt(1e18), big.NewInt(-1e18)
+
+	// in range, rounded up when added and down when removed
+	amount0, amount1, err := liquidityAmounts(sqrtPrice, 0, -60, 60, added)
+	assert.NoError(t, err)
+	assert.Equal(t, "2995354955910781", amount0.String())
+	assert.Equal(t, "2995354955910781", amount1.String())
+	amount0, amount1, err = liquidityAmounts(sqrtPrice, 0, -60, 60, removed)
+	assert.NoError(t, err)
+	assert.Equal(t, "-2995354955910780", amount0.String())
+	assert.Equal(t, "-2995354955910780", amount1.String())
+
+	// below the range only token0, above only token1
+	amount0, amount1, err = liquidityAmounts(sqrtPrice, 0, 60, 120, added)
+	assert.NoError(t, err)
+	assert.Positive(t, amount0.Sign())
+	assert.Zero(t, amount1.Sign())
+	amount0, amount1, err = liquidityAmounts(sqrtPrice, 0, -120, -60, added)
+	assert.NoError(t, err)
+	assert.Zero(t, amount0.Sign())
+	assert.Positive(t, amount1.Sign())
+
+	_, _, err = liquidityAmounts(sqrtPrice, 0, -60, 1_000_000, added)
+	assert.Error(t, err)
+}
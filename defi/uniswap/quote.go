@@ -0,0 +1,91 @@
+package uniswap
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// FeeDenominator is the denominator of TPoolStatic.FeePpm: 3000 is the 0.3% of Uniswap V2.
+const FeeDenominator = 1_000_000
+
+var (
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+	ErrInsufficientAmount    = errors.New("insufficient amount")
+)
+
+// Quote is a trade on a constant product pool: the amounts, the price impact, which includes the fee,
+// and the reserves of the pool after the trade.
+type Quote struct {
+	AmountIn    *big.Int
+	AmountOut   *big.Int
+	PriceImpact float64
+	ReserveIn   *big.Int
+	ReserveOut  *big.Int
+}
+
+// GetAmountOut returns the output of amountIn like UniswapV2Library.getAmountOut, with the fee in ppm.
+func GetAmountOut(amountIn, reserveIn, reserveOut *big.Int, feePpm int32) (*big.Int, error) {
+	if amountIn.Sign() <= 0 {
+		return nil, ErrInsufficientAmount
+	}
+	if reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	amountInWithFee := new(big.Int).Mul(amountIn, big.NewInt(int64(FeeDenominator-feePpm)))
+	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	denominator := new(big.Int).Mul(reserveIn, big.NewInt(FeeDenominator))
+	denominator.Add(denominator, amountInWithFee)
+	return numerator.Quo(numerator, denominator), nil
+}
+
+// GetAmountIn returns the input needed for amountOut like UniswapV2Library.getAmountIn, with the fee in ppm.
+func GetAmountIn(amountOut, reserveIn, reserveOut *big.Int, feePpm int32) (*big.Int, error) {
+	if amountOut.Sign() <= 0 {
+		return nil, ErrInsufficientAmount
+	}
+	if reserveIn.Sign() <= 0 || reserveOut.Cmp(amountOut) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	numerator := new(big.Int).Mul(reserveIn, amountOut)
+	numerator.Mul(numerator, big.NewInt(FeeDenominator))
+	denominator := new(big.Int).Sub(reserveOut, amountOut)
+	denominator.Mul(denominator, big.NewInt(int64(FeeDenominator-feePpm)))
+	numerator.Quo(numerator, denominator)
+	return numerator.Add(numerator, big.NewInt(1)), nil
+}
+
+// QuoteExactIn quotes swapping amountIn on a constant product pool.
+func QuoteExactIn(amountIn, reserveIn, reserveOut *big.Int, feePpm int32) (*Quote, error) {
+	amountOut, err := GetAmountOut(amountIn, reserveIn, reserveOut, feePpm)
+	if err != nil {
+		return nil, err
+	}
+	return newQuote(amountIn, amountOut, reserveIn, reserveOut), nil
+}
+
+// QuoteExactOut quotes receiving amountOut from a constant product pool.
+func QuoteExactOut(amountOut, reserveIn, reserveOut *big.Int, feePpm int32) (*Quote, error) {
+	amountIn, err := GetAmountIn(amountOut, reserveIn, reserveOut, feePpm)
+	if err != nil {
+		return nil, err
+	}
+	return newQuote(amountIn, amountOut, reserveIn, reserveOut), nil
+}
+
+// newQuote fills the price impact, 1 - amountOut / (amountIn * reserveOut / reserveIn), and the reserves after the trade.
+func newQuote(amountIn, amountOut, reserveIn, reserveOut *big.Int) *Quote {
+	q := &Quote{
+		AmountIn:   amountIn,
+		AmountOut:  amountOut,
+		ReserveIn:  new(big.Int).Add(reserveIn, amountIn),
+		ReserveOut: new(big.Int).Sub(reserveOut, amountOut),
+	}
+	// amountOut * reserveIn / (amountIn * reserveOut)
+	ratio, _ := new(big.Rat).SetFrac(
+		new(big.Int).Mul(amountOut, reserveIn),
+		new(big.Int).Mul(amountIn, reserveOut),
+	).Float64()
+	q.PriceImpact = 1 - ratio
+	return q
+}
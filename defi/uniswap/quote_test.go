@@ -0,0 +1,33 @@
+package uniswap
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteV2(t *testing.T) {
+	reserveIn, reserveOut := big.NewInt(1_000_000), big.NewInt(2_000_000)
+
+	// 997*2000000*1000 / (1000000*1000 + 1000*997)
+	q, err := QuoteExactIn(big.NewInt(1000), reserveIn, reserveOut, 3000)
+	assert.NoError(t, err)
+	assert.Equal(t, "1992", q.AmountOut.String())
+	assert.Equal(t, "1001000", q.ReserveIn.String())
+	assert.Equal(t, "1998008", q.ReserveOut.String())
+	assert.InDelta(t, 0.004, q.PriceImpact, 1e-9)
+
+	// the input of the output is enough for it
+	q, err = QuoteExactOut(big.NewInt(1992), reserveIn, reserveOut, 3000)
+	assert.NoError(t, err)
+	assert.True(t, q.AmountIn.Cmp(big.NewInt(1000)) <= 0)
+	out, err := GetAmountOut(q.AmountIn, reserveIn, reserveOut, 3000)
+	assert.NoError(t, err)
+	assert.Equal(t, "1992", out.String())
+
+	_, err = GetAmountIn(reserveOut, reserveIn, reserveOut, 3000)
+	assert.ErrorIs(t, err, ErrInsufficientLiquidity)
+	_, err = GetAmountOut(big.NewInt(0), reserveIn, reserveOut, 3000)
+	assert.ErrorIs(t, err, ErrInsufficientAmount)
+}
@@ -0,0 +1,172 @@
+package uniswap
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/dexerlab/utils-go/dal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownDeployer is returned for a chain and dex pool that are not in the PoolRegistry.
+var ErrUnknownDeployer = errors.New("unknown pool deployer")
+
+// DeployerKind is how a factory derives the CREATE2 salt of its pools.
+type DeployerKind int
+
+const (
+	DeployerUnknown DeployerKind = iota
+	// DeployerV2 salts with keccak256(token0 ++ token1), like Uniswap V2, Sushi and PancakeSwap V2.
+	DeployerV2
+	// DeployerV3 salts with keccak256(abi.encode(token0, token1, fee)), like Uniswap V3 and PancakeSwap V3.
+	// The fee is the tick spacing for Aerodrome Slipstream.
+	DeployerV3
+	// DeployerSolidly salts with keccak256(token0 ++ token1 ++ stable), like Velodrome and Aerodrome.
+	// The fee is SolidlyStable for the stable pools and 0 for the volatile ones.
+	DeployerSolidly
+)
+
+// SolidlyStable is the fee of the stable pools of a DeployerSolidly.
+const SolidlyStable = 1
+
+var zkSyncCreate2Prefix = crypto.Keccak256([]byte("zksyncCreate2"))
+
+// PoolDeployer is how the pools of a dex are deployed on a chain.
+type PoolDeployer struct {
+	Kind    DeployerKind
+	Factory common.Address
+	// Deployer is the address that creates the pools when it is not the factory, such as the PancakeSwap V3 PoolDeployer.
+	Deployer     common.Address
+	InitCodeHash common.Hash
+	// ZkSync is set for zkSync Era, where CREATE2 hashes with the zksyncCreate2 prefix and InitCodeHash
+	// is the bytecode hash of the pool.
+	ZkSync bool
+}
+
+// KnownPoolDeployer is a PoolDeployer with the chains where it is deployed at the same address.
+type KnownPoolDeployer struct {
+	ChainIDs []int64
+	PoolDeployer
+}
+
+// KnownPoolDeployers are the deployments PoolRegistry.Seed recognizes in TDexPool.Factory: Uniswap V2 and V3,
+// PancakeSwap V2 and V3. The other dexes, such as Sushi, Aerodrome and the deployments on zkSync Era,
+// are not known and must be set with PoolRegistry.Register.
+var KnownPoolDeployers = []KnownPoolDeployer{
+	{ChainIDs: []int64{1}, PoolDeployer: PoolDeployer{Kind: DeployerV2,
+		Factory:      common.HexToAddress(FactoryAddrV2),
+		InitCodeHash: common.BytesToHash(PoolInitCodeV2)}},
+	{ChainIDs: []int64{1, 10, 137, 42161}, PoolDeployer: PoolDeployer{Kind: DeployerV3,
+		Factory:      common.HexToAddress(FactoryAddrV3),
+		InitCodeHash: common.BytesToHash(PoolInitCodeV3)}},
+	{ChainIDs: []int64{8453}, PoolDeployer: PoolDeployer{Kind: DeployerV3,
+		Factory:      common.HexToAddress("0x33128a8fC17869897dcE68Ed026d694621f6FDfD"),
+		InitCodeHash: common.BytesToHash(PoolInitCodeV3)}},
+	{ChainIDs: []int64{56}, PoolDeployer: PoolDeployer{Kind: DeployerV2,
+		Factory:      common.HexToAddress("0xcA143Ce32Fe78f1f7019d7d551a6402fC5350c73"),
+		InitCodeHash: common.HexToHash("0x00fb7f630766e6a796048ea87d01acd3068e8ff67d078148a3fa3f4a84f69bd5")}},
+	{ChainIDs: []int64{1, 56, 8453, 42161}, PoolDeployer: PoolDeployer{Kind: DeployerV3,
+		Factory:      common.HexToAddress("0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865"),
+		Deployer:     common.HexToAddress("0x41ff9AA7e16B8B1a8a8dc4f0eFacd93D02d071c9"),
+		InitCodeHash: common.HexToHash("0x6ce8eb472fa82df5469c6ab6d485f17c3ad13c8cd7af59b3d4a8026c5ce0f7e2")}},
+}
+
+// PoolAddress returns the address of the pool of tokenA and tokenB, fee being ignored by DeployerV2.
+func (d *PoolDeployer) PoolAddress(tokenA, tokenB common.Address, fee uint32) (poolAddr common.Address, err error) {
+	tkn0, tkn1 := sortAddressess(tokenA, tokenB)
+	var salt []byte
+	switch d.Kind {
+	case DeployerV2:
+		salt = crypto.Keccak256(tkn0.Bytes(), tkn1.Bytes())
+	case DeployerV3:
+		paramsPacked, err := saltAbiArguments.Pack(tkn0, tkn1, big.NewInt(int64(fee)))
+		if err != nil {
+			return poolAddr, errors.Wrap(err, "pack arguments")
+		}
+		salt = crypto.Keccak256(paramsPacked)
+	case DeployerSolidly:
+		stable := []byte{0}
+		if fee == SolidlyStable {
+			stable[0] = 1
+		}
+		salt = crypto.Keccak256(tkn0.Bytes(), tkn1.Bytes(), stable)
+	default:
+		return poolAddr, errors.Errorf("deployer kind %d", d.Kind)
+	}
+
+	deployer := d.Deployer
+	if deployer == (common.Address{}) {
+		deployer = d.Factory
+	}
+	if d.ZkSync {
+		// keccak256(prefix ++ sender ++ salt ++ bytecodeHash ++ keccak256(constructorInput)), without constructor input
+		hash := crypto.Keccak256(zkSyncCreate2Prefix, common.LeftPadBytes(deployer.Bytes(), 32), salt,
+			d.InitCodeHash.Bytes(), crypto.Keccak256(nil))
+		return common.BytesToAddress(hash[12:]), nil
+	}
+	return crypto.CreateAddress2(deployer, common.BytesToHash(salt), d.InitCodeHash.Bytes()), nil
+}
+
+type poolDeployerKey struct {
+	chainid   int64
+	dexPoolID int32
+}
+
+// PoolRegistry knows the PoolDeployer of each dex pool (TDexPool.ID) on each chain.
+// It can't be modified while it is read.
+type PoolRegistry struct {
+	deployers map[poolDeployerKey]*PoolDeployer
+}
+
+func NewPoolRegistry() *PoolRegistry {
+	return &PoolRegistry{deployers: make(map[poolDeployerKey]*PoolDeployer)}
+}
+
+// Register sets the PoolDeployer of a dex pool on a chain.
+func (r *PoolRegistry) Register(chainid int64, dexPoolID int32, deployer PoolDeployer) {
+	r.deployers[poolDeployerKey{chainid, dexPoolID}] = &deployer
+}
+
+// Deployer returns the PoolDeployer of a dex pool on a chain.
+func (r *PoolRegistry) Deployer(chainid int64, dexPoolID int32) (*PoolDeployer, bool) {
+	d, ok := r.deployers[poolDeployerKey{chainid, dexPoolID}]
+	return d, ok
+}
+
+// Seed registers the KnownPoolDeployers whose factory is listed in the Factory of the dex pools,
+// and returns how many were registered.
+func (r *PoolRegistry) Seed(pools ...*model.TDexPool) int {
+	known := make(map[common.Address][]*KnownPoolDeployer, len(KnownPoolDeployers))
+	for i := range KnownPoolDeployers {
+		k := &KnownPoolDeployers[i]
+		known[k.Factory] = append(known[k.Factory], k)
+	}
+
+	n := 0
+	for _, p := range pools {
+		for _, part := range strings.Split(p.Factory, "|") {
+			part = strings.TrimSpace(part)
+			if !common.IsHexAddress(part) {
+				continue
+			}
+			for _, k := range known[common.HexToAddress(part)] {
+				for _, chainid := range k.ChainIDs {
+					r.Register(chainid, p.ID, k.PoolDeployer)
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+// PoolAddress returns the address of the pool of tokenA and tokenB of a dex pool on a chain.
+func (r *PoolRegistry) PoolAddress(chainid int64, dexPoolID int32, tokenA, tokenB common.Address, fee uint32) (common.Address, error) {
+	d, ok := r.Deployer(chainid, dexPoolID)
+	if !ok {
+		return common.Address{}, errors.Wrapf(ErrUnknownDeployer, "chain %d dex pool %d", chainid, dexPoolID)
+	}
+	return d.PoolAddress(tokenA, tokenB, fee)
+}
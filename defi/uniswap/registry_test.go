@@ -0,0 +1,61 @@
+package uniswap
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dexerlab/utils-go/dal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolRegistry(t *testing.T) {
+	r := NewPoolRegistry()
+	assert.Equal(t, 1+4+1+4, r.Seed(
+		&model.TDexPool{ID: 1, Factory: FactoryAddrV2},
+		&model.TDexPool{ID: 2, Factory: "0x1F98431c8aD98523631AE4a59f267346ea31F984|0x33128a8fC17869897dcE68Ed026d694621f6FDfD"},
+		&model.TDexPool{ID: 3, Factory: " 0x0bfbcf9fa4f9c56b0f40a671ad40e0805a091865 "},
+		&model.TDexPool{ID: 4, Factory: "not a factory"},
+	))
+
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	addr, err := r.PoolAddress(1, 1, weth, usdc, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"), addr)
+	addr, err = r.PoolAddress(1, 2, weth, usdc, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640"), addr)
+
+	// the Base deployment of Uniswap V3
+	addr, err = r.PoolAddress(8453, 2, common.HexToAddress("0x4200000000000000000000000000000000000006"),
+		common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), 500)
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0xd0b53D9277642d899DF5C87A3966A349A798F224"), addr)
+
+	// PancakeSwap V3 USDT/WBNB 0.05% on BSC, created by its PoolDeployer
+	addr, err = r.PoolAddress(56, 3, common.HexToAddress("0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c"),
+		common.HexToAddress("0x55d398326f99059fF775485246999027B3197955"), 500)
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x36696169C63e42cd08ce11f5deeBbCeBae652050"), addr)
+
+	_, err = r.PoolAddress(56, 1, weth, usdc, 0)
+	assert.ErrorIs(t, err, ErrUnknownDeployer)
+
+	// zkSync hashes differently, a deployment that is not known is registered
+	d := PoolDeployer{Kind: DeployerV3, Factory: common.HexToAddress("0x8FdA5a7a8dCA67BBcDd10F02Fa0649A937215422"),
+		InitCodeHash: common.HexToHash("0x010013f177ea1fcbc4520f9a3ca7cd2d1d77959e05aa66484027cb38e712aeed")}
+	evm, _ := d.PoolAddress(weth, usdc, 500)
+	d.ZkSync = true
+	r.Register(324, 5, d)
+	zk, err := r.PoolAddress(324, 5, weth, usdc, 500)
+	assert.NoError(t, err)
+	assert.NotEqual(t, evm, zk)
+	// keccak256(keccak256("zksyncCreate2") ++ sender ++ salt ++ bytecodeHash ++ keccak256(input))[12:]
+	salt, err := saltAbiArguments.Pack(usdc, weth, big.NewInt(500))
+	assert.NoError(t, err)
+	hash := crypto.Keccak256(crypto.Keccak256([]byte("zksyncCreate2")), common.LeftPadBytes(d.Factory.Bytes(), 32),
+		crypto.Keccak256(salt), d.InitCodeHash.Bytes(), crypto.Keccak256())
+	assert.Equal(t, common.BytesToAddress(hash[12:]), zk)
+}
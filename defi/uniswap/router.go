@@ -0,0 +1,228 @@
+package uniswap
+
+import (
+	"math/big"
+	"slices"
+
+	"github.com/dexerlab/utils-go/defi/clmm"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// MaxHops is the longest route the Router looks for.
+const MaxHops = 3
+
+// ErrNoRoute is returned when no route of at most MaxHops pools joins the tokens.
+var ErrNoRoute = errors.New("no route")
+
+// PoolLister lists the pools of a chain, it is implemented by loader.DexManager.
+type PoolLister interface {
+	GetRoutePools(chainid int64, minLiquidityu float64) ([]loader.RoutePool, error)
+}
+
+// Hop is a swap of a route, in a single pool. Deployer is nil for the dex pools that are not known
+// by the PoolRegistry of the Router.
+type Hop struct {
+	PoolID    int64
+	Pool      common.Address
+	DexPoolID int32
+	Deployer  *PoolDeployer
+	TokenIn   common.Address
+	TokenOut  common.Address
+	FeePpm    int32
+	AmountIn  *big.Int
+	AmountOut *big.Int
+}
+
+// Route is a path of 1 to MaxHops pools from Path[0] to Path[len(Path)-1], Fees being the fee of each hop.
+type Route struct {
+	Path      []common.Address
+	Fees      []int
+	Hops      []Hop
+	AmountIn  *big.Int
+	AmountOut *big.Int
+}
+
+// EncodePath encodes the route for the exactInput of the V3 routers, which needs all the hops
+// to be in DeployerV3 pools of the same deployer.
+func (r *Route) EncodePath() ([]byte, error) {
+	for _, h := range r.Hops {
+		if h.Deployer == nil || h.Deployer.Kind != DeployerV3 {
+			return nil, errors.Errorf("pool %d is not a known V3 pool", h.PoolID)
+		}
+		if *h.Deployer != *r.Hops[0].Deployer {
+			return nil, errors.Errorf("pools %d and %d have different deployers", r.Hops[0].PoolID, h.PoolID)
+		}
+	}
+	return EncodePath(r.Path, r.Fees)
+}
+
+// edge is a pool seen from one of its tokens.
+type edge struct {
+	pool       *loader.RoutePool
+	address    common.Address
+	zeroForOne bool
+	tokenOut   common.Address
+	reserveIn  *big.Int
+	reserveOut *big.Int
+}
+
+// Router finds the best route of a trade on a graph of tokens whose edges are the pools.
+// The pools are quoted as constant product pools on their balances, unless their V3 state is
+// given with SetCLMMPool, so the output of the concentrated pools is underestimated without it.
+// A Router is not safe for concurrent use with SetCLMMPool and SetRegistry.
+type Router struct {
+	edges    map[common.Address][]*edge
+	clmms    map[int64]*clmm.Pool
+	registry *PoolRegistry
+	chainid  int64
+}
+
+// NewRouter builds the graph of the pools, skipping the pools that are not on an EVM chain or have no balance.
+// With dexPoolIDs, only the pools of these dex pools are used, such as the ones of a single V3 deployer
+// for the routes given to its router.
+func NewRouter(pools []loader.RoutePool, dexPoolIDs ...int32) *Router {
+	r := &Router{edges: make(map[common.Address][]*edge), clmms: make(map[int64]*clmm.Pool)}
+	for i := range pools {
+		p := &pools[i]
+		if len(dexPoolIDs) > 0 && !slices.Contains(dexPoolIDs, p.DexPoolID) {
+			continue
+		}
+		if !common.IsHexAddress(p.Address) || !common.IsHexAddress(p.Token0) || !common.IsHexAddress(p.Token1) {
+			continue
+		}
+		if p.Liquidity0.Sign() <= 0 || p.Liquidity1.Sign() <= 0 {
+			continue
+		}
+		address := common.HexToAddress(p.Address)
+		token0, token1 := common.HexToAddress(p.Token0), common.HexToAddress(p.Token1)
+		reserve0, reserve1 := p.Liquidity0.BigInt(), p.Liquidity1.BigInt()
+		r.edges[token0] = append(r.edges[token0], &edge{pool: p, address: address, zeroForOne: true,
+			tokenOut: token1, reserveIn: reserve0, reserveOut: reserve1})
+		r.edges[token1] = append(r.edges[token1], &edge{pool: p, address: address, zeroForOne: false,
+			tokenOut: token0, reserveIn: reserve1, reserveOut: reserve0})
+	}
+	return r
+}
+
+// LoadRouter builds the Router of the pools of a chain with at least minLiquidityu of liquidity,
+// of dexPoolIDs if given.
+func LoadRouter(pools PoolLister, chainid int64, minLiquidityu float64, dexPoolIDs ...int32) (*Router, error) {
+	list, err := pools.GetRoutePools(chainid, minLiquidityu)
+	if err != nil {
+		return nil, errors.Wrap(err, "load pools")
+	}
+	return NewRouter(list, dexPoolIDs...), nil
+}
+
+// SetRegistry sets the deployers of the dex pools of the chain of the pools, which tell the routes
+// that Route.EncodePath can encode.
+func (r *Router) SetRegistry(chainid int64, registry *PoolRegistry) {
+	r.chainid, r.registry = chainid, registry
+}
+
+// SetCLMMPool sets the V3 state of a pool, which is then quoted by simulating its swaps.
+func (r *Router) SetCLMMPool(poolID int64, pool *clmm.Pool) {
+	r.clmms[poolID] = pool
+}
+
+// BestRoute returns the route of at most maxHops pools giving the most tokenOut for amountIn of tokenIn.
+// A route goes through a token at most once.
+func (r *Router) BestRoute(tokenIn, tokenOut common.Address, amountIn *big.Int, maxHops int) (*Route, error) {
+	if amountIn.Sign() <= 0 {
+		return nil, ErrInsufficientAmount
+	}
+	if tokenIn == tokenOut {
+		return nil, errors.New("same token in and out")
+	}
+	maxHops = min(max(maxHops, 1), MaxHops)
+
+	// each layer keeps the best route reaching each token with one more hop
+	var best *Route
+	layer := map[common.Address]*Route{tokenIn: {Path: []common.Address{tokenIn}, AmountIn: amountIn, AmountOut: amountIn}}
+	for hop := 0; hop < maxHops && len(layer) > 0; hop++ {
+		next := make(map[common.Address]*Route)
+		for token, route := range layer {
+			for _, e := range r.edges[token] {
+				if route.visits(e.tokenOut) || (e.tokenOut != tokenOut && hop == maxHops-1) {
+					continue
+				}
+				out, err := r.quote(e, route.AmountOut)
+				if err != nil || out.Sign() <= 0 {
+					continue
+				}
+				if cur, ok := next[e.tokenOut]; ok && cur.AmountOut.Cmp(out) >= 0 {
+					continue
+				}
+				next[e.tokenOut] = route.extend(e, out, r.deployer(e.pool.DexPoolID))
+			}
+		}
+		if route, ok := next[tokenOut]; ok && (best == nil || route.AmountOut.Cmp(best.AmountOut) > 0) {
+			best = route
+		}
+		delete(next, tokenOut)
+		layer = next
+	}
+	if best == nil {
+		return nil, ErrNoRoute
+	}
+	return best, nil
+}
+
+func (r *Router) quote(e *edge, amountIn *big.Int) (*big.Int, error) {
+	if pool, ok := r.clmms[e.pool.ID]; ok {
+		out, swap, err := pool.QuoteExactInput(e.zeroForOne, amountIn)
+		if err != nil {
+			return nil, err
+		}
+		// a pool running out of liquidity stops at the price limit without taking all of amountIn
+		consumed := swap.Amount1
+		if e.zeroForOne {
+			consumed = swap.Amount0
+		}
+		if consumed.Cmp(amountIn) < 0 {
+			return nil, errors.New("partial fill")
+		}
+		return out, nil
+	}
+	return GetAmountOut(amountIn, e.reserveIn, e.reserveOut, e.pool.FeePpm)
+}
+
+func (r *Router) deployer(dexPoolID int32) *PoolDeployer {
+	if r.registry == nil {
+		return nil
+	}
+	d, _ := r.registry.Deployer(r.chainid, dexPoolID)
+	return d
+}
+
+func (route *Route) visits(token common.Address) bool {
+	for _, t := range route.Path {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func (route *Route) extend(e *edge, amountOut *big.Int, deployer *PoolDeployer) *Route {
+	tokenIn := route.Path[len(route.Path)-1]
+	return &Route{
+		Path: append(append(make([]common.Address, 0, len(route.Path)+1), route.Path...), e.tokenOut),
+		Fees: append(append(make([]int, 0, len(route.Fees)+1), route.Fees...), int(e.pool.FeePpm)),
+		Hops: append(append(make([]Hop, 0, len(route.Hops)+1), route.Hops...), Hop{
+			PoolID:    e.pool.ID,
+			Pool:      e.address,
+			DexPoolID: e.pool.DexPoolID,
+			Deployer:  deployer,
+			TokenIn:   tokenIn,
+			TokenOut:  e.tokenOut,
+			FeePpm:    e.pool.FeePpm,
+			AmountIn:  route.AmountOut,
+			AmountOut: amountOut,
+		}),
+		AmountIn:  route.AmountIn,
+		AmountOut: amountOut,
+	}
+}
@@ -0,0 +1,111 @@
+package uniswap
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dexerlab/utils-go/defi/clmm"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter(t *testing.T) {
+	weth := "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+	usdc := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	dai := "0x6b175474e89094c44da98b954eedeac495271d0f"
+	pool := func(id int64, dexPoolID int32, token0, token1 string, fee int32, liq0, liq1 int64) loader.RoutePool {
+		return loader.RoutePool{ID: id, Address: common.BigToAddress(big.NewInt(id)).Hex(), DexPoolID: dexPoolID,
+			Token0: token0, Token1: token1, FeePpm: fee, Liquidity0: decimal.NewFromInt(liq0), Liquidity1: decimal.NewFromInt(liq1)}
+	}
+	pools := []loader.RoutePool{
+		pool(1, 2, usdc, weth, 3000, 1_010_000, 1_000),   // a shallow direct V2 pool
+		pool(2, 3, dai, weth, 500, 100_000_000, 100_000), // deep V3 pools through dai
+		pool(3, 3, dai, usdc, 100, 100_000_000, 100_000_000),
+		pool(4, 3, "So11111111111111111111111111111111111111112", weth, 3000, 1, 1), // not an evm pool
+	}
+	registry := NewPoolRegistry()
+	registry.Register(1, 2, KnownPoolDeployers[0].PoolDeployer)
+	registry.Register(1, 3, KnownPoolDeployers[1].PoolDeployer)
+	r := NewRouter(pools)
+	r.SetRegistry(1, registry)
+
+	// a small trade goes direct
+	route, err := r.BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(1), 3)
+	assert.NoError(t, err)
+	assert.Len(t, route.Hops, 1)
+	assert.Equal(t, int32(2), route.Hops[0].DexPoolID)
+	_, err = route.EncodePath()
+	assert.Error(t, err)
+
+	// a large one through dai
+	route, err = r.BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(100), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(weth), common.HexToAddress(dai), common.HexToAddress(usdc)}, route.Path)
+	assert.Equal(t, []int{500, 100}, route.Fees)
+	assert.Equal(t, route.Hops[0].AmountOut, route.Hops[1].AmountIn)
+	assert.Equal(t, route.Hops[1].AmountOut, route.AmountOut)
+	encoded, err := route.EncodePath()
+	assert.NoError(t, err)
+	assert.Len(t, encoded, 2*Offset+AddrSize)
+
+	// not encoded without the deployers, nor through pools of different deployers
+	route, err = NewRouter(pools).BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(100), 3)
+	assert.NoError(t, err)
+	_, err = route.EncodePath()
+	assert.Error(t, err)
+	route.Hops[1].Deployer = &KnownPoolDeployers[2].PoolDeployer
+	_, err = route.EncodePath()
+	assert.Error(t, err)
+
+	// the direct pool when limited to one hop
+	route, err = r.BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(100), 1)
+	assert.NoError(t, err)
+	assert.Len(t, route.Hops, 1)
+
+	_, err = r.BestRoute(common.HexToAddress(weth), common.HexToAddress("0x01"), big.NewInt(100), 3)
+	assert.ErrorIs(t, err, ErrNoRoute)
+
+	// only the V3 pools, even for a small trade
+	r = NewRouter(pools, 3)
+	r.SetRegistry(1, registry)
+	route, err = r.BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(1), 3)
+	assert.NoError(t, err)
+	assert.Len(t, route.Hops, 2)
+	_, err = route.EncodePath()
+	assert.NoError(t, err)
+}
+
+func TestRouterCLMMPartialFill(t *testing.T) {
+	weth := "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+	usdc := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	dai := "0x6b175474e89094c44da98b954eedeac495271d0f"
+	pool := func(id int64, token0, token1 string, fee int32, liq0, liq1 int64) loader.RoutePool {
+		return loader.RoutePool{ID: id, Address: common.BigToAddress(big.NewInt(id)).Hex(), Token0: token0, Token1: token1,
+			FeePpm: fee, Liquidity0: decimal.NewFromInt(liq0), Liquidity1: decimal.NewFromInt(liq1)}
+	}
+	r := NewRouter([]loader.RoutePool{
+		pool(1, usdc, weth, 3000, 1_000_000, 1_000_000),
+		pool(2, dai, weth, 500, 100_000_000, 100_000),
+		pool(3, dai, usdc, 100, 100_000_000, 100_000_000),
+	})
+
+	// the direct pool pays 1M usdc a weth, but takes about 3 weth before its liquidity runs out
+	liquidity := big.NewInt(1_000_000)
+	sqrtPrice, err := clmm.GetSqrtRatioAtTick(-138180)
+	assert.NoError(t, err)
+	r.SetCLMMPool(1, &clmm.Pool{SqrtPriceX96: sqrtPrice, Tick: -138180, Liquidity: liquidity, Fee: 3000, TickSpacing: 60,
+		Ticks: []clmm.Tick{{Index: -138240, LiquidityNet: liquidity}, {Index: -138120, LiquidityNet: new(big.Int).Neg(liquidity)}}})
+
+	route, err := r.BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(2), 3)
+	assert.NoError(t, err)
+	assert.Len(t, route.Hops, 1)
+	assert.Equal(t, big.NewInt(1000798), route.AmountOut)
+
+	// a larger trade would only fill in part
+	route, err = r.BestRoute(common.HexToAddress(weth), common.HexToAddress(usdc), big.NewInt(100), 3)
+	assert.NoError(t, err)
+	assert.Len(t, route.Hops, 2)
+	assert.Equal(t, common.HexToAddress(dai), route.Path[1])
+}
@@ -0,0 +1,179 @@
+package uniswap
+
+import (
+	"context"
+	"math/big"
+
+	uniswapv4 "github.com/dexerlab/utils-go/abi/swap/uniswap/v4"
+	"github.com/dexerlab/utils-go/defi/clmm"
+	"github.com/dexerlab/utils-go/rpc"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	PoolManagerAddrV4 = "0x000000000004444c5dc75cB358380D2e3dE08A90"
+	StateViewAddrV4   = "0x7fFE42C4a5DEeA5b0feC41C94C136Cf115597227"
+
+	// DynamicFeeFlag is the fee of the pools whose LP fee is set by their hooks.
+	DynamicFeeFlag = 0x800000
+)
+
+var (
+	Int24, _          = abi.NewType("int24", "", nil)
+	poolKeyArguments  = abi.Arguments{{Name: "currency0", Type: Address}, {Name: "currency1", Type: Address}, {Name: "fee", Type: Uint24}, {Name: "tickSpacing", Type: Int24}, {Name: "hooks", Type: Address}}
+	stateViewV4ABI, _ = uniswapv4.StateviewV4MetaData.GetAbi()
+)
+
+// HookFlags are the permissions of a V4 hook, encoded in the lowest 14 bits of its address.
+type HookFlags uint16
+
+const (
+	AfterRemoveLiquidityReturnsDelta HookFlags = 1 << iota
+	AfterAddLiquidityReturnsDelta
+	AfterSwapReturnsDelta
+	BeforeSwapReturnsDelta
+	AfterDonate
+	BeforeDonate
+	AfterSwap
+	BeforeSwap
+	AfterRemoveLiquidity
+	BeforeRemoveLiquidity
+	AfterAddLiquidity
+	BeforeAddLiquidity
+	AfterInitialize
+	BeforeInitialize
+
+	AllHookFlags HookFlags = 1<<14 - 1
+)
+
+// HookFlagsOf returns the permissions of the hooks contract.
+func HookFlagsOf(hooks common.Address) HookFlags {
+	return HookFlags(uint16(hooks[common.AddressLength-2])<<8|uint16(hooks[common.AddressLength-1])) & AllHookFlags
+}
+
+// Has reports whether all of flags are set.
+func (f HookFlags) Has(flags HookFlags) bool {
+	return f&flags == flags
+}
+
+// NewPoolKey returns the PoolKey of a V4 pool, with the currencies sorted. The native currency is the zero address.
+func NewPoolKey(currencyA, currencyB common.Address, fee uint32, tickSpacing int32, hooks common.Address) uniswapv4.PoolKey {
+	currency0, currency1 := sortAddressess(currencyA, currencyB)
+	return uniswapv4.PoolKey{
+		Currency0:   currency0,
+		Currency1:   currency1,
+		Fee:         big.NewInt(int64(fee)),
+		TickSpacing: big.NewInt(int64(tickSpacing)),
+		Hooks:       hooks,
+	}
+}
+
+// CalculatePoolIdV4 returns the PoolId of a V4 pool, the keccak of its abi encoded PoolKey.
+func CalculatePoolIdV4(key uniswapv4.PoolKey) (poolID common.Hash, err error) {
+	packed, err := poolKeyArguments.Pack(key.Currency0, key.Currency1, key.Fee, key.TickSpacing, key.Hooks)
+	if err != nil {
+		err = errors.Wrap(err, "pack pool key")
+		return
+	}
+	return crypto.Keccak256Hash(packed), nil
+}
+
+// TickInfoV4 is an initialized tick of a V4 pool.
+type TickInfoV4 struct {
+	Tick           int32
+	LiquidityGross *big.Int
+	LiquidityNet   *big.Int
+}
+
+// PoolStateV4 is the state of a V4 pool read from StateView. Err is why the pool could not be read,
+// such as a pool that is not initialized.
+type PoolStateV4 struct {
+	PoolID       common.Hash
+	SqrtPriceX96 *big.Int
+	Tick         int32
+	ProtocolFee  uint32
+	LpFee        uint32
+	Liquidity    *big.Int
+	Ticks        []TickInfoV4
+	Err          error
+}
+
+// CLMMPool returns the pool for the offline quoter, the ticks being the ones read.
+func (s *PoolStateV4) CLMMPool(key uniswapv4.PoolKey) *clmm.Pool {
+	p := &clmm.Pool{
+		SqrtPriceX96: s.SqrtPriceX96,
+		Tick:         s.Tick,
+		Liquidity:    s.Liquidity,
+		Fee:          s.LpFee,
+		TickSpacing:  int32(key.TickSpacing.Int64()),
+	}
+	for _, t := range s.Ticks {
+		if t.LiquidityGross.Sign() > 0 {
+			p.Ticks = append(p.Ticks, clmm.Tick{Index: t.Tick, LiquidityNet: t.LiquidityNet})
+		}
+	}
+	return p
+}
+
+// PoolQueryV4 is a pool to read with ReadPoolStatesV4, and the ticks to read with it, sorted.
+type PoolQueryV4 struct {
+	PoolID common.Hash
+	Ticks  []int32
+}
+
+// ReadPoolStatesV4 reads slot0, liquidity and the ticks of the pools from the StateView contract at blockNumber,
+// the latest block if nil. The calls are sent in one Flush of mc, with the calls already queued.
+// The error is about the transport, the pools report their own errors.
+func ReadPoolStatesV4(ctx context.Context, mc *rpc.Multicall, stateView common.Address, pools []PoolQueryV4, blockNumber *big.Int) ([]*PoolStateV4, error) {
+	type calls struct {
+		slot0     *rpc.Call
+		liquidity *rpc.Call
+		ticks     []*rpc.Call
+	}
+	queued := make([]calls, len(pools))
+	for i, p := range pools {
+		queued[i].slot0 = mc.Add(stateView, stateViewV4ABI, "getSlot0", p.PoolID)
+		queued[i].liquidity = mc.Add(stateView, stateViewV4ABI, "getLiquidity", p.PoolID)
+		for _, tick := range p.Ticks {
+			queued[i].ticks = append(queued[i].ticks, mc.Add(stateView, stateViewV4ABI, "getTickInfo", p.PoolID, big.NewInt(int64(tick))))
+		}
+	}
+	if err := mc.Flush(ctx, blockNumber); err != nil {
+		return nil, errors.Wrap(err, "read pool states")
+	}
+
+	states := make([]*PoolStateV4, len(pools))
+	for i, p := range pools {
+		s := &PoolStateV4{PoolID: p.PoolID}
+		states[i] = s
+		slot0, err := queued[i].slot0.Outputs()
+		if err != nil {
+			s.Err = errors.Wrap(err, "getSlot0")
+			continue
+		}
+		s.SqrtPriceX96 = slot0[0].(*big.Int)
+		s.Tick = int32(slot0[1].(*big.Int).Int64())
+		s.ProtocolFee = uint32(slot0[2].(*big.Int).Uint64())
+		s.LpFee = uint32(slot0[3].(*big.Int).Uint64())
+		if s.SqrtPriceX96.Sign() == 0 {
+			s.Err = errors.New("pool not initialized")
+			continue
+		}
+		if s.Liquidity, err = rpc.CallResult[*big.Int](queued[i].liquidity); err != nil {
+			s.Err = errors.Wrap(err, "getLiquidity")
+			continue
+		}
+		for j, c := range queued[i].ticks {
+			info, err := c.Outputs()
+			if err != nil {
+				s.Err = errors.Wrapf(err, "getTickInfo %d", p.Ticks[j])
+				break
+			}
+			s.Ticks = append(s.Ticks, TickInfoV4{Tick: p.Ticks[j], LiquidityGross: info[0].(*big.Int), LiquidityNet: info[1].(*big.Int)})
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,102 @@
+package uniswap
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	utilsrpc "github.com/dexerlab/utils-go/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculatePoolIdV4(t *testing.T) {
+	// the ETH/USDC 0.05% pool on Ethereum
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	key := NewPoolKey(usdc, common.Address{}, 500, 10, common.Address{})
+	assert.Equal(t, common.Address{}, key.Currency0)
+	poolID, err := CalculatePoolIdV4(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x21c67e77068de97969ba93d4aab21826d33ca12bb9f565d8496e8fda8a82ca27", poolID.Hex())
+}
+
+func TestHookFlagsOf(t *testing.T) {
+	flags := HookFlagsOf(common.HexToAddress("0x00000000000000000000000000000000000020c0"))
+	assert.True(t, flags.Has(BeforeInitialize|BeforeSwap|AfterSwap))
+	assert.False(t, flags.Has(AfterInitialize))
+	assert.Equal(t, HookFlags(0), HookFlagsOf(common.Address{}))
+	assert.Equal(t, AllHookFlags, HookFlagsOf(common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff")))
+}
+
+// stateViewService is a stand-in node without Multicall3, where the StateView has pool 1 at tick 100
+// with the ticks 60 and 120 initialized, pool 2 not initialized, and the ticks above 1000 revert.
+type stateViewService struct{}
+
+func (s *stateViewService) Call(msg map[string]interface{}, block string) (hexutil.Bytes, error) {
+	if common.HexToAddress(msg["to"].(string)) != common.HexToAddress(StateViewAddrV4) {
+		return nil, nil
+	}
+	data := hexutil.MustDecode(msg["data"].(string))
+	method, err := stateViewV4ABI.MethodById(data)
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	poolID := common.Hash(args[0].([32]byte))
+	switch method.Name {
+	case "getSlot0":
+		if poolID != (common.Hash{1}) {
+			return method.Outputs.Pack(new(big.Int), new(big.Int), new(big.Int), new(big.Int))
+		}
+		return method.Outputs.Pack(big.NewInt(1<<40), big.NewInt(100), big.NewInt(0), big.NewInt(3000))
+	case "getLiquidity":
+		return method.Outputs.Pack(big.NewInt(5000))
+	case "getTickInfo":
+		tick := args[1].(*big.Int)
+		if tick.Int64() > 1000 {
+			return nil, errors.New("execution reverted")
+		}
+		return method.Outputs.Pack(big.NewInt(700), new(big.Int).Neg(tick), new(big.Int), new(big.Int))
+	}
+	return nil, errors.New("execution reverted")
+}
+
+func TestReadPoolStatesV4(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("eth", &stateViewService{}))
+	t.Cleanup(server.Stop)
+	mc := utilsrpc.NewMulticall(ethclient.NewClient(rpc.DialInProc(server)), utilsrpc.MulticallOptions{})
+
+	states, err := ReadPoolStatesV4(context.Background(), mc, common.HexToAddress(StateViewAddrV4), []PoolQueryV4{
+		{PoolID: common.Hash{1}, Ticks: []int32{60, 120}},
+		{PoolID: common.Hash{2}, Ticks: []int32{60}},
+		{PoolID: common.Hash{1}, Ticks: []int32{-60, 1200}},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, states, 3)
+
+	s := states[0]
+	assert.NoError(t, s.Err)
+	assert.Equal(t, big.NewInt(1<<40), s.SqrtPriceX96)
+	assert.Equal(t, int32(100), s.Tick)
+	assert.Equal(t, uint32(3000), s.LpFee)
+	assert.Equal(t, big.NewInt(5000), s.Liquidity)
+	assert.Equal(t, []TickInfoV4{
+		{Tick: 60, LiquidityGross: big.NewInt(700), LiquidityNet: big.NewInt(-60)},
+		{Tick: 120, LiquidityGross: big.NewInt(700), LiquidityNet: big.NewInt(-120)},
+	}, s.Ticks)
+
+	assert.EqualError(t, states[1].Err, "pool not initialized")
+	assert.Empty(t, states[1].Ticks)
+
+	assert.ErrorContains(t, states[2].Err, "getTickInfo 1200")
+	assert.Len(t, states[2].Ticks, 1)
+	assert.Equal(t, big.NewInt(60), states[2].Ticks[0].LiquidityNet)
+}
@@ -0,0 +1,171 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gagliardetto/solana-go"
+	solrpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// EvmLogAdapter fetches the logs of addresses matching topics, its events hold a types.Log.
+// Empty addresses or topics match everything, see ethereum.FilterQuery.
+type EvmLogAdapter struct {
+	client    *ethclient.Client
+	addresses []common.Address
+	topics    [][]common.Hash
+}
+
+func NewEvmLogAdapter(client *ethclient.Client, addresses []common.Address, topics [][]common.Hash) *EvmLogAdapter {
+	return &EvmLogAdapter{client: client, addresses: addresses, topics: topics}
+}
+
+func (a *EvmLogAdapter) FetchEvents(ctx context.Context, from int64, to int64) ([]*Event, error) {
+	logs, err := a.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: big.NewInt(from),
+		ToBlock:   big.NewInt(to),
+		Addresses: a.addresses,
+		Topics:    a.topics,
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*Event, 0, len(logs))
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		events = append(events, &Event{
+			BlockNumber: int64(l.BlockNumber),
+			BlockHash:   l.BlockHash.Hex(),
+			TxHash:      l.TxHash.Hex(),
+			Index:       int64(l.Index),
+			Data:        l,
+		})
+	}
+	return events, nil
+}
+
+// SubscribeNewHeads notifies the new heads when the client is on a websocket.
+func (a *EvmLogAdapter) SubscribeNewHeads(ctx context.Context) (<-chan int64, error) {
+	headers := make(chan *types.Header, 16)
+	sub, err := a.client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return nil, err
+	}
+	heads := make(chan int64, 1)
+	go func() {
+		defer close(heads)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.Err():
+				return
+			case h := <-headers:
+				select {
+				case heads <- h.Number.Int64():
+				default:
+				}
+			}
+		}
+	}()
+	return heads, nil
+}
+
+// JSON-RPC errors of getBlock on a slot without block.
+const (
+	solanaSlotSkipped                = -32007
+	solanaLongTermStorageSlotSkipped = -32009
+)
+
+// SolanaTxAdapter fetches the successful transactions of the slots that invoke one of programs,
+// its events hold a *solrpc.TransactionWithMeta. Every program matches when programs is empty.
+type SolanaTxAdapter struct {
+	client   *solrpc.Client
+	programs map[solana.PublicKey]bool
+}
+
+func NewSolanaTxAdapter(client *solrpc.Client, programs []solana.PublicKey) *SolanaTxAdapter {
+	a := &SolanaTxAdapter{client: client, programs: make(map[solana.PublicKey]bool, len(programs))}
+	for _, program := range programs {
+		a.programs[program] = true
+	}
+	return a
+}
+
+func (a *SolanaTxAdapter) FetchEvents(ctx context.Context, from int64, to int64) ([]*Event, error) {
+	maxVersion := uint64(0)
+	rewards := false
+	var events []*Event
+	for slot := from; slot <= to; slot++ {
+		block, err := a.client.GetBlockWithOpts(ctx, uint64(slot), &solrpc.GetBlockOpts{
+			Encoding:                       solana.EncodingBase64,
+			TransactionDetails:             solrpc.TransactionDetailsFull,
+			Rewards:                        &rewards,
+			Commitment:                     solrpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		if err != nil {
+			var rpcErr *jsonrpc.RPCError
+			if errors.As(err, &rpcErr) && (rpcErr.Code == solanaSlotSkipped || rpcErr.Code == solanaLongTermStorageSlotSkipped) {
+				continue
+			}
+			return nil, err
+		}
+		for i := range block.Transactions {
+			tx := &block.Transactions[i]
+			if tx.Meta == nil || tx.Meta.Err != nil {
+				continue
+			}
+			parsed, err := tx.GetTransaction()
+			if err != nil {
+				return nil, err
+			}
+			if !a.invokes(parsed, tx.Meta) {
+				continue
+			}
+			events = append(events, &Event{
+				BlockNumber: slot,
+				BlockHash:   block.Blockhash.String(),
+				TxHash:      parsed.Signatures[0].String(),
+				Index:       int64(i),
+				Data:        tx,
+			})
+		}
+	}
+	return events, nil
+}
+
+// invokes reports whether tx calls one of the programs, directly or through an inner instruction.
+func (a *SolanaTxAdapter) invokes(tx *solana.Transaction, meta *solrpc.TransactionMeta) bool {
+	if len(a.programs) == 0 {
+		return true
+	}
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	keys = append(keys, meta.LoadedAddresses.Writable...)
+	keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+	isProgram := func(idx uint16) bool {
+		return int(idx) < len(keys) && a.programs[keys[idx]]
+	}
+	for _, inst := range tx.Message.Instructions {
+		if isProgram(inst.ProgramIDIndex) {
+			return true
+		}
+	}
+	for _, inner := range meta.InnerInstructions {
+		for _, inst := range inner.Instructions {
+			if isProgram(inst.ProgramIDIndex) {
+				return true
+			}
+		}
+	}
+	return false
+}
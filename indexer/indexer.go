@@ -0,0 +1,325 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dexerlab/utils-go/log"
+	"github.com/dexerlab/utils-go/rpc"
+)
+
+// Event is an event or a transaction found by an Adapter.
+type Event struct {
+	BlockNumber int64
+	BlockHash   string
+	TxHash      string
+	// Index orders the events of a block: the log index on EVM, the transaction index elsewhere.
+	Index int64
+	// Data is the adapter payload, e.g. a types.Log for EvmLogAdapter.
+	Data interface{}
+}
+
+// Adapter fetches the events of the blocks [from, to] of a chain.
+type Adapter interface {
+	FetchEvents(ctx context.Context, from int64, to int64) ([]*Event, error)
+}
+
+// AdapterFunc turns a function into an Adapter.
+type AdapterFunc func(ctx context.Context, from int64, to int64) ([]*Event, error)
+
+func (f AdapterFunc) FetchEvents(ctx context.Context, from int64, to int64) ([]*Event, error) {
+	return f(ctx, from, to)
+}
+
+// Subscriber is implemented by adapters that are notified of new heads,
+// the indexer then polls the head as soon as a block arrives instead of waiting for PollInterval.
+type Subscriber interface {
+	SubscribeNewHeads(ctx context.Context) (<-chan int64, error)
+}
+
+// Pass tells why a Batch is delivered.
+type Pass int32
+
+const (
+	// PassForward delivers blocks for the first time.
+	PassForward Pass = iota + 1
+	// PassBacktrack delivers blocks again once they are BacktrackDepth blocks deep.
+	PassBacktrack
+	// PassGap delivers blocks whose first delivery failed.
+	PassGap
+)
+
+func (p Pass) String() string {
+	switch p {
+	case PassForward:
+		return "forward"
+	case PassBacktrack:
+		return "backtrack"
+	case PassGap:
+		return "gap"
+	}
+	return "unknown"
+}
+
+// Batch is the events of the blocks [From, To] sorted by block and index.
+type Batch struct {
+	ChainId int32
+	AppId   int32
+	From    int64
+	To      int64
+	Pass    Pass
+	Events  []*Event
+}
+
+// Handler processes a batch. Delivery is at least once: a batch is delivered again
+// when a handler fails, to every handler, and the backtrack pass delivers every block twice,
+// so handlers must be idempotent.
+type Handler func(ctx context.Context, batch *Batch) error
+
+// Options configures an Indexer, zero fields take the defaults.
+type Options struct {
+	ChainId int32
+	AppId   int32
+	// StartBlock is the first block of an app without cursor, the safe head if 0.
+	StartBlock int64
+	// Confirmations keeps the indexer this many blocks behind the head.
+	Confirmations int64
+	// BatchSize is the maximum number of blocks per Batch, 100 by default.
+	BatchSize int64
+	// PollInterval is the wait between two rounds when the indexer caught up, 3s by default.
+	PollInterval time.Duration
+	// MaxRetries is how many times a batch is tried before its blocks are recorded as gaps, 3 by default.
+	MaxRetries int
+	// BacktrackDepth enables the backtrack pass, which delivers again the blocks
+	// this many blocks behind the cursor to catch events lost to reorgs. 0 disables it.
+	BacktrackDepth int64
+	// GapBatchSize is the maximum number of gaps reprocessed per round, 100 by default.
+	GapBatchSize int
+}
+
+// Indexer delivers the events of a chain to handlers, block range after block range.
+// It follows the head through rpc.Rpc, fetches the events through an Adapter
+// and persists its progress in a Store so that it resumes where it stopped.
+type Indexer struct {
+	rpc      rpc.Rpc
+	adapter  Adapter
+	store    Store
+	opt      Options
+	handlers []Handler
+}
+
+func NewIndexer(r rpc.Rpc, adapter Adapter, store Store, opt Options) *Indexer {
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 100
+	}
+	if opt.PollInterval <= 0 {
+		opt.PollInterval = 3 * time.Second
+	}
+	if opt.MaxRetries <= 0 {
+		opt.MaxRetries = 3
+	}
+	if opt.GapBatchSize <= 0 {
+		opt.GapBatchSize = 100
+	}
+	return &Indexer{rpc: r, adapter: adapter, store: store, opt: opt}
+}
+
+// Register adds a handler, handlers are called in the order they were registered.
+// It must not be called once Run started.
+func (ix *Indexer) Register(handler Handler) {
+	ix.handlers = append(ix.handlers, handler)
+}
+
+// Run indexes until ctx is done and returns ctx.Err().
+func (ix *Indexer) Run(ctx context.Context) error {
+	var heads <-chan int64
+	if sub, ok := ix.adapter.(Subscriber); ok {
+		ch, err := sub.SubscribeNewHeads(ctx)
+		if err != nil {
+			log.Warnf("indexer %d/%d: subscribe new heads failed, polling only: %v", ix.opt.ChainId, ix.opt.AppId, err)
+		} else {
+			heads = ch
+		}
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		case _, ok := <-heads:
+			if !ok {
+				heads = nil
+			}
+		}
+
+		caughtUp, err := ix.Step(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Errorf("indexer %d/%d: %v", ix.opt.ChainId, ix.opt.AppId, err)
+		}
+
+		wait := ix.opt.PollInterval
+		if err == nil && !caughtUp {
+			wait = 0
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// Step runs one round: a forward batch, a backtrack batch and the reprocessing of gaps.
+// It reports whether the indexer caught up with the head.
+func (ix *Indexer) Step(ctx context.Context) (caughtUp bool, err error) {
+	head, err := ix.rpc.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get latest block number: %w", err)
+	}
+	safeHead := head - ix.opt.Confirmations
+
+	cursor, err := ix.cursor(ctx, safeHead)
+	if err != nil {
+		return false, err
+	}
+	cursor.LatestBlockNumber = head
+
+	caughtUp = true
+	if from := cursor.BlockNumber + 1; from <= safeHead {
+		to := min(from+ix.opt.BatchSize-1, safeHead)
+		if err := ix.deliverOrGap(ctx, from, to, PassForward); err != nil {
+			return false, err
+		}
+		cursor.BlockNumber = to
+		caughtUp = to == safeHead
+	}
+
+	if ix.opt.BacktrackDepth > 0 {
+		target := cursor.BlockNumber - ix.opt.BacktrackDepth
+		if from := cursor.BacktrackBlockNumber + 1; from <= target {
+			to := min(from+ix.opt.BatchSize-1, target)
+			if err := ix.deliverOrGap(ctx, from, to, PassBacktrack); err != nil {
+				return false, err
+			}
+			cursor.BacktrackBlockNumber = to
+			caughtUp = caughtUp && to == target
+		}
+	}
+
+	if err := ix.store.SaveCursor(ctx, cursor); err != nil {
+		return false, fmt.Errorf("save cursor: %w", err)
+	}
+
+	if err := ix.processGaps(ctx); err != nil {
+		return caughtUp, err
+	}
+	return caughtUp, nil
+}
+
+// cursor loads the cursor, or starts one before StartBlock.
+func (ix *Indexer) cursor(ctx context.Context, safeHead int64) (*Cursor, error) {
+	cursor, err := ix.store.LoadCursor(ctx, ix.opt.ChainId, ix.opt.AppId)
+	if err != nil {
+		return nil, fmt.Errorf("load cursor: %w", err)
+	}
+	if cursor != nil {
+		return cursor, nil
+	}
+	start := ix.opt.StartBlock
+	if start <= 0 {
+		start = max(safeHead, 0)
+	}
+	return &Cursor{
+		Chainid:              ix.opt.ChainId,
+		Appid:                ix.opt.AppId,
+		BlockNumber:          start - 1,
+		BacktrackBlockNumber: start - 1,
+	}, nil
+}
+
+// deliverOrGap delivers [from, to] and records its blocks as gaps if it keeps failing.
+// The error is only about the store, the cursor must not move past the range then.
+func (ix *Indexer) deliverOrGap(ctx context.Context, from int64, to int64, pass Pass) error {
+	var err error
+	for i := 0; i < ix.opt.MaxRetries; i++ {
+		if err = ix.deliver(ctx, from, to, pass); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Warnf("indexer %d/%d: %s blocks [%d, %d] attempt %d failed: %v",
+			ix.opt.ChainId, ix.opt.AppId, pass, from, to, i+1, err)
+	}
+
+	log.Errorf("indexer %d/%d: %s blocks [%d, %d] recorded as gaps: %v", ix.opt.ChainId, ix.opt.AppId, pass, from, to, err)
+	blocks := make([]int64, 0, to-from+1)
+	for b := from; b <= to; b++ {
+		blocks = append(blocks, b)
+	}
+	if err := ix.store.AddGaps(ctx, ix.opt.ChainId, ix.opt.AppId, blocks); err != nil {
+		return fmt.Errorf("add gaps [%d, %d]: %w", from, to, err)
+	}
+	return nil
+}
+
+// deliver fetches the events of [from, to] and hands them to every handler.
+func (ix *Indexer) deliver(ctx context.Context, from int64, to int64, pass Pass) error {
+	events, err := ix.adapter.FetchEvents(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("fetch events: %w", err)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].Index < events[j].Index
+	})
+	batch := &Batch{
+		ChainId: ix.opt.ChainId,
+		AppId:   ix.opt.AppId,
+		From:    from,
+		To:      to,
+		Pass:    pass,
+		Events:  events,
+	}
+	for _, handler := range ix.handlers {
+		if err := handler(ctx, batch); err != nil {
+			return fmt.Errorf("handler: %w", err)
+		}
+	}
+	return nil
+}
+
+// processGaps delivers the recorded gaps once each, a gap that fails again stays recorded.
+func (ix *Indexer) processGaps(ctx context.Context) error {
+	gaps, err := ix.store.ListGaps(ctx, ix.opt.ChainId, ix.opt.AppId, ix.opt.GapBatchSize)
+	if err != nil {
+		return fmt.Errorf("list gaps: %w", err)
+	}
+	var processed []int64
+	for _, block := range gaps {
+		if err := ix.deliver(ctx, block, block, PassGap); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Warnf("indexer %d/%d: gap %d failed: %v", ix.opt.ChainId, ix.opt.AppId, block, err)
+			continue
+		}
+		processed = append(processed, block)
+	}
+	if err := ix.store.MarkGapsProcessed(ctx, ix.opt.ChainId, ix.opt.AppId, processed); err != nil {
+		return fmt.Errorf("mark gaps processed: %w", err)
+	}
+	return ctx.Err()
+}
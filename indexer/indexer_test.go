@@ -0,0 +1,196 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dexerlab/utils-go/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+type headRpc struct {
+	rpc.Rpc
+	mu   sync.Mutex
+	head int64
+}
+
+func (r *headRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.head, nil
+}
+
+func (r *headRpc) setHead(head int64) {
+	r.mu.Lock()
+	r.head = head
+	r.mu.Unlock()
+}
+
+// blockAdapter returns one event per block, blocks in broken fail.
+type blockAdapter struct {
+	mu     sync.Mutex
+	broken map[int64]bool
+}
+
+func (a *blockAdapter) FetchEvents(ctx context.Context, from int64, to int64) ([]*Event, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var events []*Event
+	for b := to; b >= from; b-- {
+		if a.broken[b] {
+			return nil, errors.New("block unavailable")
+		}
+		events = append(events, &Event{BlockNumber: b, Data: b})
+	}
+	return events, nil
+}
+
+func (a *blockAdapter) setBroken(blocks ...int64) {
+	a.mu.Lock()
+	a.broken = make(map[int64]bool)
+	for _, b := range blocks {
+		a.broken[b] = true
+	}
+	a.mu.Unlock()
+}
+
+type recorder struct {
+	mu        sync.Mutex
+	delivered map[Pass][]int64
+}
+
+func (r *recorder) handle(ctx context.Context, batch *Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.delivered == nil {
+		r.delivered = make(map[Pass][]int64)
+	}
+	for _, e := range batch.Events {
+		r.delivered[batch.Pass] = append(r.delivered[batch.Pass], e.BlockNumber)
+	}
+	return nil
+}
+
+func (r *recorder) blocks(pass Pass) []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.delivered[pass]...)
+}
+
+func TestIndexer(t *testing.T) {
+	ctx := context.Background()
+	head := &headRpc{head: 19}
+	adapter := &blockAdapter{}
+	store := NewMemoryStore()
+	ix := NewIndexer(head, adapter, store, Options{
+		ChainId:       1,
+		AppId:         7,
+		StartBlock:    10,
+		Confirmations: 2,
+		BatchSize:     4,
+	})
+	rec := &recorder{}
+	ix.Register(rec.handle)
+
+	caughtUp, err := ix.Step(ctx)
+	assert.NoError(t, err)
+	assert.False(t, caughtUp)
+	assert.Equal(t, []int64{10, 11, 12, 13}, rec.blocks(PassForward), "sorted by block")
+	caughtUp, err = ix.Step(ctx)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Equal(t, []int64{10, 11, 12, 13, 14, 15, 16, 17}, rec.blocks(PassForward))
+
+	cursor, err := store.LoadCursor(ctx, 1, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(17), cursor.BlockNumber)
+	assert.Equal(t, int64(19), cursor.LatestBlockNumber)
+
+	// a range that keeps failing is recorded as gaps and the cursor moves on
+	adapter.setBroken(20)
+	head.setHead(23)
+	_, err = ix.Step(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, rec.blocks(PassForward), 8)
+	assert.Equal(t, []int64{18, 19, 21}, rec.blocks(PassGap), "gaps except block 20")
+	gaps, err := store.ListGaps(ctx, 1, 7, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{20}, gaps)
+	cursor, _ = store.LoadCursor(ctx, 1, 7)
+	assert.Equal(t, int64(21), cursor.BlockNumber)
+
+	adapter.setBroken()
+	_, err = ix.Step(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{18, 19, 21, 20}, rec.blocks(PassGap))
+	gaps, _ = store.ListGaps(ctx, 1, 7, 10)
+	assert.Empty(t, gaps)
+
+	// a failing handler gets the batch again, the cursor resumes after a restart
+	failures := 1
+	ix = NewIndexer(head, adapter, store, Options{ChainId: 1, AppId: 7, Confirmations: 2, BatchSize: 4})
+	rec = &recorder{}
+	ix.Register(rec.handle)
+	ix.Register(func(ctx context.Context, batch *Batch) error {
+		if failures > 0 {
+			failures--
+			return errors.New("db down")
+		}
+		return nil
+	})
+	head.setHead(27)
+	_, err = ix.Step(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{22, 23, 24, 25, 22, 23, 24, 25}, rec.blocks(PassForward))
+	gaps, _ = store.ListGaps(ctx, 1, 7, 10)
+	assert.Empty(t, gaps)
+}
+
+func TestIndexerBacktrack(t *testing.T) {
+	ctx := context.Background()
+	head := &headRpc{head: 9}
+	store := NewMemoryStore()
+	ix := NewIndexer(head, &blockAdapter{}, store, Options{
+		ChainId:        1,
+		AppId:          1,
+		StartBlock:     1,
+		BatchSize:      5,
+		BacktrackDepth: 3,
+	})
+	rec := &recorder{}
+	ix.Register(rec.handle)
+
+	for i := 0; i < 3; i++ {
+		_, err := ix.Step(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9}, rec.blocks(PassForward))
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, rec.blocks(PassBacktrack))
+	cursor, _ := store.LoadCursor(ctx, 1, 1)
+	assert.Equal(t, int64(9), cursor.BlockNumber)
+	assert.Equal(t, int64(6), cursor.BacktrackBlockNumber)
+}
+
+func TestIndexerRun(t *testing.T) {
+	head := &headRpc{head: 5}
+	ix := NewIndexer(head, &blockAdapter{}, NewMemoryStore(), Options{
+		StartBlock:   1,
+		BatchSize:    2,
+		PollInterval: 10 * time.Millisecond,
+	})
+	rec := &recorder{}
+	ix.Register(rec.handle)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ix.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(rec.blocks(PassForward)) == 5 }, time.Second, 5*time.Millisecond)
+	head.setHead(7)
+	assert.Eventually(t, func() bool { return len(rec.blocks(PassForward)) == 7 }, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,166 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/dexerlab/utils-go/dal/model"
+	"github.com/dexerlab/utils-go/dal/query"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cursor is the progress of an app on a chain, a row of t_event_processed_block.
+// BlockNumber is the last block delivered, BacktrackBlockNumber the last block delivered again
+// by the backtrack pass, LatestBlockNumber the head seen by the last round.
+type Cursor = model.TEventProcessedBlock
+
+// Store persists the cursor and the gapped blocks of an indexer.
+type Store interface {
+	// LoadCursor returns nil, nil if the app never ran on the chain.
+	LoadCursor(ctx context.Context, chainId int32, appId int32) (*Cursor, error)
+	SaveCursor(ctx context.Context, cursor *Cursor) error
+	// AddGaps records blocks to reprocess, a block already recorded is marked unprocessed again.
+	AddGaps(ctx context.Context, chainId int32, appId int32, blocks []int64) error
+	// ListGaps returns at most limit unprocessed blocks in ascending order.
+	ListGaps(ctx context.Context, chainId int32, appId int32, limit int) ([]int64, error)
+	MarkGapsProcessed(ctx context.Context, chainId int32, appId int32, blocks []int64) error
+}
+
+// DBStore is a Store on t_event_processed_block and t_gapped_block through the default query,
+// query.SetDefault must have been called.
+type DBStore struct{}
+
+func NewDBStore() *DBStore {
+	return &DBStore{}
+}
+
+func (s *DBStore) LoadCursor(ctx context.Context, chainId int32, appId int32) (*Cursor, error) {
+	q := query.TEventProcessedBlock
+	cursor, err := q.WithContext(ctx).Where(q.Chainid.Eq(chainId), q.Appid.Eq(appId)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return cursor, err
+}
+
+func (s *DBStore) SaveCursor(ctx context.Context, cursor *Cursor) error {
+	return query.TEventProcessedBlock.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chainid"}, {Name: "appid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_number", "latest_block_number", "backtrack_block_number"}),
+	}).Create(cursor)
+}
+
+func (s *DBStore) AddGaps(ctx context.Context, chainId int32, appId int32, blocks []int64) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	gaps := make([]*model.TGappedBlock, len(blocks))
+	for i, block := range blocks {
+		gaps[i] = &model.TGappedBlock{Chainid: chainId, Appid: appId, BlockNumber: block}
+	}
+	return query.TGappedBlock.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chainid"}, {Name: "appid"}, {Name: "block_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_processed"}),
+	}).CreateInBatches(gaps, 500)
+}
+
+func (s *DBStore) ListGaps(ctx context.Context, chainId int32, appId int32, limit int) ([]int64, error) {
+	q := query.TGappedBlock
+	var blocks []int64
+	err := q.WithContext(ctx).
+		Where(q.Chainid.Eq(chainId), q.Appid.Eq(appId), q.IsProcessed.Eq(0)).
+		Order(q.BlockNumber).Limit(limit).
+		Pluck(q.BlockNumber, &blocks)
+	return blocks, err
+}
+
+func (s *DBStore) MarkGapsProcessed(ctx context.Context, chainId int32, appId int32, blocks []int64) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	q := query.TGappedBlock
+	_, err := q.WithContext(ctx).
+		Where(q.Chainid.Eq(chainId), q.Appid.Eq(appId), q.BlockNumber.In(blocks...)).
+		Update(q.IsProcessed, 1)
+	return err
+}
+
+type storeKey struct {
+	chainId int32
+	appId   int32
+}
+
+// MemoryStore is a Store in memory, for tests and for indexers that can restart from scratch.
+type MemoryStore struct {
+	mu      sync.Mutex
+	cursors map[storeKey]Cursor
+	gaps    map[storeKey]map[int64]bool // block -> processed
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		cursors: make(map[storeKey]Cursor),
+		gaps:    make(map[storeKey]map[int64]bool),
+	}
+}
+
+func (s *MemoryStore) LoadCursor(ctx context.Context, chainId int32, appId int32) (*Cursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cursor, ok := s.cursors[storeKey{chainId, appId}]
+	if !ok {
+		return nil, nil
+	}
+	return &cursor, nil
+}
+
+func (s *MemoryStore) SaveCursor(ctx context.Context, cursor *Cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[storeKey{cursor.Chainid, cursor.Appid}] = *cursor
+	return nil
+}
+
+func (s *MemoryStore) AddGaps(ctx context.Context, chainId int32, appId int32, blocks []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := storeKey{chainId, appId}
+	if s.gaps[key] == nil {
+		s.gaps[key] = make(map[int64]bool)
+	}
+	for _, block := range blocks {
+		s.gaps[key][block] = false
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListGaps(ctx context.Context, chainId int32, appId int32, limit int) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var blocks []int64
+	for block, processed := range s.gaps[storeKey{chainId, appId}] {
+		if !processed {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	if len(blocks) > limit {
+		blocks = blocks[:limit]
+	}
+	return blocks, nil
+}
+
+func (s *MemoryStore) MarkGapsProcessed(ctx context.Context, chainId int32, appId int32, blocks []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gaps := s.gaps[storeKey{chainId, appId}]
+	for _, block := range blocks {
+		if _, ok := gaps[block]; ok {
+			gaps[block] = true
+		}
+	}
+	return nil
+}
@@ -7,13 +7,8 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/NethermindEth/starknet.go/rpc"
-	"github.com/block-vision/sui-go-sdk/sui"
 	"github.com/dexerlab/utils-go/alert"
 	"github.com/dexerlab/utils-go/convert"
-	"github.com/ethereum/go-ethereum/ethclient"
-	solrpc "github.com/gagliardetto/solana-go/rpc"
-	"github.com/sentioxyz/fuel-go"
 	"github.com/xssnick/tonutils-go/liteclient"
 	"github.com/xssnick/tonutils-go/ton"
 )
@@ -65,6 +60,10 @@ type ChainInfo struct {
 	DepositContractAddress  sql.NullString
 	Layer1                  sql.NullString
 	Client                  interface{}
+
+	// Nodes are the rpc endpoints of t_node_info, RpcEndPoint is not included.
+	Nodes []*NodeInfo
+	db    *sql.DB
 }
 
 func (ci *ChainInfo) GetInt32ChainId() int32 {
@@ -186,6 +185,7 @@ func (mgr *ChainInfoManager) LoadAllChains() {
 	allChains := make([]*ChainInfo, 0)
 
 	counter := 0
+	nodes := mgr.loadNodes()
 
 	// Iterate over the result set
 	for rows.Next() {
@@ -214,23 +214,7 @@ func (mgr *ChainInfoManager) LoadAllChains() {
 			chain.DepositContractAddress.String = strings.TrimSpace(chain.DepositContractAddress.String)
 			chain.Layer1.String = strings.TrimSpace(chain.Layer1.String)
 
-			if chain.Backend == EthereumBackend {
-				chain.Client, err = ethclient.Dial(chain.RpcEndPoint)
-				if err != nil {
-					mgr.alerter.AlertText("create evm client error", err)
-					continue
-				}
-			} else if chain.Backend == StarknetBackend {
-				chain.Client, err = rpc.NewProvider(chain.RpcEndPoint)
-				if err != nil {
-					mgr.alerter.AlertText("create starknet client error", err)
-					continue
-				}
-			} else if chain.Backend == SolanaBackend {
-				chain.Client = solrpc.New(chain.RpcEndPoint)
-			} else if chain.Backend == SuiBackend {
-				chain.Client = sui.NewSuiClient(chain.RpcEndPoint)
-			} else if chain.Backend == TonBackend {
+			if chain.Backend == TonBackend {
 				if mgr.tonClient == nil {
 					client := liteclient.NewConnectionPool()
 					configUrl := ConfigURLTestnet
@@ -249,9 +233,15 @@ func (mgr *ChainInfoManager) LoadAllChains() {
 				} else {
 					chain.Client = mgr.tonClient
 				}
-			} else if chain.Backend == FuelBackend {
-				chain.Client = fuel.NewClient(chain.RpcEndPoint)
+			} else {
+				chain.Client, err = DialClient(chain.Backend, chain.RpcEndPoint)
+				if err != nil {
+					mgr.alerter.AlertText("create "+chain.Name+" client error", err)
+					continue
+				}
 			}
+			chain.Nodes = nodes[chain.GetInt64ChainId()]
+			chain.db = mgr.db
 
 			idChains[chain.Id] = &chain
 			chainIdChains[strings.ToLower(chain.ChainId)] = &chain
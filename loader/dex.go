@@ -42,6 +42,19 @@ type PoolDyn struct {
 	Token1ID   int64
 }
 
+// RoutePool is a pool of a chain with its token addresses, fee and raw token balances, to build a routing graph.
+type RoutePool struct {
+	ID         int64
+	Address    string
+	DexPoolID  int32
+	Token0     string
+	Token1     string
+	FeePpm     int32
+	Liquidity0 decimal.Decimal
+	Liquidity1 decimal.Decimal
+	Liquidityu float64
+}
+
 // factory: 0x1..|0x2...|0x3...
 type DexManager struct {
 	chainFamousTokens map[string]map[string]*model.TFamousToken // chainname -> address -> famous token
@@ -292,6 +305,64 @@ func (mgr *DexManager) GetTokenDyn(chainid int64, address string, cache bool, ca
 	return dyn, true, nil
 }
 
+// GetTokenDynByID is GetTokenDyn for a token known by its id, such as PoolDyn.Token0ID.
+func (mgr *DexManager) GetTokenDynByID(id int64, cache bool, cache404 bool) (TokenDyn, bool, error) {
+	if cache {
+		if v, ok := mgr.tokenDyns.Get(id); ok {
+			if v.ID <= 0 {
+				return TokenDyn{}, false, nil
+			} else {
+				return v, true, nil
+			}
+		}
+	}
+
+	d := query.TTokenDynamic
+	s := query.TTokenStatic
+	var dyn TokenDyn
+	err := d.WithContext(context.Background()).
+		Select(d.ID, d.Priceu, d.BestPoolID, d.BestPoolVliq, s.Decimals).
+		LeftJoin(s, s.TokenID.EqCol(d.ID)).
+		Where(d.ID.Eq(id)).Scan(&dyn)
+	if err != nil {
+		mgr.alerter.AlertText("DexManager.GetTokenDynByID: query token failed", err)
+		return TokenDyn{}, false, err
+	}
+	if dyn.ID <= 0 {
+		if cache && cache404 {
+			mgr.tokenDyns.Set(id, TokenDyn{ID: 0}, 1)
+		}
+		return TokenDyn{}, false, nil
+	}
+
+	if cache {
+		mgr.tokenDyns.Set(id, dyn, 1)
+	}
+	return dyn, true, nil
+}
+
+// GetRoutePools returns the pools of a chain with at least minLiquidityu of liquidity, with their tokens.
+func (mgr *DexManager) GetRoutePools(chainid int64, minLiquidityu float64) ([]RoutePool, error) {
+	d := query.TPoolDynamic
+	s := query.TPoolStatic
+	t0 := query.TTokenDynamic.As("t0")
+	t1 := query.TTokenDynamic.As("t1")
+
+	var pools []RoutePool
+	err := d.WithContext(context.Background()).
+		Select(d.ID, d.Address, s.DexPoolID, t0.Address.As("token0"), t1.Address.As("token1"), s.FeePpm,
+			d.Liquidity0, d.Liquidity1, d.Liquidityu).
+		Join(s, s.PoolID.EqCol(d.ID)).
+		Join(t0, t0.ID.EqCol(s.Token0ID)).
+		Join(t1, t1.ID.EqCol(s.Token1ID)).
+		Where(d.ChainID.Eq(chainid), d.Liquidityu.Gte(minLiquidityu)).Scan(&pools)
+	if err != nil {
+		mgr.alerter.AlertText("DexManager.GetRoutePools: query pools failed", err)
+		return nil, err
+	}
+	return pools, nil
+}
+
 func (mgr *DexManager) DbUpdatePoolDynBatch(chainid int64, ids []int64, liq0s []decimal.Decimal, liq1s []decimal.Decimal,
 	liqus []float64, block int64) error {
 
@@ -0,0 +1,73 @@
+package loader
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/NethermindEth/starknet.go/rpc"
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/dexerlab/utils-go/dal/model"
+	"github.com/ethereum/go-ethereum/ethclient"
+	solrpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sentioxyz/fuel-go"
+)
+
+type NodeInfo = model.TNodeInfo
+
+// DialClient creates the client of a rpc endpoint for the backends dialed by url.
+// Backends without such a client (bitcoin, zkslite, bfc, ...) return a nil client.
+func DialClient(backend Backend, url string) (interface{}, error) {
+	switch backend {
+	case EthereumBackend:
+		return ethclient.Dial(url)
+	case StarknetBackend:
+		return rpc.NewProvider(url)
+	case SolanaBackend:
+		return solrpc.New(url), nil
+	case SuiBackend:
+		return sui.NewSuiClient(url), nil
+	case FuelBackend:
+		return fuel.NewClient(url), nil
+	}
+	return nil, nil
+}
+
+// UpdateNodeUsability sets the usability of node and writes it back to t_node_info.
+func (ci *ChainInfo) UpdateNodeUsability(node *NodeInfo, usability int32) error {
+	node.Usability = usability
+	if ci.db == nil || node.ID == 0 {
+		return nil
+	}
+	_, err := ci.db.Exec("UPDATE t_node_info SET usability = ? WHERE id = ?", usability, node.ID)
+	return err
+}
+
+// loadNodes returns the rows of t_node_info grouped by chain id.
+func (mgr *ChainInfoManager) loadNodes() map[int64][]*NodeInfo {
+	nodes := make(map[int64][]*NodeInfo)
+	rows, err := mgr.db.Query("SELECT id, chain_id, rpc_url, type, usability FROM t_node_info")
+	if err != nil || rows == nil {
+		mgr.alerter.AlertText("select t_node_info error", err)
+		return nodes
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var node NodeInfo
+		var usability sql.NullInt32
+		if err = rows.Scan(&node.ID, &node.ChainID, &node.RPCURL, &node.Type, &usability); err != nil {
+			mgr.alerter.AlertText("scan t_node_info row error", err)
+			continue
+		}
+		node.RPCURL = strings.TrimSpace(node.RPCURL)
+		node.Usability = 100
+		if usability.Valid {
+			node.Usability = usability.Int32
+		}
+		nodes[node.ChainID] = append(nodes[node.ChainID], &node)
+	}
+	if err = rows.Err(); err != nil {
+		mgr.alerter.AlertText("get next t_node_info row error", err)
+	}
+	return nodes
+}
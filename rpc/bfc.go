@@ -34,6 +34,18 @@ func (w *BenfenRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr strin
 	return w.GetBalance(ctx, ownerAddr, tokenAddr)
 }
 
+func (w *BenfenRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(tokenAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddr, tokenAddrs[i])
+	})
+}
+
+func (w *BenfenRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
 func (w *BenfenRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool) (*loader.TokenInfo, error) {
 	return nil, fmt.Errorf("no impl")
 }
@@ -75,6 +87,10 @@ func (w *BenfenRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64,
 	return false, 0, fmt.Errorf("not impl")
 }
 
+func (w *BenfenRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	return nil, fmt.Errorf("not impl")
+}
+
 func (w *BenfenRpc) Client() interface{} {
 	return w.chainInfo.Client
 }
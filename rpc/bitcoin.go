@@ -3,6 +3,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -15,11 +16,15 @@ import (
 	"github.com/dexerlab/utils-go/util"
 	_ "github.com/gagliardetto/solana-go"
 	"github.com/ninja0404/go-unisat"
+	"github.com/shopspring/decimal"
 )
 
 type BitcoinRpc struct {
 	chainInfo *loader.ChainInfo
 	apolloSDK *apollosdk.ApolloSDK
+
+	// unisatAPIConfig overrides the apollo config, for tests.
+	unisatAPIConfig UnisatAPIConfig
 }
 
 type UnisatAPIConfig map[string]*chainServerBearer
@@ -66,24 +71,93 @@ func (w *BitcoinRpc) GetChecksumAddress(addr string) string {
 	return a.String()
 }
 
+// unisatServer returns the unisat api of the chain.
+func (w *BitcoinRpc) unisatServer() (*chainServerBearer, error) {
+	unisatAPIConfig := w.unisatAPIConfig
+	if unisatAPIConfig == nil {
+		if w.apolloSDK == nil {
+			return nil, fmt.Errorf("unisat api config of %v not found", w.chainInfo.Name)
+		}
+		var err error
+		unisatAPIConfig, err = apollosdk.GetConfig(w.apolloSDK, "base_config", "unisat_api_config", ParseUnisatAPIConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	server, ok := unisatAPIConfig[w.chainInfo.Name]
+	if !ok || server == nil {
+		return nil, fmt.Errorf("unisat api config of %v not found", w.chainInfo.Name)
+	}
+	return server, nil
+}
+
 func (w *BitcoinRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool) (*loader.TokenInfo, error) {
-	return nil, fmt.Errorf("no impl")
+	tokenAddr = strings.TrimSpace(tokenAddr)
+	if util.IsHexStringZero(tokenAddr) {
+		return &loader.TokenInfo{
+			TokenName:    w.chainInfo.GasTokenName,
+			ChainName:    w.chainInfo.Name,
+			TokenAddress: tokenAddr,
+			Decimals:     w.chainInfo.GasTokenDecimal,
+			FullName:     w.chainInfo.AliasName,
+			TotalSupply:  decimal.Zero,
+		}, nil
+	}
+	if !strings.HasPrefix(tokenAddr, "brc20_") || len(tokenAddr) <= 6 {
+		return nil, fmt.Errorf("not impl")
+	}
+
+	server, err := w.unisatServer()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := unisat.GetBrc20Info(ctx, server.Server, server.Bearer, tokenAddr[6:])
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("unisat GetBrc20Info error: %v", resp.Message)
+	}
+	totalSupply, err := decimal.NewFromString(resp.Data.Max)
+	if err != nil {
+		totalSupply = decimal.Zero
+	}
+	return &loader.TokenInfo{
+		TokenName:    resp.Data.Ticker,
+		ChainName:    w.chainInfo.Name,
+		TokenAddress: tokenAddr,
+		Decimals:     int32(resp.Data.Decimal),
+		FullName:     resp.Data.Ticker,
+		TotalSupply:  totalSupply,
+	}, nil
 }
 
 func (w *BitcoinRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error) {
 	return w.GetBalance(ctx, ownerAddr, tokenAddr)
 }
 
+func (w *BitcoinRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(tokenAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddr, tokenAddrs[i])
+	})
+}
+
+func (w *BitcoinRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
 func (w *BitcoinRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
 	ownerAddr = strings.TrimSpace(ownerAddr)
 	tokenAddr = strings.TrimSpace(tokenAddr)
 
-	unisatAPIConfig, err := apollosdk.GetConfig(w.apolloSDK, "base_config", "unisat_api_config", ParseUnisatAPIConfig)
+	server, err := w.unisatServer()
 	if err != nil {
 		return nil, err
 	}
 	if util.IsHexStringZero(tokenAddr) {
-		resp, err := unisat.GetAddressBalance(ctx, unisatAPIConfig[w.chainInfo.Name].Server, unisatAPIConfig[w.chainInfo.Name].Bearer, ownerAddr)
+		resp, err := unisat.GetAddressBalance(ctx, server.Server, server.Bearer, ownerAddr)
 		if err != nil {
 			return nil, err
 		}
@@ -93,7 +167,7 @@ func (w *BitcoinRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr
 		return resp.Data.Satoshi, nil
 	} else if strings.HasPrefix(tokenAddr, "brc20_") && len(tokenAddr) > 6 {
 		brc20 := tokenAddr[6:]
-		resp, err := unisat.GetAddressBrc20TickInfo(ctx, unisatAPIConfig[w.chainInfo.Name].Server, unisatAPIConfig[w.chainInfo.Name].Bearer, ownerAddr, brc20)
+		resp, err := unisat.GetAddressBrc20TickInfo(ctx, server.Server, server.Bearer, ownerAddr, brc20)
 		if err != nil {
 			return nil, err
 		}
@@ -114,8 +188,75 @@ func (w *BitcoinRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAd
 	return big.NewInt(0), fmt.Errorf("not impl")
 }
 
+// IsTxSuccess returns the height of a confirmed transaction, a transaction still in the mempool is an error.
+// A bitcoin transaction cannot fail once mined.
 func (w *BitcoinRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
-	return false, 0, fmt.Errorf("not impl")
+	tx, err := w.getTxInfo(ctx, hash)
+	if err != nil {
+		return false, 0, err
+	}
+	if tx.Confirmations <= 0 {
+		return false, 0, fmt.Errorf("bitcoin tx %s not confirmed yet", hash)
+	}
+	return true, tx.Height, nil
+}
+
+// GetTxConfirmations returns the number of confirmations of a transaction, 0 while it is in the mempool.
+func (w *BitcoinRpc) GetTxConfirmations(ctx context.Context, hash string) (int64, error) {
+	tx, err := w.getTxInfo(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+	return int64(tx.Confirmations), nil
+}
+
+// ErrBitcoinTxNotFound is returned for a transaction unknown to unisat.
+var ErrBitcoinTxNotFound = errors.New("unisat tx not found")
+
+// bitcoinFinalityDepth is the confirmations after which a bitcoin transaction is final.
+const bitcoinFinalityDepth = 6
+
+// GetTxStatus counts the confirmations of a transaction, it is final after bitcoinFinalityDepth.
+// Transactions unknown to unisat are dropped.
+func (w *BitcoinRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	tx, err := w.getTxInfo(ctx, hash)
+	status := &TxStatus{Hash: hash}
+	if errors.Is(err, ErrBitcoinTxNotFound) {
+		status.State = TxStateDropped
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tx.InSatoshi != nil && tx.OutSatoshi != nil {
+		status.Fee = new(big.Int).Sub(tx.InSatoshi, tx.OutSatoshi)
+	}
+	if tx.Confirmations <= 0 {
+		status.State = TxStatePending
+		return status, nil
+	}
+	status.BlockNumber = tx.Height
+	status.Confirmations = int64(tx.Confirmations)
+	status.finalize(true, status.Confirmations >= bitcoinFinalityDepth, "")
+	return status, nil
+}
+
+func (w *BitcoinRpc) getTxInfo(ctx context.Context, hash string) (*unisat.Tx, error) {
+	server, err := w.unisatServer()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := unisat.GetTxInfo(ctx, server.Server, server.Bearer, strings.TrimSpace(hash))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		if strings.Contains(strings.ToLower(resp.Message), "not found") {
+			return nil, fmt.Errorf("%w: %v", ErrBitcoinTxNotFound, resp.Message)
+		}
+		return nil, fmt.Errorf("unisat GetTxInfo error: %v", resp.Message)
+	}
+	return &resp.Data, nil
 }
 
 func (w *BitcoinRpc) Client() interface{} {
@@ -127,7 +268,18 @@ func (w *BitcoinRpc) Backend() int32 {
 }
 
 func (w *BitcoinRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
-	return 0, fmt.Errorf("not impl")
+	server, err := w.unisatServer()
+	if err != nil {
+		return 0, err
+	}
+	resp, err := unisat.GetBlockchainInfo(ctx, server.Server, server.Bearer)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Code != 0 {
+		return 0, fmt.Errorf("unisat GetBlockchainInfo error: %v", resp.Message)
+	}
+	return resp.Data.Blocks, nil
 }
 
 //type unisatServer struct {
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitcoinRpc(t *testing.T) {
+	txs := map[string]string{
+		"b61b0172d95e266c18aea0c624db987e971a5d6d4ebc2aaed85da4642d635735": "unisat_tx_confirmed",
+		"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16": "unisat_tx_mempool",
+	}
+	srv := serveFixtures(t, func(r *http.Request) string {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			return ""
+		}
+		if r.URL.Path == "/v1/indexer/blockchain/info" {
+			return "unisat_blockchain_info"
+		}
+		if name, ok := txs[strings.TrimPrefix(r.URL.Path, "/v1/indexer/tx/")]; ok {
+			return name
+		}
+		return "unisat_tx_not_found"
+	})
+	w := NewBitcoinRpc(&loader.ChainInfo{Name: "Bitcoin"}, nil)
+	ctx := context.Background()
+
+	_, err := w.GetLatestBlockNumber(ctx)
+	assert.Error(t, err, "no unisat config")
+
+	w.unisatAPIConfig = UnisatAPIConfig{"Bitcoin": {Server: srv.URL, Bearer: "token"}}
+	height, err := w.GetLatestBlockNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(865214), height)
+
+	ok, blockNumber, err := w.IsTxSuccess(ctx, "b61b0172d95e266c18aea0c624db987e971a5d6d4ebc2aaed85da4642d635735")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(865201), blockNumber)
+	confirmations, err := w.GetTxConfirmations(ctx, "b61b0172d95e266c18aea0c624db987e971a5d6d4ebc2aaed85da4642d635735")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(14), confirmations)
+
+	_, _, err = w.IsTxSuccess(ctx, "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16")
+	assert.Error(t, err)
+	confirmations, err = w.GetTxConfirmations(ctx, "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), confirmations)
+
+	_, err = w.GetTxConfirmations(ctx, "0000000000000000000000000000000000000000000000000000000000000000")
+	assert.ErrorIs(t, err, ErrBitcoinTxNotFound)
+
+	status, err := w.GetTxStatus(ctx, "b61b0172d95e266c18aea0c624db987e971a5d6d4ebc2aaed85da4642d635735")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, status.State)
+	assert.Equal(t, int64(865201), status.BlockNumber)
+	assert.Equal(t, int64(14), status.Confirmations)
+	assert.Equal(t, int64(2000), status.Fee.Int64())
+
+	status, err = w.GetTxStatus(ctx, "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStatePending, status.State)
+	assert.Equal(t, int64(1500), status.Fee.Int64())
+
+	status, err = w.GetTxStatus(ctx, "0000000000000000000000000000000000000000000000000000000000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateDropped, status.State)
+}
@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -14,7 +15,6 @@ import (
 	"github.com/dexerlab/utils-go/util"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
@@ -28,15 +28,40 @@ type EvmRpc struct {
 	tokenInfoMgr *loader.TokenInfoManager
 	chainInfo    *loader.ChainInfo
 	erc20ABI     abi.ABI
+	multicallOpt MulticallOptions
 }
 
 func NewEvmRpc(chainInfo *loader.ChainInfo) *EvmRpc {
 	erc20ABI, _ := abi.JSON(strings.NewReader(erc20.Erc20ABI))
-	return &EvmRpc{
+	w := &EvmRpc{
 		chainInfo:    chainInfo,
 		tokenInfoMgr: loader.NewTokenInfoManager(nil, nil),
 		erc20ABI:     erc20ABI,
 	}
+	if chainInfo.Name == owlconsts.ZKSyncEra {
+		w.multicallOpt.Address = Multicall3ZksyncAddress
+	}
+	return w
+}
+
+// SetMulticallOptions sets the options of the multicalls the ERC20 reads go through.
+func (w *EvmRpc) SetMulticallOptions(opt MulticallOptions) {
+	w.multicallOpt = opt
+}
+
+// NewMulticall returns an empty Multicall on the client of the chain.
+func (w *EvmRpc) NewMulticall() *Multicall {
+	return NewMulticall(w.GetClient(), w.multicallOpt)
+}
+
+// erc20Call reads a single ERC20 value at blockNumber, the latest block if nil.
+func (w *EvmRpc) erc20Call(ctx context.Context, blockNumber *big.Int, tokenAddr string, method string, args ...interface{}) (*big.Int, error) {
+	mc := w.NewMulticall()
+	call := mc.Add(common.HexToAddress(tokenAddr), &w.erc20ABI, method, args...)
+	if err := mc.Flush(ctx, blockNumber); err != nil {
+		return nil, err
+	}
+	return CallResult[*big.Int](call)
 }
 
 func (w *EvmRpc) IsAddressValid(addr string) bool {
@@ -75,99 +100,32 @@ func (w *EvmRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool)
 		return tokenInfo, nil
 	}
 
-	var symbolHex hexutil.Bytes
-	var nameHex hexutil.Bytes
-	var decimalsHex hexutil.Bytes
-	var totalSupplyHex hexutil.Bytes
-
-	symbolData, _ := w.erc20ABI.Pack("symbol")
-	decimalsData, _ := w.erc20ABI.Pack("decimals")
-	nameData, _ := w.erc20ABI.Pack("name")
-	totalSupplyData, _ := w.erc20ABI.Pack("totalSupply")
-
-	var be []rpc.BatchElem
-	be = append(be, rpc.BatchElem{
-		Method: "eth_call",
-		Args: []interface{}{
-			map[string]interface{}{
-				"to":   tokenAddr,
-				"data": hexutil.Encode(symbolData),
-			},
-			"latest",
-		},
-		Result: &symbolHex,
-	})
-	be = append(be, rpc.BatchElem{
-		Method: "eth_call",
-		Args: []interface{}{
-			map[string]interface{}{
-				"to":   tokenAddr,
-				"data": hexutil.Encode(decimalsData),
-			},
-			"latest",
-		},
-		Result: &decimalsHex,
-	})
-	be = append(be, rpc.BatchElem{
-		Method: "eth_call",
-		Args: []interface{}{
-			map[string]interface{}{
-				"to":   tokenAddr,
-				"data": hexutil.Encode(nameData),
-			},
-			"latest"},
-		Result: &nameHex,
-	})
-	be = append(be, rpc.BatchElem{
-		Method: "eth_call",
-		Args: []interface{}{
-			map[string]interface{}{
-				"to":   tokenAddr,
-				"data": hexutil.Encode(totalSupplyData),
-			},
-			"latest",
-		},
-		Result: &totalSupplyHex,
-	})
-
-	if err := w.GetClient().Client().BatchCallContext(ctx, be); err != nil {
-		return nil, err
-	}
-	for _, b := range be {
-		if b.Error != nil {
-			return nil, fmt.Errorf("get token error %s %w", b.Method, b.Error)
-		}
-	}
-
-	symbolr, err := w.erc20ABI.Unpack("symbol", symbolHex)
-	if err != nil || len(symbolr) != 1 {
+	token := common.HexToAddress(tokenAddr)
+	mc := w.NewMulticall()
+	symbolCall := mc.Add(token, &w.erc20ABI, "symbol")
+	decimalsCall := mc.Add(token, &w.erc20ABI, "decimals")
+	nameCall := mc.Add(token, &w.erc20ABI, "name")
+	totalSupplyCall := mc.Add(token, &w.erc20ABI, "totalSupply")
+	if err := mc.Flush(ctx, nil); err != nil {
 		return nil, err
 	}
 
-	namer, err := w.erc20ABI.Unpack("name", nameHex)
-	if err != nil || len(namer) != 1 {
-		return nil, err
-	}
-	symbol, ok := symbolr[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("symbol not string")
+	symbol, err := CallResult[string](symbolCall)
+	if err != nil {
+		return nil, fmt.Errorf("get token error symbol %w", err)
 	}
-	name, ok := namer[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("name not string")
+	name, err := CallResult[string](nameCall)
+	if err != nil {
+		return nil, fmt.Errorf("get token error name %w", err)
 	}
-
-	decimalsBytes, err := hexutil.Decode(decimalsHex.String())
+	decimals, err := CallResult[uint8](decimalsCall)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get token error decimals %w", err)
 	}
-	decimals := new(big.Int).SetBytes(decimalsBytes)
-
-	totalSupplyBytes, err := hexutil.Decode(totalSupplyHex.String())
+	totalSupply, err := CallResult[*big.Int](totalSupplyCall)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get token error totalSupply %w", err)
 	}
-	totalSupply := new(big.Int).SetBytes(totalSupplyBytes)
 
 	// if decimals.Cmp(common.Big0) < 0 || len(symbol) == 0 {
 	// 	return nil, fmt.Errorf("not found")
@@ -177,7 +135,7 @@ func (w *EvmRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool)
 		TokenName:    strings.TrimSpace(string(symbol)),
 		ChainName:    w.chainInfo.Name,
 		TokenAddress: tokenAddr,
-		Decimals:     int32(decimals.Uint64()),
+		Decimals:     int32(decimals),
 		FullName:     strings.TrimSpace(string(name)),
 		TotalSupply:  decimal.NewFromBigInt(totalSupply, 0),
 	}
@@ -188,16 +146,7 @@ func (w *EvmRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool)
 }
 
 func (w *EvmRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
-	econtract, err := erc20.NewErc20(common.HexToAddress(tokenAddr), w.GetClient())
-	if err != nil {
-		return nil, err
-	}
-	allowance, err := econtract.Allowance(nil, common.HexToAddress(ownerAddr), common.HexToAddress(spenderAddr))
-
-	if err != nil {
-		return nil, err
-	}
-	return allowance, nil
+	return w.erc20Call(ctx, nil, tokenAddr, "allowance", common.HexToAddress(ownerAddr), common.HexToAddress(spenderAddr))
 }
 
 func (w *EvmRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error) {
@@ -211,21 +160,7 @@ func (w *EvmRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string,
 		}
 		return nativeBalance, nil
 	} else {
-		econtract, err := erc20.NewErc20(common.HexToAddress(tokenAddr), w.GetClient())
-		if err != nil {
-			return nil, err
-		}
-
-		balance, err := econtract.BalanceOf(&bind.CallOpts{
-			Pending:     false,
-			Context:     ctx,
-			BlockNumber: big.NewInt(blockNumber),
-		}, common.HexToAddress(ownerAddr))
-
-		if err != nil {
-			return nil, err
-		}
-		return balance, nil
+		return w.erc20Call(ctx, big.NewInt(blockNumber), tokenAddr, "balanceOf", common.HexToAddress(ownerAddr))
 	}
 }
 
@@ -240,17 +175,52 @@ func (w *EvmRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr str
 		}
 		return nativeBalance, nil
 	} else {
-		econtract, err := erc20.NewErc20(common.HexToAddress(tokenAddr), w.GetClient())
-		if err != nil {
-			return nil, err
+		return w.erc20Call(ctx, nil, tokenAddr, "balanceOf", common.HexToAddress(ownerAddr))
+	}
+}
+
+func (w *EvmRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	ownerAddrs := make([]string, len(tokenAddrs))
+	for i := range ownerAddrs {
+		ownerAddrs[i] = ownerAddr
+	}
+	return w.getBalances(ctx, ownerAddrs, tokenAddrs)
+}
+
+func (w *EvmRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	tokenAddrs := make([]string, len(ownerAddrs))
+	for i := range tokenAddrs {
+		tokenAddrs[i] = tokenAddr
+	}
+	return w.getBalances(ctx, ownerAddrs, tokenAddrs)
+}
+
+// getBalances gets the balance of ownerAddrs[i] in tokenAddrs[i] with a single multicall.
+func (w *EvmRpc) getBalances(ctx context.Context, ownerAddrs []string, tokenAddrs []string) ([]*big.Int, error) {
+	mc := w.NewMulticall()
+	calls := make([]*Call, len(ownerAddrs))
+	for i := range ownerAddrs {
+		owner := common.HexToAddress(strings.TrimSpace(ownerAddrs[i]))
+		tokenAddr := strings.TrimSpace(tokenAddrs[i])
+		if util.IsHexStringZero(tokenAddr) {
+			calls[i] = mc.AddEthBalance(owner)
+		} else {
+			calls[i] = mc.Add(common.HexToAddress(tokenAddr), &w.erc20ABI, "balanceOf", owner)
 		}
-		balance, err := econtract.BalanceOf(nil, common.HexToAddress(ownerAddr))
+	}
+	if err := mc.Flush(ctx, nil); err != nil {
+		return nil, err
+	}
 
+	balances := make([]*big.Int, len(calls))
+	for i, call := range calls {
+		balance, err := CallResult[*big.Int](call)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("get balance of %s in %s error %w", ownerAddrs[i], tokenAddrs[i], err)
 		}
-		return balance, nil
+		balances[i] = balance
 	}
+	return balances, nil
 }
 
 func (w *EvmRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
@@ -264,6 +234,119 @@ func (w *EvmRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, err
 	return receipt.Status == ethtypes.ReceiptStatusSuccessful, receipt.BlockNumber.Int64(), nil
 }
 
+// evmFinalityDepth is the confirmations after which a transaction is final on chains
+// supporting neither the finalized nor the safe block tags.
+const evmFinalityDepth = 64
+
+// GetTxStatus reads the receipt, the head and the finalized and safe blocks in one batch.
+// A transaction is final once its block is finalized, or safe on chains without the finalized tag.
+// The revert reason of a failed transaction comes from replaying it on the state of its parent block.
+func (w *EvmRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	type blockHead struct {
+		Number *hexutil.Big `json:"number"`
+	}
+	var (
+		receipt   *ethtypes.Receipt
+		head      hexutil.Uint64
+		finalized *blockHead
+		safe      *blockHead
+	)
+	txHash := common.HexToHash(hash)
+	be := []rpc.BatchElem{
+		{Method: "eth_getTransactionReceipt", Args: []interface{}{txHash}, Result: &receipt},
+		{Method: "eth_blockNumber", Result: &head},
+		{Method: "eth_getBlockByNumber", Args: []interface{}{"finalized", false}, Result: &finalized},
+		{Method: "eth_getBlockByNumber", Args: []interface{}{"safe", false}, Result: &safe},
+	}
+	if err := w.GetClient().Client().BatchCallContext(ctx, be); err != nil {
+		return nil, err
+	}
+	for _, e := range be[:2] {
+		if e.Error != nil {
+			return nil, e.Error
+		}
+	}
+
+	status := &TxStatus{Hash: hash}
+	if receipt == nil {
+		_, isPending, err := w.GetClient().TransactionByHash(ctx, txHash)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			status.State = TxStateDropped
+		case err != nil:
+			return nil, err
+		case isPending:
+			status.State = TxStatePending
+		default:
+			return nil, fmt.Errorf("get receipt failed")
+		}
+		return status, nil
+	}
+
+	status.BlockNumber = receipt.BlockNumber.Int64()
+	status.Confirmations = confirmations(int64(head), status.BlockNumber)
+	status.GasUsed = receipt.GasUsed
+	// effectiveGasPrice is optional, some nodes and chains leave it out
+	if receipt.EffectiveGasPrice != nil {
+		status.Fee = new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+		if receipt.BlobGasPrice != nil {
+			status.Fee.Add(status.Fee, new(big.Int).Mul(new(big.Int).SetUint64(receipt.BlobGasUsed), receipt.BlobGasPrice))
+		}
+	}
+
+	var final bool
+	switch {
+	case be[2].Error == nil && finalized != nil:
+		final = finalized.Number.ToInt().Cmp(receipt.BlockNumber) >= 0
+	case be[3].Error == nil && safe != nil:
+		final = safe.Number.ToInt().Cmp(receipt.BlockNumber) >= 0
+	default:
+		final = status.Confirmations >= evmFinalityDepth
+	}
+
+	success := receipt.Status == ethtypes.ReceiptStatusSuccessful
+	var revertReason string
+	if !success {
+		revertReason = w.revertReason(ctx, txHash, receipt.BlockNumber)
+	}
+	status.finalize(success, final, revertReason)
+	return status, nil
+}
+
+// revertReason replays a failed transaction on the state of the parent of its block.
+func (w *EvmRpc) revertReason(ctx context.Context, txHash common.Hash, blockNumber *big.Int) string {
+	const unknown = "execution reverted"
+	tx, _, err := w.GetClient().TransactionByHash(ctx, txHash)
+	if err != nil {
+		return unknown
+	}
+	from, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return unknown
+	}
+	_, err = w.GetClient().CallContract(ctx, ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}, new(big.Int).Sub(blockNumber, big.NewInt(1)))
+	if err == nil {
+		// the transaction succeeds on the parent state, it failed on a state change of its own block
+		return unknown
+	}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if reason, err := abi.UnpackRevert(common.FromHex(data)); err == nil {
+				return reason
+			}
+		}
+	}
+	return err.Error()
+}
+
 func (w *EvmRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	blockNumber, err := w.GetClient().BlockNumber(ctx)
 	if err != nil {
@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/dexerlab/utils-go/log"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrReorgTooDeep is returned with the reorg when no block of the ring is on the canonical chain
+// anymore, the tracker then starts again from the head.
+var ErrReorgTooDeep = errors.New("reorg deeper than the tracked blocks")
+
+// BlockRef identifies a block of an EVM chain.
+type BlockRef struct {
+	Number     int64
+	Hash       common.Hash
+	ParentHash common.Hash
+}
+
+// Reorg is a rollback of the blocks [From, To], Orphaned holds the tracked ones in ascending order.
+// Ancestor is the last block both chains share, From is Ancestor.Number + 1.
+// When the reorg is deeper than the ring Ancestor is zero and blocks before From may be orphaned too.
+type Reorg struct {
+	From     int64
+	To       int64
+	Ancestor BlockRef
+	Orphaned []BlockRef
+}
+
+// ReorgHandler undoes what was written for the orphaned blocks.
+type ReorgHandler func(ctx context.Context, reorg *Reorg)
+
+// ReorgTracker follows the head of an EVM chain and compares the hash of the recent blocks
+// with the canonical chain to detect reorgs. It keeps the last depth blocks in a ring,
+// a reorg deeper than that is reported as ErrReorgTooDeep.
+type ReorgTracker struct {
+	client *ethclient.Client
+
+	mu       sync.Mutex
+	ring     []BlockRef // block n at n % depth
+	head     int64      // -1 when empty
+	handlers []ReorgHandler
+}
+
+// NewReorgTracker tracks the last depth blocks of w, 64 if depth <= 0.
+func (w *EvmRpc) NewReorgTracker(depth int) *ReorgTracker {
+	return NewReorgTracker(w.GetClient(), depth)
+}
+
+func NewReorgTracker(client *ethclient.Client, depth int) *ReorgTracker {
+	if depth <= 0 {
+		depth = evmFinalityDepth
+	}
+	return &ReorgTracker{client: client, ring: make([]BlockRef, depth), head: -1}
+}
+
+// OnReorg registers a handler called by Poll for every reorg, before the new blocks are tracked.
+// The handler runs with the tracker locked and must not call it.
+func (t *ReorgTracker) OnReorg(handler ReorgHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers = append(t.handlers, handler)
+}
+
+// Head returns the last tracked block, false if nothing is tracked yet.
+func (t *ReorgTracker) Head() (BlockRef, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(t.head)
+}
+
+// Block returns the tracked block number, false if it is not in the ring.
+func (t *ReorgTracker) Block(number int64) (BlockRef, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(number)
+}
+
+func (t *ReorgTracker) get(number int64) (BlockRef, bool) {
+	if number < 0 || number > t.head || number <= t.head-int64(len(t.ring)) {
+		return BlockRef{}, false
+	}
+	ref := t.ring[number%int64(len(t.ring))]
+	return ref, ref.Number == number && ref.Hash != (common.Hash{})
+}
+
+func (t *ReorgTracker) put(ref BlockRef) {
+	t.ring[ref.Number%int64(len(t.ring))] = ref
+	t.head = ref.Number
+}
+
+// Poll catches up with the latest block and returns the reorg it found, nil if the chain only grew.
+// The handlers are called with the reorg before Poll returns.
+func (t *ReorgTracker) Poll(ctx context.Context) (*Reorg, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	latest, err := t.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	latestRef := headerRef(latest)
+
+	var reorg *Reorg
+	var deepErr error
+	ancestor := t.head
+	if t.head >= 0 {
+		ancestor, err = t.findAncestor(ctx, latestRef)
+		if errors.Is(err, ErrReorgTooDeep) {
+			deepErr = err
+		} else if err != nil {
+			return nil, err
+		}
+		if ancestor < t.head {
+			reorg = t.rollback(ancestor)
+			log.Warnf("reorg tracker: blocks [%d, %d] orphaned", reorg.From, reorg.To)
+			for _, handler := range t.handlers {
+				handler(ctx, reorg)
+			}
+		}
+		if deepErr != nil {
+			clear(t.ring)
+			t.head, ancestor = -1, -1
+		}
+	}
+
+	if err := t.extend(ctx, ancestor, latestRef); err != nil {
+		return reorg, err
+	}
+	return reorg, deepErr
+}
+
+// findAncestor returns the highest tracked block still on the canonical chain of latest.
+func (t *ReorgTracker) findAncestor(ctx context.Context, latest BlockRef) (int64, error) {
+	n := min(t.head, latest.Number)
+	oldest := max(t.head-int64(len(t.ring))+1, 0)
+	for n >= oldest {
+		ref, ok := t.get(n)
+		if !ok {
+			break
+		}
+		canonical := latest
+		if n != latest.Number {
+			header, err := t.client.HeaderByNumber(ctx, big.NewInt(n))
+			if err != nil {
+				return 0, err
+			}
+			canonical = headerRef(header)
+		}
+		if canonical.Hash == ref.Hash {
+			return n, nil
+		}
+		n--
+	}
+	return n, ErrReorgTooDeep
+}
+
+// rollback drops the blocks after ancestor and returns them as a Reorg.
+func (t *ReorgTracker) rollback(ancestor int64) *Reorg {
+	reorg := &Reorg{From: ancestor + 1, To: t.head}
+	reorg.Ancestor, _ = t.get(ancestor)
+	for n := ancestor + 1; n <= t.head; n++ {
+		if ref, ok := t.get(n); ok {
+			reorg.Orphaned = append(reorg.Orphaned, ref)
+		}
+	}
+	t.head = ancestor
+	return reorg
+}
+
+// extend tracks the blocks after from up to latest, at most the ring size, fetched in a single batch.
+// It stops at the first block that does not chain to the previous one, the next Poll handles it.
+func (t *ReorgTracker) extend(ctx context.Context, from int64, latest BlockRef) error {
+	start := max(from+1, latest.Number-int64(len(t.ring))+1)
+	if start > latest.Number {
+		return nil
+	}
+	headers := make([]*ethtypes.Header, latest.Number-start)
+	be := make([]rpc.BatchElem, len(headers))
+	for i := range be {
+		be[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeBig(big.NewInt(start + int64(i))), false},
+			Result: &headers[i],
+		}
+	}
+	if len(be) > 0 {
+		if err := t.client.Client().BatchCallContext(ctx, be); err != nil {
+			return err
+		}
+	}
+
+	refs := make([]BlockRef, 0, len(headers)+1)
+	for i, header := range headers {
+		if be[i].Error != nil {
+			return be[i].Error
+		}
+		if header == nil {
+			return fmt.Errorf("block %d not found", start+int64(i))
+		}
+		refs = append(refs, headerRef(header))
+	}
+	refs = append(refs, latest)
+
+	for _, ref := range refs {
+		if prev, ok := t.get(ref.Number - 1); ok && t.head == ref.Number-1 && prev.Hash != ref.ParentHash {
+			break
+		}
+		if t.head >= 0 && t.head != ref.Number-1 {
+			// a gap larger than the ring, start over
+			clear(t.ring)
+		}
+		t.put(ref)
+	}
+	return nil
+}
+
+// Run polls every interval until ctx is done.
+func (t *ReorgTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := t.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("reorg tracker poll error %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func headerRef(header *ethtypes.Header) BlockRef {
+	return BlockRef{
+		Number:     header.Number.Int64(),
+		Hash:       header.Hash(),
+		ParentHash: header.ParentHash,
+	}
+}
@@ -0,0 +1,365 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// ethService is a stand-in node where the native balance of an owner is its last address byte,
+// and its balance in a token 1000 times the last byte of the token plus that of the owner.
+// Tokens whose last byte is 0xff revert.
+type ethService struct {
+	noMulticall bool
+	aggregates  int
+}
+
+func (s *ethService) GetBalance(owner common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(int64(owner[19])))
+}
+
+func (s *ethService) Call(msg map[string]interface{}, block string) (hexutil.Bytes, error) {
+	to := common.HexToAddress(msg["to"].(string))
+	data := hexutil.MustDecode(msg["data"].(string))
+	if to == Multicall3Address && s.noMulticall {
+		return nil, nil
+	}
+	if to == Multicall3Address && [4]byte(data) == [4]byte(multicall3ABI.Methods["aggregate3"].ID) {
+		return s.aggregate3(data)
+	}
+	ret, ok := s.call(to, data)
+	if !ok {
+		return nil, errors.New("execution reverted")
+	}
+	return ret, nil
+}
+
+func (s *ethService) call(to common.Address, data []byte) ([]byte, bool) {
+	owner := common.BytesToAddress(data[4:36])
+	if to == Multicall3Address {
+		return common.LeftPadBytes(big.NewInt(int64(owner[19])).Bytes(), 32), true
+	}
+	if to[19] == 0xff {
+		reason, _ := abi.Arguments{{Type: abi.Type{T: abi.StringTy}}}.Pack("paused")
+		return append(hexutil.MustDecode("0x08c379a0"), reason...), false
+	}
+	return common.LeftPadBytes(big.NewInt(int64(to[19])*1000+int64(owner[19])).Bytes(), 32), true
+}
+
+func (s *ethService) aggregate3(data []byte) (hexutil.Bytes, error) {
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	s.aggregates++
+	method := multicall3ABI.Methods["aggregate3"]
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(args[0], new([]call3)).(*[]call3)
+	results := make([]result, len(calls))
+	for i, c := range calls {
+		results[i].ReturnData, results[i].Success = s.call(c.Target, c.CallData)
+	}
+	return method.Outputs.Pack(results)
+}
+
+func newTestEvmRpc(t *testing.T, s *ethService) *EvmRpc {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("eth", s))
+	t.Cleanup(server.Stop)
+	return NewEvmRpc(&loader.ChainInfo{Name: "test", Client: ethclient.NewClient(rpc.DialInProc(server))})
+}
+
+func TestEvmGetBalances(t *testing.T) {
+	for _, s := range []*ethService{{}, {noMulticall: true}} {
+		w := newTestEvmRpc(t, s)
+		owner := "0x0000000000000000000000000000000000000007"
+		tokens := []string{
+			"0x0000000000000000000000000000000000000000",
+			"0x0000000000000000000000000000000000000001",
+			"0x0000000000000000000000000000000000000002",
+		}
+		balances, err := w.GetBalances(context.Background(), owner, tokens)
+		assert.NoError(t, err)
+		assert.Equal(t, []*big.Int{big.NewInt(7), big.NewInt(1007), big.NewInt(2007)}, balances)
+
+		owners := make([]string, 150)
+		for i := range owners {
+			owners[i] = common.BigToAddress(big.NewInt(int64(i))).Hex()
+		}
+		w.SetMulticallOptions(MulticallOptions{MaxCalls: 100})
+		balances, err = w.GetBalancesForOwners(context.Background(), owners, tokens[2])
+		assert.NoError(t, err)
+		assert.Len(t, balances, 150)
+		assert.Equal(t, int64(2149), balances[149].Int64())
+
+		balance, err := w.GetBalance(context.Background(), owner, tokens[1])
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1007), balance.Int64())
+	}
+}
+
+func TestMulticall(t *testing.T) {
+	s := &ethService{}
+	w := newTestEvmRpc(t, s)
+	mc := NewMulticall(w.GetClient(), MulticallOptions{MaxGas: 250_000})
+	owner := common.HexToAddress("0x0000000000000000000000000000000000000003")
+	ok := mc.Add(common.HexToAddress("0x0000000000000000000000000000000000000005"), &w.erc20ABI, "balanceOf", owner)
+	reverted := mc.Add(common.HexToAddress("0x00000000000000000000000000000000000000ff"), &w.erc20ABI, "balanceOf", owner)
+	badArgs := mc.Add(common.HexToAddress("0x0000000000000000000000000000000000000005"), &w.erc20ABI, "balanceOf")
+	native := mc.AddEthBalance(owner)
+	assert.NoError(t, mc.Flush(context.Background(), nil))
+	assert.Equal(t, 0, mc.Len())
+	// 3 packed calls of 100k gas in chunks of at most 250k
+	assert.Equal(t, 2, s.aggregates)
+
+	balance, err := CallResult[*big.Int](ok)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5003), balance.Int64())
+	_, err = CallResult[*big.Int](reverted)
+	assert.ErrorIs(t, err, ErrCallReverted)
+	assert.ErrorContains(t, err, "paused")
+	assert.Error(t, badArgs.Err())
+	balance, err = CallResult[*big.Int](native)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), balance.Int64())
+	_, err = CallResult[string](native)
+	assert.Error(t, err)
+}
+
+func newSimulatedBackend(t *testing.T, alloc ethtypes.GenesisAlloc) (*simulated.Backend, *ethclient.Client) {
+	// the node is dialed over ipc, in a short directory for the socket path limit
+	dir, err := os.MkdirTemp("", "sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ipc := filepath.Join(dir, "geth.ipc")
+	backend := simulated.NewBackend(alloc, func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+		nodeConf.IPCPath = ipc
+	})
+	t.Cleanup(func() { backend.Close() })
+	client, err := rpc.Dial(ipc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return backend, ethclient.NewClient(client)
+}
+
+// revertInitCode is a contract creation reverting with Error("nope").
+var revertInitCode = append(hexutil.MustDecode("0x6064600c60003960646000fd"), append(
+	hexutil.MustDecode("0x08c379a0"),
+	hexutil.MustDecode("0x"+
+		"0000000000000000000000000000000000000000000000000000000000000020"+
+		"0000000000000000000000000000000000000000000000000000000000000004"+
+		"6e6f706500000000000000000000000000000000000000000000000000000000")...)...)
+
+func TestEvmGetTxStatus(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	backend, client := newSimulatedBackend(t, ethtypes.GenesisAlloc{from: {Balance: big.NewInt(1e18)}})
+	w := NewEvmRpc(&loader.ChainInfo{Name: "test", Client: client})
+	ctx := context.Background()
+
+	chainID, err := client.ChainID(ctx)
+	assert.NoError(t, err)
+	signer := ethtypes.LatestSignerForChainID(chainID)
+	var nonce uint64
+	send := func(to *common.Address, gas uint64, data []byte) common.Hash {
+		tx := ethtypes.MustSignNewTx(key, signer, &ethtypes.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: big.NewInt(1e9),
+			GasFeeCap: big.NewInt(10e9),
+			Gas:       gas,
+			To:        to,
+			Value:     big.NewInt(0),
+			Data:      data,
+		})
+		nonce++
+		assert.NoError(t, client.SendTransaction(ctx, tx))
+		return tx.Hash()
+	}
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	transfer := send(&to, 21000, nil)
+	backend.Commit()
+	failed := send(nil, 100000, revertInitCode)
+	backend.Commit()
+
+	status, err := w.GetTxStatus(ctx, transfer.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateIncluded, status.State)
+	assert.Equal(t, int64(1), status.BlockNumber)
+	assert.Equal(t, int64(2), status.Confirmations)
+	assert.Equal(t, uint64(21000), status.GasUsed)
+	assert.True(t, status.Fee.Sign() > 0)
+	assert.False(t, status.Final)
+
+	status, err = w.GetTxStatus(ctx, failed.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+	assert.Equal(t, "nope", status.RevertReason)
+	assert.False(t, status.IsSuccess())
+
+	// the simulated beacon finalizes every 32 blocks
+	for i := 0; i < 30; i++ {
+		backend.Commit()
+	}
+	status, err = w.GetTxStatus(ctx, transfer.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, status.State)
+	assert.True(t, status.Final)
+	status, err = w.GetTxStatus(ctx, failed.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+	assert.True(t, status.Final)
+
+	pending := send(&to, 21000, nil)
+	status, err = w.GetTxStatus(ctx, pending.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStatePending, status.State)
+
+	status, err = w.GetTxStatus(ctx, common.HexToHash("0x01").Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateDropped, status.State)
+}
+
+// receiptService is a stand-in node returning a successful receipt without effectiveGasPrice in block 5,
+// with the head at block 7 and no finalized or safe block.
+type receiptService struct{}
+
+func (s *receiptService) GetTransactionReceipt(hash common.Hash) map[string]interface{} {
+	return map[string]interface{}{
+		"transactionHash":   hash,
+		"blockHash":         common.Hash{5},
+		"blockNumber":       "0x5",
+		"transactionIndex":  "0x0",
+		"status":            "0x1",
+		"cumulativeGasUsed": "0x5208",
+		"gasUsed":           "0x5208",
+		"logs":              []interface{}{},
+		"logsBloom":         hexutil.Bytes(make([]byte, 256)),
+	}
+}
+
+func (s *receiptService) BlockNumber() hexutil.Uint64 {
+	return 7
+}
+
+func (s *receiptService) GetBlockByNumber(number string, full bool) (map[string]interface{}, error) {
+	return nil, errors.New("unknown block")
+}
+
+func TestEvmGetTxStatusWithoutEffectiveGasPrice(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("eth", &receiptService{}))
+	t.Cleanup(server.Stop)
+	w := NewEvmRpc(&loader.ChainInfo{Name: "test", Client: ethclient.NewClient(rpc.DialInProc(server))})
+
+	status, err := w.GetTxStatus(context.Background(), common.Hash{1}.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateIncluded, status.State)
+	assert.Equal(t, int64(5), status.BlockNumber)
+	assert.Equal(t, int64(3), status.Confirmations)
+	assert.Equal(t, uint64(21000), status.GasUsed)
+	assert.Nil(t, status.Fee)
+}
+
+func TestReorgTracker(t *testing.T) {
+	backend, client := newSimulatedBackend(t, ethtypes.GenesisAlloc{})
+	tracker := NewEvmRpc(&loader.ChainInfo{Name: "test", Client: client}).NewReorgTracker(8)
+	var reorgs []*Reorg
+	tracker.OnReorg(func(ctx context.Context, reorg *Reorg) {
+		reorgs = append(reorgs, reorg)
+	})
+	ctx := context.Background()
+
+	hashes := []common.Hash{{}}
+	for i := 0; i < 5; i++ {
+		hashes = append(hashes, backend.Commit())
+	}
+	reorg, err := tracker.Poll(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, reorg)
+	head, ok := tracker.Head()
+	assert.True(t, ok)
+	assert.Equal(t, BlockRef{Number: 5, Hash: hashes[5], ParentHash: hashes[4]}, head)
+	block, ok := tracker.Block(1)
+	assert.True(t, ok)
+	assert.Equal(t, hashes[1], block.Hash)
+
+	// the chain rewinds to block 3
+	assert.NoError(t, backend.Fork(hashes[3]))
+	reorg, err = tracker.Poll(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), reorg.From)
+	assert.Equal(t, int64(5), reorg.To)
+	assert.Equal(t, hashes[3], reorg.Ancestor.Hash)
+	assert.Equal(t, []common.Hash{hashes[4], hashes[5]}, []common.Hash{reorg.Orphaned[0].Hash, reorg.Orphaned[1].Hash})
+	assert.Equal(t, []*Reorg{reorg}, reorgs)
+
+	// and grows a side chain
+	assert.NoError(t, backend.AdjustTime(10*time.Second))
+	backend.Commit()
+	side := backend.Commit()
+	reorg, err = tracker.Poll(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, reorg)
+	head, _ = tracker.Head()
+	assert.Equal(t, side, head.Hash)
+	block, _ = tracker.Block(4)
+	assert.NotEqual(t, hashes[4], block.Hash)
+
+	// a block replaced at the same height
+	assert.NoError(t, backend.Fork(head.ParentHash))
+	assert.NoError(t, backend.AdjustTime(20*time.Second))
+	reorg, err = tracker.Poll(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), reorg.From)
+	assert.Equal(t, int64(6), reorg.To)
+	assert.Equal(t, side, reorg.Orphaned[0].Hash)
+
+	// a reorg deeper than the ring
+	for i := 0; i < 20; i++ {
+		backend.Commit()
+	}
+	_, err = tracker.Poll(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, backend.Fork(hashes[2]))
+	assert.NoError(t, backend.AdjustTime(30*time.Second))
+	reorg, err = tracker.Poll(ctx)
+	assert.ErrorIs(t, err, ErrReorgTooDeep)
+	assert.Equal(t, int64(26), reorg.To)
+	assert.Len(t, reorg.Orphaned, 8)
+	head, _ = tracker.Head()
+	assert.Equal(t, int64(3), head.Number)
+	_, ok = tracker.Block(2)
+	assert.True(t, ok)
+	assert.Len(t, reorgs, 3)
+}
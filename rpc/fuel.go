@@ -77,6 +77,51 @@ func (f *FuelRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, er
 	}
 }
 
+// GetTxStatus maps the status of the transaction, blocks of the Fuel block producer are final.
+func (f *FuelRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	txn, err := f.GetClient().GetTransaction(ctx, types.QueryTransactionParams{
+		Id: types.TransactionId{Hash: common.HexToHash(hash)},
+	}, fuel.GetTransactionOption{
+		WithStatus: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	status := &TxStatus{Hash: hash}
+	switch {
+	case txn == nil || txn.Status == nil:
+		status.State = TxStateDropped
+		return status, nil
+	case txn.Status.SubmittedStatus != nil:
+		status.State = TxStatePending
+		return status, nil
+	case txn.Status.SqueezedOutStatus != nil:
+		status.State = TxStateDropped
+		status.RevertReason = string(txn.Status.SqueezedOutStatus.Reason)
+		return status, nil
+	case txn.Status.SuccessStatus != nil:
+		st := txn.Status.SuccessStatus
+		status.BlockNumber = int64(st.BlockHeight)
+		status.GasUsed = uint64(st.TotalGas)
+		status.Fee = new(big.Int).SetUint64(uint64(st.TotalFee))
+		status.finalize(true, true, "")
+	case txn.Status.FailureStatus != nil:
+		st := txn.Status.FailureStatus
+		status.BlockNumber = int64(st.BlockHeight)
+		status.GasUsed = uint64(st.TotalGas)
+		status.Fee = new(big.Int).SetUint64(uint64(st.TotalFee))
+		status.finalize(false, true, string(st.Reason))
+	default:
+		return nil, fmt.Errorf("fuel tx: %v unknown status %v", hash, txn.Status.TypeName_)
+	}
+	head, err := f.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Confirmations = confirmations(head, status.BlockNumber)
+	return status, nil
+}
+
 func (f *FuelRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
 	return nil, fmt.Errorf("not implement")
 }
@@ -127,6 +172,18 @@ func (f *FuelRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string,
 	return f.GetBalance(ctx, ownerAddr, tokenAddr)
 }
 
+func (f *FuelRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(tokenAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return f.GetBalance(ctx, ownerAddr, tokenAddrs[i])
+	})
+}
+
+func (f *FuelRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return f.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
 func (f *FuelRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool) (*loader.TokenInfo, error) {
 	return nil, fmt.Errorf("not implement")
 }
@@ -0,0 +1,306 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const multicall3ABIJSON = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"},{"inputs":[{"internalType":"address","name":"addr","type":"address"}],"name":"getEthBalance","outputs":[{"internalType":"uint256","name":"balance","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
+var multicall3ABI, _ = abi.JSON(strings.NewReader(multicall3ABIJSON))
+
+var (
+	// Multicall3Address is the address of Multicall3 on most EVM chains.
+	Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+	// Multicall3ZksyncAddress is the address of Multicall3 on zkSync Era.
+	Multicall3ZksyncAddress = common.HexToAddress("0xF9cda624FBC7e059355ce98a31693d299FACd963")
+)
+
+// ErrCallReverted is wrapped by the error of a call that reverted inside aggregate3.
+var ErrCallReverted = errors.New("call reverted")
+
+// MulticallOptions configures a Multicall, zero fields take the defaults.
+type MulticallOptions struct {
+	// Address is the Multicall3 contract, Multicall3Address by default.
+	Address common.Address
+	// An aggregate3 call holds at most MaxCalls calls (500) and MaxGas gas (30M),
+	// calls without a Gas estimate count for CallGas (100k).
+	MaxCalls int
+	MaxGas   uint64
+	CallGas  uint64
+}
+
+// Call is a contract call queued in a Multicall. Its result is available after Flush.
+type Call struct {
+	Target common.Address
+	ABI    *abi.ABI
+	Method string
+	Args   []interface{}
+	// Gas is the gas estimate of the call used to split batches.
+	Gas uint64
+
+	native     bool // getEthBalance of the Multicall3 contract
+	data       []byte
+	returnData []byte
+	err        error
+}
+
+// Err returns why the call failed, nil if it succeeded.
+func (c *Call) Err() error {
+	return c.err
+}
+
+// ReturnData returns the raw output of the call.
+func (c *Call) ReturnData() []byte {
+	return c.returnData
+}
+
+// Outputs returns the unpacked outputs of the call.
+func (c *Call) Outputs() ([]interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.ABI.Unpack(c.Method, c.returnData)
+}
+
+// CallResult returns the first output of c as a T.
+func CallResult[T any](c *Call) (T, error) {
+	var ret T
+	outputs, err := c.Outputs()
+	if err != nil {
+		return ret, err
+	}
+	if len(outputs) == 0 {
+		return ret, fmt.Errorf("%s returned nothing", c.Method)
+	}
+	ret, ok := outputs[0].(T)
+	if !ok {
+		return ret, fmt.Errorf("%s returned %T instead of %T", c.Method, outputs[0], ret)
+	}
+	return ret, nil
+}
+
+// Multicall queues contract calls and runs them with Multicall3 aggregate3.
+// Every call is allowed to fail on its own, its error is reported by Call.Err.
+// On chains without Multicall3 the calls are sent as a JSON-RPC batch of eth_call.
+type Multicall struct {
+	client *ethclient.Client
+	opt    MulticallOptions
+	calls  []*Call
+}
+
+func NewMulticall(client *ethclient.Client, opt MulticallOptions) *Multicall {
+	if opt.Address == (common.Address{}) {
+		opt.Address = Multicall3Address
+	}
+	if opt.MaxCalls <= 0 {
+		opt.MaxCalls = 500
+	}
+	if opt.MaxGas == 0 {
+		opt.MaxGas = 30_000_000
+	}
+	if opt.CallGas == 0 {
+		opt.CallGas = 100_000
+	}
+	return &Multicall{client: client, opt: opt}
+}
+
+// Add queues a call of method on target.
+func (m *Multicall) Add(target common.Address, contractABI *abi.ABI, method string, args ...interface{}) *Call {
+	c := &Call{Target: target, ABI: contractABI, Method: method, Args: args}
+	m.calls = append(m.calls, c)
+	return c
+}
+
+// AddEthBalance queues a read of the native balance of owner, its result is a *big.Int.
+func (m *Multicall) AddEthBalance(owner common.Address) *Call {
+	c := m.Add(m.opt.Address, &multicall3ABI, "getEthBalance", owner)
+	c.native = true
+	return c
+}
+
+// Len returns the number of queued calls.
+func (m *Multicall) Len() int {
+	return len(m.calls)
+}
+
+// Flush runs the queued calls at blockNumber, the latest block if nil, and empties the queue.
+// The calls are split in as many aggregate3 as needed, all sent in a single JSON-RPC batch.
+// The error is only about the transport, the calls report their own errors.
+func (m *Multicall) Flush(ctx context.Context, blockNumber *big.Int) error {
+	calls := m.calls
+	m.calls = nil
+
+	pending := make([]*Call, 0, len(calls))
+	for _, c := range calls {
+		if c.data, c.err = c.ABI.Pack(c.Method, c.Args...); c.err == nil {
+			pending = append(pending, c)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	chunks := m.split(pending)
+	results := make([]hexutil.Bytes, len(chunks))
+	be := make([]rpc.BatchElem, len(chunks))
+	for i, chunk := range chunks {
+		type call3 struct {
+			Target       common.Address
+			AllowFailure bool
+			CallData     []byte
+		}
+		args := make([]call3, len(chunk))
+		for j, c := range chunk {
+			args[j] = call3{Target: c.Target, AllowFailure: true, CallData: c.data}
+		}
+		data, err := multicall3ABI.Pack("aggregate3", args)
+		if err != nil {
+			return err
+		}
+		be[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{callMsg(m.opt.Address, data), blockNumberArg(blockNumber)},
+			Result: &results[i],
+		}
+	}
+	if err := m.client.Client().BatchCallContext(ctx, be); err != nil {
+		return err
+	}
+
+	var missing []*Call
+	for i, chunk := range chunks {
+		if be[i].Error != nil {
+			for _, c := range chunk {
+				c.err = be[i].Error
+			}
+			continue
+		}
+		if len(results[i]) == 0 {
+			// no contract at the address
+			missing = append(missing, chunk...)
+			continue
+		}
+		if err := setResults(chunk, results[i]); err != nil {
+			for _, c := range chunk {
+				c.err = err
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return m.fallback(ctx, missing, blockNumber)
+	}
+	return nil
+}
+
+// split cuts calls in chunks within MaxCalls and MaxGas.
+func (m *Multicall) split(calls []*Call) [][]*Call {
+	var chunks [][]*Call
+	var chunk []*Call
+	var gas uint64
+	for _, c := range calls {
+		callGas := c.Gas
+		if callGas == 0 {
+			callGas = m.opt.CallGas
+		}
+		if len(chunk) > 0 && (len(chunk) >= m.opt.MaxCalls || gas+callGas > m.opt.MaxGas) {
+			chunks = append(chunks, chunk)
+			chunk, gas = nil, 0
+		}
+		chunk = append(chunk, c)
+		gas += callGas
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// setResults decodes the output of aggregate3 into chunk.
+func setResults(chunk []*Call, data []byte) error {
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	out, err := multicall3ABI.Unpack("aggregate3", data)
+	if err != nil {
+		return err
+	}
+	results := *abi.ConvertType(out[0], new([]result)).(*[]result)
+	if len(results) != len(chunk) {
+		return fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(chunk))
+	}
+	for i, r := range results {
+		chunk[i].returnData = r.ReturnData
+		if !r.Success {
+			chunk[i].err = revertError(r.ReturnData)
+		}
+	}
+	return nil
+}
+
+// fallback runs calls one by one in a JSON-RPC batch.
+func (m *Multicall) fallback(ctx context.Context, calls []*Call, blockNumber *big.Int) error {
+	results := make([]hexutil.Bytes, len(calls))
+	balances := make([]hexutil.Big, len(calls))
+	be := make([]rpc.BatchElem, len(calls))
+	for i, c := range calls {
+		if c.native {
+			be[i] = rpc.BatchElem{
+				Method: "eth_getBalance",
+				Args:   []interface{}{c.Args[0], blockNumberArg(blockNumber)},
+				Result: &balances[i],
+			}
+			continue
+		}
+		be[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{callMsg(c.Target, c.data), blockNumberArg(blockNumber)},
+			Result: &results[i],
+		}
+	}
+	if err := m.client.Client().BatchCallContext(ctx, be); err != nil {
+		return err
+	}
+	for i, c := range calls {
+		switch {
+		case be[i].Error != nil:
+			c.err = be[i].Error
+		case c.native:
+			c.returnData = common.LeftPadBytes(balances[i].ToInt().Bytes(), 32)
+		default:
+			c.returnData = results[i]
+		}
+	}
+	return nil
+}
+
+func revertError(data []byte) error {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return fmt.Errorf("%w: %s", ErrCallReverted, reason)
+	}
+	return ErrCallReverted
+}
+
+func callMsg(to common.Address, data []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"to":   to,
+		"data": hexutil.Bytes(data),
+	}
+}
+
+func blockNumberArg(blockNumber *big.Int) string {
+	if blockNumber == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(blockNumber)
+}
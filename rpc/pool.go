@@ -0,0 +1,498 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dexerlab/utils-go/apollosdk"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/dexerlab/utils-go/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// PoolOptions configures the failover and health checks of a RpcPool.
+type PoolOptions struct {
+	// CheckInterval is the minimum time between two health checks.
+	// A check probes the latest block number of every endpoint.
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+	// MaxBlockLag is how many blocks an endpoint may lag behind the highest one before it is stale.
+	MaxBlockLag int64
+	// MaxAttempts is how many endpoints a call is tried on before its error is returned.
+	MaxAttempts int
+}
+
+// DefaultPoolOptions are the options of the pools handed out by GetRpc.
+var DefaultPoolOptions = PoolOptions{
+	CheckInterval: 30 * time.Second,
+	CheckTimeout:  5 * time.Second,
+	MaxBlockLag:   20,
+	MaxAttempts:   3,
+}
+
+// ewmaWeight is the weight of the latest observation in the latency and error rate averages.
+const ewmaWeight = 0.2
+
+type endpoint struct {
+	node *loader.NodeInfo
+	rpc  Rpc
+	// client is the client dialed by the pool, nil when it is the client of the chain
+	client interface{}
+
+	mu        sync.Mutex
+	latency   time.Duration
+	errRate   float64
+	height    int64
+	stale     bool
+	usability int32
+}
+
+func newEndpoint(node *loader.NodeInfo, r Rpc) *endpoint {
+	usability := min(max(node.Usability, 0), 100)
+	return &endpoint{
+		node: node,
+		rpc:  r,
+		// start from the usability saved by the previous run
+		errRate:   1 - float64(usability)/100,
+		usability: usability,
+	}
+}
+
+// observe records the outcome of a call.
+func (e *endpoint) observe(latency time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency += time.Duration(ewmaWeight * float64(latency-e.latency))
+	}
+	failed := 0.0
+	if err != nil {
+		failed = 1
+	}
+	e.errRate += ewmaWeight * (failed - e.errRate)
+	e.usability = e.score()
+}
+
+// setHeight records the block height probed by a health check.
+func (e *endpoint) setHeight(height int64, best int64, maxLag int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.height = height
+	e.stale = best-height > maxLag
+	e.usability = e.score()
+}
+
+// score is the usability in [0, 100]: the success rate minus up to 40 points for latency
+// (one per 20ms), at most 10 for a stale endpoint. e.mu must be held.
+func (e *endpoint) score() int32 {
+	score := 100*(1-e.errRate) - min(float64(e.latency/(20*time.Millisecond)), 40)
+	if e.stale {
+		score = min(score, 10)
+	}
+	return int32(max(score, 0))
+}
+
+func (e *endpoint) Usability() int32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.usability
+}
+
+// RpcPool is a Rpc spreading over RpcEndPoint and the nodes of a chain.
+// Calls go to the endpoint with the best usability and fail over to the next ones on the errors
+// of the endpoint, such as a timeout or an http 5xx, see isEndpointError.
+// The usability of every endpoint is computed from its latency, error rate and block height,
+// and written back to t_node_info by the health checks.
+type RpcPool struct {
+	chainInfo *loader.ChainInfo
+	opt       PoolOptions
+	endpoints []*endpoint
+
+	checkMu   sync.Mutex
+	lastCheck atomic.Int64
+	checking  atomic.Bool
+}
+
+var _ Rpc = (*RpcPool)(nil)
+
+// NewRpcPool dials RpcEndPoint and every node of chainInfo.
+func NewRpcPool(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK, opt PoolOptions) (*RpcPool, error) {
+	return renewRpcPool(chainInfo, apolloSDK, opt, nil)
+}
+
+// renewRpcPool is NewRpcPool reusing the clients of prev for the urls it already dialed.
+func renewRpcPool(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK, opt PoolOptions, prev *RpcPool) (*RpcPool, error) {
+	dialed := make(map[string]interface{})
+	if prev != nil {
+		for _, e := range prev.endpoints {
+			if e.client != nil {
+				dialed[e.node.RPCURL] = e.client
+			}
+		}
+	}
+	nodes := make([]*loader.NodeInfo, 0, len(chainInfo.Nodes)+1)
+	if chainInfo.RpcEndPoint != "" {
+		nodes = append(nodes, &loader.NodeInfo{
+			ChainID:   chainInfo.GetInt64ChainId(),
+			RPCURL:    chainInfo.RpcEndPoint,
+			Usability: 100,
+		})
+	}
+	for _, node := range chainInfo.Nodes {
+		if node.RPCURL != "" && node.RPCURL != chainInfo.RpcEndPoint {
+			nodes = append(nodes, node)
+		}
+	}
+
+	endpoints := make([]*endpoint, 0, len(nodes))
+	for _, node := range nodes {
+		ci := *chainInfo
+		ci.RpcEndPoint = node.RPCURL
+		ci.Nodes = nil
+		var client interface{}
+		if node.RPCURL != chainInfo.RpcEndPoint || ci.Client == nil {
+			if client = dialed[node.RPCURL]; client == nil {
+				var err error
+				if client, err = loader.DialClient(ci.Backend, node.RPCURL); err != nil {
+					log.Errorf("%v dial node %v error %v", chainInfo.Name, node.RPCURL, err)
+					continue
+				}
+			}
+			ci.Client = client
+		}
+		r, err := newRpc(&ci, apolloSDK)
+		if err != nil {
+			return nil, err
+		}
+		e := newEndpoint(node, r)
+		e.client = client
+		endpoints = append(endpoints, e)
+	}
+	return newRpcPool(chainInfo, endpoints, opt)
+}
+
+func newRpcPool(chainInfo *loader.ChainInfo, endpoints []*endpoint, opt PoolOptions) (*RpcPool, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no rpc endpoint for %v", chainInfo.Name)
+	}
+	if opt.MaxAttempts <= 0 {
+		opt.MaxAttempts = 1
+	}
+	return &RpcPool{
+		chainInfo: chainInfo,
+		opt:       opt,
+		endpoints: endpoints,
+	}, nil
+}
+
+var (
+	pools   = make(map[int64]*RpcPool) // chain auto id -> pool
+	poolsMu sync.Mutex
+)
+
+// getRpcPool returns the pool of chainInfo, a new one is created when the chains are reloaded.
+// The new pool keeps the clients of the urls that did not change, the others are closed.
+// The creation is serialized so that concurrent callers share a single pool.
+func getRpcPool(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK) (Rpc, error) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	prev, ok := pools[chainInfo.Id]
+	if ok && prev.chainInfo == chainInfo {
+		return prev, nil
+	}
+	pool, err := renewRpcPool(chainInfo, apolloSDK, DefaultPoolOptions, prev)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		prev.closeClients(pool)
+	}
+	pools[chainInfo.Id] = pool
+	return pool, nil
+}
+
+// closeClients closes the clients dialed by p that next does not use.
+func (p *RpcPool) closeClients(next *RpcPool) {
+	used := make(map[interface{}]bool, len(next.endpoints))
+	for _, e := range next.endpoints {
+		if e.client != nil {
+			used[e.client] = true
+		}
+	}
+	for _, e := range p.endpoints {
+		if e.client == nil || used[e.client] {
+			continue
+		}
+		switch c := e.client.(type) {
+		case interface{ Close() }:
+			c.Close()
+		case interface{ Close() error }:
+			if err := c.Close(); err != nil {
+				log.Errorf("%v close node %v error %v", p.chainInfo.Name, e.node.RPCURL, err)
+			}
+		}
+	}
+}
+
+// isPoolable reports whether the endpoints of backend are interchangeable rpc urls.
+func isPoolable(backend loader.Backend) bool {
+	return backend != loader.BitcoinBackend && backend != loader.TonBackend
+}
+
+// ranked returns the endpoints by decreasing usability.
+func (p *RpcPool) ranked() []*endpoint {
+	ranked := make([]*endpoint, len(p.endpoints))
+	copy(ranked, p.endpoints)
+	usability := make(map[*endpoint]int32, len(ranked))
+	for _, e := range ranked {
+		usability[e] = e.Usability()
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return usability[ranked[i]] > usability[ranked[j]]
+	})
+	return ranked
+}
+
+// Best returns the Rpc of the endpoint with the best usability,
+// e.g. to call the methods of a backend that are not in Rpc.
+func (p *RpcPool) Best() Rpc {
+	return p.ranked()[0].rpc
+}
+
+// Do calls fn with the endpoints by decreasing usability until it succeeds, fails with an error
+// that is not about the endpoint, or MaxAttempts is reached.
+func (p *RpcPool) Do(ctx context.Context, fn func(r Rpc) error) error {
+	_, err := poolCall(ctx, p, func(r Rpc) (struct{}, error) {
+		return struct{}{}, fn(r)
+	})
+	return err
+}
+
+func poolCall[T any](ctx context.Context, p *RpcPool, fn func(r Rpc) (T, error)) (T, error) {
+	p.maybeCheck()
+	var ret T
+	var err error
+	for i, e := range p.ranked() {
+		if i >= p.opt.MaxAttempts {
+			break
+		}
+		start := time.Now()
+		ret, err = fn(e.rpc)
+		if ctx.Err() != nil {
+			// the caller gave up, this says nothing about the endpoint
+			return ret, err
+		}
+		if err != nil && !isEndpointError(err) {
+			// a revert, a missing tx or a bad argument fails the same way on every endpoint
+			e.observe(time.Since(start), nil)
+			return ret, err
+		}
+		e.observe(time.Since(start), err)
+		if err == nil {
+			return ret, nil
+		}
+		log.Warnf("%v rpc %v error %v", p.chainInfo.Name, e.node.RPCURL, err)
+	}
+	return ret, err
+}
+
+// endpointErrorHints are found in the messages of the transport errors of the clients
+// that don't return typed errors.
+var endpointErrorHints = []string{
+	"timeout", "deadline exceeded", "connection refused", "connection reset", "broken pipe", "eof",
+	"no such host", "too many requests", "rate limit", "bad gateway", "service unavailable", "gateway timeout",
+}
+
+// isEndpointError reports whether err is about the endpoint rather than the request: a transport error,
+// a timeout, an http 5xx or 429, or a json-rpc rate limit or internal error. Only these errors lower
+// the usability of the endpoint and fail over to the next one.
+func isEndpointError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return isEndpointStatus(httpErr.StatusCode)
+	}
+	var solanaHTTPErr *jsonrpc.HTTPError
+	if errors.As(err, &solanaHTTPErr) {
+		return isEndpointStatus(solanaHTTPErr.Code)
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return isEndpointCode(rpcErr.ErrorCode())
+	}
+	var solanaRPCErr *jsonrpc.RPCError
+	if errors.As(err, &solanaRPCErr) {
+		return isEndpointCode(solanaRPCErr.Code)
+	}
+	msg := strings.ToLower(err.Error())
+	for _, hint := range endpointErrorHints {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEndpointStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+// isEndpointCode reports whether a json-rpc error code is about the node:
+// -32005 is a rate limit on EVM nodes and an unhealthy node on Solana, -32603 an internal error.
+func isEndpointCode(code int) bool {
+	return code == -32005 || code == -32603
+}
+
+// maybeCheck starts a health check in the background if the last one is older than CheckInterval.
+func (p *RpcPool) maybeCheck() {
+	if time.Now().UnixNano()-p.lastCheck.Load() < int64(p.opt.CheckInterval) {
+		return
+	}
+	if !p.checking.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer p.checking.Store(false)
+		p.Check(context.Background())
+	}()
+}
+
+// Check probes the block height of every endpoint, marks the ones lagging more than MaxBlockLag
+// as stale and writes the usability of the nodes back.
+func (p *RpcPool) Check(ctx context.Context) {
+	p.checkMu.Lock()
+	defer p.checkMu.Unlock()
+	p.lastCheck.Store(time.Now().UnixNano())
+
+	heights := make([]int64, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range p.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx := ctx
+			if p.opt.CheckTimeout > 0 {
+				var cancel context.CancelFunc
+				cctx, cancel = context.WithTimeout(ctx, p.opt.CheckTimeout)
+				defer cancel()
+			}
+			start := time.Now()
+			height, err := e.rpc.GetLatestBlockNumber(cctx)
+			e.observe(time.Since(start), err)
+			if err == nil {
+				heights[i] = height
+			}
+		}()
+	}
+	wg.Wait()
+
+	var best int64
+	for _, h := range heights {
+		best = max(best, h)
+	}
+	for i, e := range p.endpoints {
+		if heights[i] > 0 {
+			e.setHeight(heights[i], best, p.opt.MaxBlockLag)
+		}
+		if usability := e.Usability(); usability != e.node.Usability {
+			if err := p.chainInfo.UpdateNodeUsability(e.node, usability); err != nil {
+				log.Errorf("%v update usability of %v error %v", p.chainInfo.Name, e.node.RPCURL, err)
+			}
+		}
+	}
+}
+
+func (p *RpcPool) Client() interface{} {
+	return p.Best().Client()
+}
+
+func (p *RpcPool) Backend() int32 {
+	return p.endpoints[0].rpc.Backend()
+}
+
+func (p *RpcPool) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	return poolCall(ctx, p, func(r Rpc) (int64, error) {
+		return r.GetLatestBlockNumber(ctx)
+	})
+}
+
+func (p *RpcPool) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
+	type result struct {
+		success bool
+		block   int64
+	}
+	ret, err := poolCall(ctx, p, func(r Rpc) (result, error) {
+		success, block, err := r.IsTxSuccess(ctx, hash)
+		return result{success, block}, err
+	})
+	return ret.success, ret.block, err
+}
+
+func (p *RpcPool) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	return poolCall(ctx, p, func(r Rpc) (*TxStatus, error) {
+		return r.GetTxStatus(ctx, hash)
+	})
+}
+
+func (p *RpcPool) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
+	return poolCall(ctx, p, func(r Rpc) (*big.Int, error) {
+		return r.GetAllowance(ctx, ownerAddr, tokenAddr, spenderAddr)
+	})
+}
+
+func (p *RpcPool) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
+	return poolCall(ctx, p, func(r Rpc) (*big.Int, error) {
+		return r.GetBalance(ctx, ownerAddr, tokenAddr)
+	})
+}
+
+func (p *RpcPool) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error) {
+	return poolCall(ctx, p, func(r Rpc) (*big.Int, error) {
+		return r.GetBalanceAtBlockNumber(ctx, ownerAddr, tokenAddr, blockNumber)
+	})
+}
+
+func (p *RpcPool) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return poolCall(ctx, p, func(r Rpc) ([]*big.Int, error) {
+		return r.GetBalances(ctx, ownerAddr, tokenAddrs)
+	})
+}
+
+func (p *RpcPool) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return poolCall(ctx, p, func(r Rpc) ([]*big.Int, error) {
+		return r.GetBalancesForOwners(ctx, ownerAddrs, tokenAddr)
+	})
+}
+
+func (p *RpcPool) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool) (*loader.TokenInfo, error) {
+	return poolCall(ctx, p, func(r Rpc) (*loader.TokenInfo, error) {
+		return r.GetTokenInfo(ctx, tokenAddr, cache)
+	})
+}
+
+func (p *RpcPool) IsAddressValid(addr string) bool {
+	return p.endpoints[0].rpc.IsAddressValid(addr)
+}
+
+func (p *RpcPool) GetChecksumAddress(addr string) string {
+	return p.endpoints[0].rpc.GetChecksumAddress(addr)
+}
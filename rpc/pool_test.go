@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRpc struct {
+	EvmRpc
+	height int64
+	err    error
+}
+
+func (f *fakeRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	return f.height, f.err
+}
+
+func (f *fakeRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return big.NewInt(f.height), nil
+}
+
+func TestRpcPool(t *testing.T) {
+	down := &fakeRpc{height: 100, err: errors.New("connection refused")}
+	lagging := &fakeRpc{height: 50}
+	healthy := &fakeRpc{height: 100}
+	nodes := []*loader.NodeInfo{
+		{RPCURL: "down", Usability: 100},
+		{RPCURL: "lagging", Usability: 100},
+		{RPCURL: "healthy", Usability: 90},
+	}
+	pool, err := newRpcPool(&loader.ChainInfo{Name: "test"}, []*endpoint{
+		newEndpoint(nodes[0], down),
+		newEndpoint(nodes[1], lagging),
+		newEndpoint(nodes[2], healthy),
+	}, PoolOptions{CheckInterval: time.Hour, MaxBlockLag: 10, MaxAttempts: 3})
+	assert.NoError(t, err)
+	pool.lastCheck.Store(time.Now().UnixNano())
+
+	// the first endpoint fails, the call fails over to the second one
+	balance, err := pool.GetBalance(context.Background(), "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), balance.Int64())
+	assert.Less(t, pool.endpoints[0].Usability(), int32(100))
+
+	pool.Check(context.Background())
+	assert.True(t, pool.endpoints[1].stale)
+	assert.False(t, pool.endpoints[2].stale)
+	assert.Equal(t, healthy, pool.Best())
+	assert.LessOrEqual(t, nodes[1].Usability, int32(10))
+	assert.Less(t, nodes[0].Usability, nodes[2].Usability)
+
+	balance, err = pool.GetBalance(context.Background(), "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), balance.Int64())
+
+	// an error of the request is returned at once, without penalizing the endpoint
+	pool.opt.MaxAttempts = 3
+	usability := pool.endpoints[2].Usability()
+	healthy.err = errors.New("execution reverted")
+	_, err = pool.GetBalance(context.Background(), "", "")
+	assert.EqualError(t, err, "execution reverted")
+	assert.GreaterOrEqual(t, pool.endpoints[2].Usability(), usability)
+
+	// no failover beyond MaxAttempts
+	healthy.err = errors.New("timeout")
+	pool.opt.MaxAttempts = 1
+	_, err = pool.GetBalance(context.Background(), "", "")
+	assert.Error(t, err)
+}
+
+func TestGetRpcPoolReload(t *testing.T) {
+	t.Cleanup(func() {
+		poolsMu.Lock()
+		delete(pools, -1)
+		poolsMu.Unlock()
+	})
+	// websocket clients hold a connection, unlike the http ones
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("eth", &ethService{}))
+	ws := httptest.NewServer(server.WebsocketHandler(nil))
+	t.Cleanup(ws.Close)
+	t.Cleanup(server.Stop)
+	url := "ws" + strings.TrimPrefix(ws.URL, "http")
+	kept, dropped := url+"/kept", url+"/dropped"
+	chainInfo := &loader.ChainInfo{Id: -1, Name: "test", Backend: loader.EthereumBackend,
+		Nodes: []*loader.NodeInfo{{RPCURL: kept}, {RPCURL: dropped}}}
+	r, err := getRpcPool(chainInfo, nil)
+	assert.NoError(t, err)
+	first := r.(*RpcPool)
+	same, err := getRpcPool(chainInfo, nil)
+	assert.NoError(t, err)
+	assert.Same(t, first, same)
+
+	// the reloaded chain keeps one of the nodes
+	reloaded := &loader.ChainInfo{Id: -1, Name: "test", Backend: loader.EthereumBackend,
+		Nodes: []*loader.NodeInfo{{RPCURL: kept}}}
+	r, err = getRpcPool(reloaded, nil)
+	assert.NoError(t, err)
+	second := r.(*RpcPool)
+	assert.NotSame(t, first, second)
+	assert.Len(t, second.endpoints, 1)
+	assert.Same(t, first.endpoints[0].client, second.endpoints[0].client)
+
+	// the client of the dropped node is closed, the kept one is still usable
+	ctx := context.Background()
+	_, err = first.endpoints[1].client.(*ethclient.Client).BalanceAt(ctx, common.Address{7}, nil)
+	assert.ErrorIs(t, err, rpc.ErrClientQuit)
+	balance, err := second.endpoints[0].client.(*ethclient.Client).BalanceAt(ctx, common.Address{19: 7}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), balance.Int64())
+}
+
+func TestIsEndpointError(t *testing.T) {
+	assert.True(t, isEndpointError(context.DeadlineExceeded))
+	assert.True(t, isEndpointError(fmt.Errorf("get balance: %w", rpc.HTTPError{StatusCode: 503})))
+	assert.True(t, isEndpointError(rpc.HTTPError{StatusCode: 429}))
+	assert.True(t, isEndpointError(jsonrpc.NewHTTPError(502, errors.New("bad"))))
+	assert.True(t, isEndpointError(&jsonrpc.RPCError{Code: -32005}))
+	assert.True(t, isEndpointError(errors.New("dial tcp: connection refused")))
+
+	assert.False(t, isEndpointError(rpc.HTTPError{StatusCode: 400}))
+	assert.False(t, isEndpointError(&jsonrpc.RPCError{Code: -32602}))
+	assert.False(t, isEndpointError(errors.New("execution reverted")))
+	assert.False(t, isEndpointError(errors.New("not found")))
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/dexerlab/utils-go/apollosdk"
 	"github.com/dexerlab/utils-go/loader"
+	"golang.org/x/sync/errgroup"
 )
 
 type Rpc interface {
@@ -14,15 +15,30 @@ type Rpc interface {
 	Backend() int32
 	GetLatestBlockNumber(ctx context.Context) (int64, error)
 	IsTxSuccess(ctx context.Context, hash string) (bool, int64, error)
+	// GetTxStatus returns the state of a transaction with the finality rule of the chain.
+	GetTxStatus(ctx context.Context, hash string) (*TxStatus, error)
 	GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error)
 	GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error)
 	GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string, tokenAddr string, blockNumber int64) (*big.Int, error)
+	// GetBalances returns the balances of ownerAddr in every token of tokenAddrs, in the same order.
+	GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error)
+	// GetBalancesForOwners returns the balances of every owner of ownerAddrs in tokenAddr, in the same order.
+	GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error)
 	GetTokenInfo(ctx context.Context, tokenAddr string, cache bool) (*loader.TokenInfo, error)
 	IsAddressValid(addr string) bool
 	GetChecksumAddress(addr string) string
 }
 
+// GetRpc returns the Rpc of a chain. Chains with several nodes in t_node_info get a shared
+// RpcPool failing over between RpcEndPoint and the nodes, see RpcPool.
 func GetRpc(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK) (Rpc, error) {
+	if len(chainInfo.Nodes) > 0 && isPoolable(chainInfo.Backend) {
+		return getRpcPool(chainInfo, apolloSDK)
+	}
+	return newRpc(chainInfo, apolloSDK)
+}
+
+func newRpc(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK) (Rpc, error) {
 	if chainInfo.Backend == 1 {
 		return NewEvmRpc(chainInfo), nil
 	} else if chainInfo.Backend == 2 {
@@ -44,3 +60,27 @@ func GetRpc(chainInfo *loader.ChainInfo, apolloSDK *apollosdk.ApolloSDK) (Rpc, e
 	}
 	return nil, fmt.Errorf("unsupport backend %v", chainInfo.Backend)
 }
+
+// balanceParallelism bounds the concurrent calls of the backends getting balances one by one.
+const balanceParallelism = 8
+
+// getBalancesParallel gets n balances with get, at most balanceParallelism at a time.
+func getBalancesParallel(ctx context.Context, n int, get func(ctx context.Context, i int) (*big.Int, error)) ([]*big.Int, error) {
+	balances := make([]*big.Int, n)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(balanceParallelism)
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			balance, err := get(ctx, i)
+			if err != nil {
+				return err
+			}
+			balances[i] = balance
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
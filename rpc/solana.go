@@ -3,6 +3,8 @@ package rpc
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -268,6 +270,99 @@ func (w *SolanaRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr
 	}
 }
 
+func (w *SolanaRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	ownerAddrs := make([]string, len(tokenAddrs))
+	for i := range ownerAddrs {
+		ownerAddrs[i] = ownerAddr
+	}
+	return w.getBalances(ctx, ownerAddrs, tokenAddrs)
+}
+
+func (w *SolanaRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	tokenAddrs := make([]string, len(ownerAddrs))
+	for i := range tokenAddrs {
+		tokenAddrs[i] = tokenAddr
+	}
+	return w.getBalances(ctx, ownerAddrs, tokenAddrs)
+}
+
+// maxMultipleAccounts is the limit of accounts of a getMultipleAccounts request.
+const maxMultipleAccounts = 100
+
+// getBalances gets the balance of ownerAddrs[i] in tokenAddrs[i] with getMultipleAccounts:
+// the owner account is read for SOL, its token and token-2022 atas for the other tokens.
+func (w *SolanaRpc) getBalances(ctx context.Context, ownerAddrs []string, tokenAddrs []string) ([]*big.Int, error) {
+	keys := make([]solana.PublicKey, 0, 2*len(ownerAddrs))
+	offsets := make([]int, len(ownerAddrs))
+	natives := make([]bool, len(ownerAddrs))
+	for i := range ownerAddrs {
+		ownerpk, err := solana.PublicKeyFromBase58(strings.TrimSpace(ownerAddrs[i]))
+		if err != nil {
+			return nil, err
+		}
+		offsets[i] = len(keys)
+		tokenAddr := strings.TrimSpace(tokenAddrs[i])
+		if util.IsHexStringZero(tokenAddr) || tokenAddr == "11111111111111111111111111111111" {
+			natives[i] = true
+			keys = append(keys, ownerpk)
+			continue
+		}
+		mintpk, err := solana.PublicKeyFromBase58(tokenAddr)
+		if err != nil {
+			return nil, err
+		}
+		ownerAta, err := sol.GetAtaFromPk(ownerpk, mintpk)
+		if err != nil {
+			return nil, err
+		}
+		ownerAta2022, err := sol.Get2022AtaFromPk(ownerpk, mintpk)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ownerAta, ownerAta2022)
+	}
+
+	accounts := make([]*rpc.Account, 0, len(keys))
+	for start := 0; start < len(keys); start += maxMultipleAccounts {
+		rsp, err := w.GetClient().GetMultipleAccountsWithOpts(
+			ctx,
+			keys[start:min(start+maxMultipleAccounts, len(keys))],
+			&rpc.GetMultipleAccountsOpts{
+				Commitment: rpc.CommitmentConfirmed,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, rsp.Value...)
+	}
+	if len(accounts) != len(keys) {
+		return nil, fmt.Errorf("get multiple accounts returned %d accounts for %d keys", len(accounts), len(keys))
+	}
+
+	balances := make([]*big.Int, len(ownerAddrs))
+	for i := range ownerAddrs {
+		balances[i] = big.NewInt(0)
+		if natives[i] {
+			if acc := accounts[offsets[i]]; acc != nil {
+				balances[i].SetUint64(acc.Lamports)
+			}
+			continue
+		}
+		for _, acc := range accounts[offsets[i] : offsets[i]+2] {
+			if acc == nil || acc.Data == nil {
+				continue
+			}
+			var tokenAccount token.Account
+			if err := tokenAccount.UnmarshalWithDecoder(bin.NewBorshDecoder(acc.Data.GetBinary())); err == nil {
+				balances[i].SetUint64(tokenAccount.Amount)
+				break
+			}
+		}
+	}
+	return balances, nil
+}
+
 func (w *SolanaRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
 	sqlAccount, err := w.GetSplAccount(ctx, ownerAddr, tokenAddr)
 	if err != nil {
@@ -300,6 +395,69 @@ func (w *SolanaRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64,
 	return receipt.Meta.Err == nil, int64(receipt.Slot), nil
 }
 
+// GetTxStatus maps the commitment of the signature: processed and confirmed transactions are
+// included, finalized ones final. Fee and error logs come from the confirmed transaction.
+func (w *SolanaRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	sig, err := solana.SignatureFromBase58(hash)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := w.GetClient().GetSignatureStatuses(ctx, true, sig)
+	if err != nil && !errors.Is(err, rpc.ErrNotFound) {
+		return nil, err
+	}
+	status := &TxStatus{Hash: hash}
+	if rsp == nil || len(rsp.Value) == 0 || rsp.Value[0] == nil {
+		status.State = TxStateDropped
+		return status, nil
+	}
+	sigStatus := rsp.Value[0]
+
+	slot, err := w.GetClient().GetSlot(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	status.BlockNumber = int64(sigStatus.Slot)
+	status.Confirmations = confirmations(int64(slot), status.BlockNumber)
+
+	var logs []string
+	if sigStatus.ConfirmationStatus != rpc.ConfirmationStatusProcessed {
+		var maxVersion uint64 = 0
+		tx, err := w.GetClient().GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if tx.Meta != nil {
+			status.Fee = new(big.Int).SetUint64(tx.Meta.Fee)
+			if tx.Meta.ComputeUnitsConsumed != nil {
+				status.GasUsed = *tx.Meta.ComputeUnitsConsumed
+			}
+			logs = tx.Meta.LogMessages
+		}
+	}
+
+	var revertReason string
+	if sigStatus.Err != nil {
+		revertReason = solanaTxError(sigStatus.Err, logs)
+	}
+	status.finalize(sigStatus.Err == nil, sigStatus.ConfirmationStatus == rpc.ConfirmationStatusFinalized, revertReason)
+	return status, nil
+}
+
+// solanaTxError formats the error of a transaction with the error logged by the failing program.
+func solanaTxError(txErr interface{}, logs []string) string {
+	reason, _ := json.Marshal(txErr)
+	for i := len(logs) - 1; i >= 0; i-- {
+		if strings.HasPrefix(logs[i], "Program log: Error") || strings.HasPrefix(logs[i], "Program log: AnchorError") {
+			return fmt.Sprintf("%s: %s", reason, strings.TrimPrefix(logs[i], "Program log: "))
+		}
+	}
+	return string(reason)
+}
+
 func (w *SolanaRpc) Client() interface{} {
 	return w.chainInfo.Client
 }
@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -50,6 +51,18 @@ func (w *StarknetRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr str
 	return w.GetBalance(ctx, ownerAddr, tokenAddr)
 }
 
+func (w *StarknetRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(tokenAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddr, tokenAddrs[i])
+	})
+}
+
+func (w *StarknetRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
 func (w *StarknetRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
 	ownerAddr = strings.TrimSpace(ownerAddr)
 	tokenAddr = strings.TrimSpace(tokenAddr)
@@ -111,6 +124,54 @@ func (w *StarknetRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64
 	}
 }
 
+// GetTxStatus follows the finality status: received transactions are pending, rejected ones
+// dropped, ACCEPTED_ON_L2 ones included and ACCEPTED_ON_L1 ones final.
+func (w *StarknetRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	bhash, err := hexutil.Decode(hash)
+	if err != nil {
+		return nil, err
+	}
+	txHash := new(felt.Felt).SetBytes(bhash)
+	status := &TxStatus{Hash: hash}
+	txStatus, err := w.GetClient().GetTransactionStatus(ctx, txHash)
+	if err != nil {
+		var rpcErr *rpc.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == rpc.ErrHashNotFound.Code {
+			status.State = TxStateDropped
+			return status, nil
+		}
+		return nil, err
+	}
+	switch txStatus.FinalityStatus {
+	case rpc.TxnStatus_Received:
+		status.State = TxStatePending
+		return status, nil
+	case rpc.TxnStatus_Rejected:
+		status.State = TxStateDropped
+		return status, nil
+	case rpc.TxnStatus_Accepted_On_L2, rpc.TxnStatus_Accepted_On_L1:
+	default:
+		return nil, fmt.Errorf("unknown tx status: %v", txStatus.FinalityStatus)
+	}
+
+	receipt, err := w.GetClient().TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	head, err := w.GetClient().BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.BlockNumber = int64(receipt.BlockNumber)
+	status.Confirmations = confirmations(int64(head), status.BlockNumber)
+	if receipt.ActualFee.Amount != nil {
+		status.Fee = receipt.ActualFee.Amount.BigInt(new(big.Int))
+	}
+	status.finalize(receipt.ExecutionStatus == rpc.TxnExecutionStatusSUCCEEDED,
+		receipt.FinalityStatus == rpc.TxnFinalityStatusAcceptedOnL1, receipt.RevertReason)
+	return status, nil
+}
+
 func (w *StarknetRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	blockNumber, err := w.GetClient().BlockNumber(ctx)
 	if err != nil {
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/NethermindEth/juno/core/felt"
+	"github.com/NethermindEth/starknet.go/rpc"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveStarknet answers starknet_getTransactionStatus and starknet_getTransactionReceipt from
+// testdata/starknet_<name>.json, names being keyed by transaction hash.
+func serveStarknet(t *testing.T, fixtures map[string]string, head uint64) *httptest.Server {
+	normalize := func(hash string) string {
+		f, _ := new(felt.Felt).SetString(hash)
+		return f.String()
+	}
+	txs := make(map[string]string, len(fixtures))
+	for hash, name := range fixtures {
+		txs[normalize(hash)] = name
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []string        `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		rsp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		var fixture map[string]json.RawMessage
+		if len(req.Params) > 0 {
+			if name, ok := txs[normalize(req.Params[0])]; ok {
+				data, err := os.ReadFile("testdata/starknet_" + name + ".json")
+				assert.NoError(t, err)
+				assert.NoError(t, json.Unmarshal(data, &fixture))
+			}
+		}
+		switch {
+		case req.Method == "starknet_blockNumber":
+			rsp["result"] = head
+		case fixture == nil:
+			rsp["error"] = rpc.ErrHashNotFound
+		case req.Method == "starknet_getTransactionStatus":
+			rsp["result"] = fixture["status"]
+		case req.Method == "starknet_getTransactionReceipt":
+			rsp["result"] = fixture["receipt"]
+		}
+		json.NewEncoder(w).Encode(rsp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStarknetGetTxStatus(t *testing.T) {
+	accepted := "0x03f0c3e2a1d7b6bc6f8bb2ab8d2fd2f3b3b9b0bdc7e2b8a38e6b0b4a4d2b1c0a"
+	reverted := "0x06b1f4c2a9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3fb"
+	received := "0x00000000000000000000000000000000000000000000000000000000000000c3"
+	srv := serveStarknet(t, map[string]string{
+		accepted: "tx_accepted_on_l1",
+		reverted: "tx_reverted",
+		"0xc3":   "tx_received",
+	}, 652010)
+	provider, err := rpc.NewProvider(srv.URL)
+	assert.NoError(t, err)
+	w := NewStarknetRpc(&loader.ChainInfo{Name: "Starknet", Client: provider})
+	ctx := context.Background()
+
+	status, err := w.GetTxStatus(ctx, accepted)
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, status.State)
+	assert.Equal(t, int64(651230), status.BlockNumber)
+	assert.Equal(t, int64(781), status.Confirmations)
+	assert.Equal(t, "2000000000000", status.Fee.String())
+
+	status, err = w.GetTxStatus(ctx, reverted)
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+	assert.False(t, status.Final)
+	assert.Contains(t, status.RevertReason, "transfer amount exceeds balance")
+
+	status, err = w.GetTxStatus(ctx, received)
+	assert.NoError(t, err)
+	assert.Equal(t, TxStatePending, status.State)
+
+	status, err = w.GetTxStatus(ctx, "0x01")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateDropped, status.State)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/block-vision/sui-go-sdk/models"
@@ -121,12 +122,128 @@ func (w *SuiRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr str
 
 }
 
+// GetBalances reads all the balances of ownerAddr with suix_getAllBalances.
+func (w *SuiRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	rsp, err := w.client.SuiXGetAllBalance(ctx, models.SuiXGetAllBalanceRequest{
+		Owner: strings.TrimSpace(ownerAddr),
+	})
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]string, len(rsp))
+	for _, b := range rsp {
+		all[normalizeSuiCoinType(b.CoinType)] = b.TotalBalance
+	}
+
+	balances := make([]*big.Int, len(tokenAddrs))
+	for i, tokenAddr := range tokenAddrs {
+		tokenAddr = strings.TrimSpace(tokenAddr)
+		if util.IsHexStringZero(tokenAddr) {
+			tokenAddr = "0x2::sui::SUI"
+		}
+		total, ok := all[normalizeSuiCoinType(tokenAddr)]
+		if !ok {
+			balances[i] = big.NewInt(0)
+			continue
+		}
+		num, ok := big.NewInt(0).SetString(total, 0)
+		if !ok {
+			return nil, fmt.Errorf("sui balance invalid %s", total)
+		}
+		balances[i] = num
+	}
+	return balances, nil
+}
+
+func (w *SuiRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
+// normalizeSuiCoinType pads the package address of a coin type, 0x2::sui::SUI and
+// 0x0000000000000000000000000000000000000000000000000000000000000002::sui::SUI are the same coin.
+func normalizeSuiCoinType(coinType string) string {
+	pkg, rest, ok := strings.Cut(coinType, "::")
+	if !ok {
+		return coinType
+	}
+	pkg = strings.TrimPrefix(strings.ToLower(pkg), "0x")
+	if len(pkg) < 64 {
+		pkg = strings.Repeat("0", 64-len(pkg)) + pkg
+	}
+	return "0x" + pkg + "::" + rest
+}
+
 func (w *SuiRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
 	return big.NewInt(0), fmt.Errorf("not impl")
 }
 
+// IsTxSuccess reads the effects status of the transaction, the block number is its checkpoint.
 func (w *SuiRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
-	return false, 0, fmt.Errorf("not impl")
+	rsp, err := w.client.SuiGetTransactionBlock(ctx, models.SuiGetTransactionBlockRequest{
+		Digest: strings.TrimSpace(hash),
+		Options: models.SuiTransactionBlockOptions{
+			ShowEffects: true,
+		},
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	if rsp.Checkpoint == "" {
+		return false, 0, fmt.Errorf("sui tx %s not checkpointed yet", hash)
+	}
+	checkpoint, err := strconv.ParseInt(rsp.Checkpoint, 10, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("sui checkpoint invalid %s", rsp.Checkpoint)
+	}
+	return rsp.Effects.Status.Status == "success", checkpoint, nil
+}
+
+// GetTxStatus reads the effects of the transaction, checkpoints are final so a checkpointed
+// transaction is final and an executed one not checkpointed yet is included.
+func (w *SuiRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	status := &TxStatus{Hash: hash}
+	rsp, err := w.client.SuiGetTransactionBlock(ctx, models.SuiGetTransactionBlockRequest{
+		Digest: strings.TrimSpace(hash),
+		Options: models.SuiTransactionBlockOptions{
+			ShowEffects: true,
+		},
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "Could not find the referenced transaction") {
+			status.State = TxStateDropped
+			return status, nil
+		}
+		return nil, err
+	}
+
+	gas := rsp.Effects.GasUsed
+	fee := new(big.Int)
+	for _, cost := range []string{gas.ComputationCost, gas.StorageCost} {
+		if v, ok := new(big.Int).SetString(cost, 10); ok {
+			fee.Add(fee, v)
+		}
+	}
+	if v, ok := new(big.Int).SetString(gas.StorageRebate, 10); ok {
+		fee.Sub(fee, v)
+	}
+	status.Fee = fee
+
+	if rsp.Checkpoint != "" {
+		checkpoint, err := strconv.ParseInt(rsp.Checkpoint, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sui checkpoint invalid %s", rsp.Checkpoint)
+		}
+		head, err := w.GetLatestBlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		status.BlockNumber = checkpoint
+		status.Confirmations = confirmations(head, checkpoint)
+	}
+	status.finalize(rsp.Effects.Status.Status == "success", rsp.Checkpoint != "", rsp.Effects.Status.Error)
+	return status, nil
 }
 
 func (w *SuiRpc) GetClient() sui.ISuiAPI {
@@ -141,8 +258,13 @@ func (w *SuiRpc) Backend() int32 {
 	return 9
 }
 
+// GetLatestBlockNumber returns the sequence number of the latest executed checkpoint.
 func (w *SuiRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
-	return 0, fmt.Errorf("not impl")
+	checkpoint, err := w.client.SuiGetLatestCheckpointSequenceNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(checkpoint), nil
 }
 
 var legTokenStr string = `{
@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/block-vision/sui-go-sdk/sui"
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveFixtures answers with testdata/<name>.json, name being picked from the request.
+func serveFixtures(t *testing.T, name func(r *http.Request) string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile("testdata/" + name(r) + ".json")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSuiRpc(t *testing.T) {
+	digests := map[string]string{
+		"5PLjmzJxrYVvNv3ydDYmTZWYxhp4QpWHxM9X8Mqsv3Et": "sui_getTransactionBlock_success",
+		"3vQ3p6FJ4NNtzLcbZ4vWy6cP1uKXrvDLSM9dWmDTxWMw": "sui_getTransactionBlock_failure",
+	}
+	srv := serveFixtures(t, func(r *http.Request) string {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == "sui_getTransactionBlock" {
+			digest, _ := req.Params[0].(string)
+			if name, ok := digests[digest]; ok {
+				return name
+			}
+			return "sui_getTransactionBlock_not_found"
+		}
+		return req.Method
+	})
+	w := NewSuiRpc(&loader.ChainInfo{Name: "Sui", Client: sui.NewSuiClient(srv.URL)})
+	ctx := context.Background()
+
+	height, err := w.GetLatestBlockNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(60484021), height)
+
+	ok, checkpoint, err := w.IsTxSuccess(ctx, "5PLjmzJxrYVvNv3ydDYmTZWYxhp4QpWHxM9X8Mqsv3Et")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(60483212), checkpoint)
+
+	ok, checkpoint, err = w.IsTxSuccess(ctx, "3vQ3p6FJ4NNtzLcbZ4vWy6cP1uKXrvDLSM9dWmDTxWMw")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(60483501), checkpoint)
+
+	_, _, err = w.IsTxSuccess(ctx, "9mAPpeVcQxyX7hNeGXjDYXqRmXcsi2FhztxoLbGRWdAV")
+	assert.Error(t, err)
+
+	status, err := w.GetTxStatus(ctx, "5PLjmzJxrYVvNv3ydDYmTZWYxhp4QpWHxM9X8Mqsv3Et")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, status.State)
+	assert.Equal(t, int64(60483212), status.BlockNumber)
+	assert.Equal(t, int64(810), status.Confirmations)
+	assert.Equal(t, int64(779640), status.Fee.Int64())
+
+	status, err = w.GetTxStatus(ctx, "3vQ3p6FJ4NNtzLcbZ4vWy6cP1uKXrvDLSM9dWmDTxWMw")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+	assert.True(t, status.Final)
+	assert.Contains(t, status.RevertReason, "MoveAbort")
+
+	status, err = w.GetTxStatus(ctx, "9mAPpeVcQxyX7hNeGXjDYXqRmXcsi2FhztxoLbGRWdAV")
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateDropped, status.State)
+}
+
+func TestNormalizeSuiCoinType(t *testing.T) {
+	assert.Equal(t, normalizeSuiCoinType("0x0000000000000000000000000000000000000000000000000000000000000002::sui::SUI"),
+		normalizeSuiCoinType("0x2::sui::SUI"))
+}
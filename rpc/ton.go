@@ -1,29 +1,44 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/dexerlab/utils-go/httputils"
 	"github.com/dexerlab/utils-go/loader"
 	"github.com/dexerlab/utils-go/util"
+	"github.com/shopspring/decimal"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/liteclient"
+	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
 	"github.com/xssnick/tonutils-go/ton/jetton"
+	"github.com/xssnick/tonutils-go/ton/nft"
 )
 
 type TonRpc struct {
-	chainInfo *loader.ChainInfo
+	chainInfo    *loader.ChainInfo
+	tokenInfoMgr *loader.TokenInfoManager
+	httpClient   *httputils.Client
 }
 
 func NewTonRpc(chainInfo *loader.ChainInfo) *TonRpc {
 	return &TonRpc{
-		chainInfo: chainInfo,
+		chainInfo:    chainInfo,
+		tokenInfoMgr: loader.NewTokenInfoManager(nil, nil),
+		httpClient:   httputils.NewClient(10 * time.Second),
 	}
 }
 
-func (t *TonRpc) GetClient() (*ton.APIClient, error) {
+func (t *TonRpc) GetClient() (ton.APIClientWrapped, error) {
 	if t.chainInfo.Client == nil {
 		client := liteclient.NewConnectionPool()
 		err := client.AddConnectionsFromConfigUrl(context.Background(), t.chainInfo.RpcEndPoint)
@@ -32,7 +47,11 @@ func (t *TonRpc) GetClient() (*ton.APIClient, error) {
 		}
 		t.chainInfo.Client = ton.NewAPIClient(client).WithRetry()
 	}
-	return t.chainInfo.Client.(*ton.APIClient), nil
+	client, ok := t.chainInfo.Client.(ton.APIClientWrapped)
+	if !ok {
+		return nil, fmt.Errorf("ton client invalid %T", t.chainInfo.Client)
+	}
+	return client, nil
 }
 
 func (t *TonRpc) Client() interface{} {
@@ -55,12 +74,118 @@ func (t *TonRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	return int64(masterchainInfo.SeqNo), nil
 }
 
+// IsTxSuccess looks a transaction up by "address:lt:hash", a TON transaction is only
+// identified by its account, the hash can be hex or base64.
+// The block number returned is 0, like the BlockNumber of GetTxStatus.
 func (t *TonRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64, error) {
-	return false, 0, fmt.Errorf("not implement")
+	tx, err := t.getTransaction(ctx, hash)
+	if err != nil {
+		return false, 0, err
+	}
+	return isTonTxSuccess(tx), 0, nil
 }
 
+// GetTxStatus looks a transaction up like IsTxSuccess. Lite servers only return transactions
+// of committed blocks, so a transaction found is final. BlockNumber and Confirmations are left at 0:
+// the logical time of a transaction is not a masterchain seqno and TON only exposes finality.
+func (t *TonRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	status := &TxStatus{Hash: hash}
+	tx, err := t.getTransaction(ctx, hash)
+	if errors.Is(err, ton.ErrNoTransactionsWereFound) {
+		status.State = TxStateDropped
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Fee = tx.TotalFees.Coins.Nano()
+	var revertReason string
+	if desc, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary); ok {
+		if vm, ok := desc.ComputePhase.Phase.(tlb.ComputePhaseVM); ok {
+			if vm.Details.GasUsed != nil {
+				status.GasUsed = vm.Details.GasUsed.Uint64()
+			}
+			revertReason = fmt.Sprintf("compute phase exit code %d", vm.Details.ExitCode)
+		}
+		if desc.ActionPhase != nil && !desc.ActionPhase.Success {
+			revertReason = fmt.Sprintf("action phase result code %d", desc.ActionPhase.ResultCode)
+		}
+		if desc.Aborted && revertReason == "" {
+			revertReason = "aborted"
+		}
+	}
+	status.finalize(isTonTxSuccess(tx), true, revertReason)
+	return status, nil
+}
+
+func (t *TonRpc) getTransaction(ctx context.Context, hash string) (*tlb.Transaction, error) {
+	addr, lt, txHash, err := parseTonTxHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	client, err := t.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	txs, err := client.ListTransactions(ctx, addr, 1, lt, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(txs) == 0 || txs[0].LT != lt || !bytes.Equal(txs[0].Hash, txHash) {
+		return nil, fmt.Errorf("ton tx %s not found: %w", hash, ton.ErrNoTransactionsWereFound)
+	}
+	return txs[0], nil
+}
+
+// parseTonTxHash splits "address:lt:hash", the address may be in raw form "0:abcd...".
+func parseTonTxHash(hash string) (*address.Address, uint64, []byte, error) {
+	parts := strings.Split(strings.TrimSpace(hash), ":")
+	if len(parts) < 3 {
+		return nil, 0, nil, fmt.Errorf("ton tx hash %s should be address:lt:hash", hash)
+	}
+	n := len(parts)
+	addrStr := strings.Join(parts[:n-2], ":")
+	addr, err := address.ParseAddr(addrStr)
+	if err != nil {
+		addr, err = address.ParseRawAddr(addrStr)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("ton tx address invalid %s: %v", addrStr, err)
+		}
+	}
+	lt, err := strconv.ParseUint(parts[n-2], 10, 64)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("ton tx lt invalid %s", parts[n-2])
+	}
+	txHash, err := hex.DecodeString(parts[n-1])
+	if err != nil {
+		txHash, err = base64.StdEncoding.DecodeString(parts[n-1])
+		if err != nil {
+			txHash, err = base64.URLEncoding.DecodeString(parts[n-1])
+		}
+	}
+	if err != nil || len(txHash) != 32 {
+		return nil, 0, nil, fmt.Errorf("ton tx hash invalid %s", parts[n-1])
+	}
+	return addr, lt, txHash, nil
+}
+
+// isTonTxSuccess reports whether an ordinary transaction ran its compute and action phases
+// without error and was not aborted.
+func isTonTxSuccess(tx *tlb.Transaction) bool {
+	desc, ok := tx.Description.Description.(tlb.TransactionDescriptionOrdinary)
+	if !ok || desc.Aborted {
+		return false
+	}
+	vm, ok := desc.ComputePhase.Phase.(tlb.ComputePhaseVM)
+	if !ok || !vm.Success {
+		return false
+	}
+	return desc.ActionPhase == nil || desc.ActionPhase.Success
+}
+
+// GetAllowance always returns 0, jettons have no allowances.
 func (t *TonRpc) GetAllowance(ctx context.Context, ownerAddr string, tokenAddr string, spenderAddr string) (*big.Int, error) {
-	return nil, fmt.Errorf("not implement")
+	return big.NewInt(0), nil
 }
 
 func (t *TonRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
@@ -107,8 +232,148 @@ func (t *TonRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr string,
 	return t.GetBalance(ctx, ownerAddr, tokenAddr)
 }
 
+// GetBalances looks the jetton wallets up in parallel.
+func (t *TonRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(tokenAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return t.GetBalance(ctx, ownerAddr, tokenAddrs[i])
+	})
+}
+
+func (t *TonRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return t.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
+// GetTokenInfo reads the metadata of a jetton master, on-chain or off-chain (TEP-64).
 func (t *TonRpc) GetTokenInfo(ctx context.Context, tokenAddr string, cache bool) (*loader.TokenInfo, error) {
-	return nil, fmt.Errorf("not implement")
+	tokenAddr = strings.TrimSpace(tokenAddr)
+	if util.IsNativeAddress(tokenAddr) {
+		return &loader.TokenInfo{
+			TokenName:    t.chainInfo.GasTokenName,
+			ChainName:    t.chainInfo.Name,
+			TokenAddress: tokenAddr,
+			Decimals:     t.chainInfo.GasTokenDecimal,
+			FullName:     t.chainInfo.AliasName,
+			TotalSupply:  decimal.Zero,
+		}, nil
+	}
+	tokenInfo, ok := t.tokenInfoMgr.GetByChainNameTokenAddr(t.chainInfo.Name, tokenAddr)
+	if ok {
+		return tokenInfo, nil
+	}
+
+	minterAddr, err := address.ParseAddr(tokenAddr)
+	if err != nil {
+		return nil, err
+	}
+	client, err := t.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	data, err := jetton.NewJettonMasterClient(client, minterAddr).GetJettonData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := t.getJettonMetadata(ctx, data.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	decimals := int32(9)
+	if meta.Decimals != "" {
+		d, err := strconv.ParseInt(meta.Decimals, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("jetton decimals invalid %s", meta.Decimals)
+		}
+		decimals = int32(d)
+	}
+	ti := &loader.TokenInfo{
+		TokenName:    strings.TrimSpace(meta.Symbol),
+		ChainName:    t.chainInfo.Name,
+		TokenAddress: tokenAddr,
+		Decimals:     decimals,
+		FullName:     strings.TrimSpace(meta.Name),
+		Icon:         meta.Image,
+		TotalSupply:  decimal.Zero,
+	}
+	if data.TotalSupply != nil {
+		ti.TotalSupply = decimal.NewFromBigInt(data.TotalSupply, 0)
+	}
+	if cache {
+		t.tokenInfoMgr.AddTokenInfo(ti)
+	}
+	return ti, nil
+}
+
+type jettonMetadata struct {
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals string `json:"decimals"`
+	Image    string `json:"image"`
+}
+
+// getJettonMetadata resolves the content of a jetton, on-chain attributes take precedence
+// over the off-chain json of a semi-chain content.
+func (t *TonRpc) getJettonMetadata(ctx context.Context, content nft.ContentAny) (*jettonMetadata, error) {
+	meta := &jettonMetadata{}
+	var onchain *nft.ContentOnchain
+	switch c := content.(type) {
+	case *nft.ContentOnchain:
+		onchain = c
+	case *nft.ContentSemichain:
+		if err := t.fetchJettonMetadata(ctx, c.URI, meta); err != nil {
+			return nil, err
+		}
+		onchain = &c.ContentOnchain
+	case *nft.ContentOffchain:
+		if err := t.fetchJettonMetadata(ctx, c.URI, meta); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("jetton content invalid %T", content)
+	}
+	if onchain != nil {
+		for _, attr := range []struct {
+			name string
+			val  *string
+		}{
+			{"name", &meta.Name},
+			{"symbol", &meta.Symbol},
+			{"decimals", &meta.Decimals},
+			{"image", &meta.Image},
+		} {
+			if v := onchain.GetAttribute(attr.name); v != "" {
+				*attr.val = v
+			}
+		}
+	}
+	return meta, nil
+}
+
+func (t *TonRpc) fetchJettonMetadata(ctx context.Context, uri string, meta *jettonMetadata) error {
+	if strings.HasPrefix(uri, "ipfs://") {
+		uri = "https://ipfs.io/ipfs/" + strings.TrimPrefix(uri, "ipfs://")
+	}
+	var raw map[string]interface{}
+	if err := t.httpClient.DoGet(ctx, uri, nil, &raw); err != nil {
+		return fmt.Errorf("fetch jetton metadata %s: %v", uri, err)
+	}
+	// decimals is a string in TEP-64 but some jettons publish a number
+	str := func(key string) string {
+		switch v := raw[key].(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return ""
+	}
+	meta.Name = str("name")
+	meta.Symbol = str("symbol")
+	meta.Decimals = str("decimals")
+	meta.Image = str("image")
+	return nil
 }
 
 func (t *TonRpc) IsAddressValid(addr string) bool {
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/dexerlab/utils-go/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/nft"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// tonAPI is a lite client answering get_jetton_data and transaction lookups from memory.
+type tonAPI struct {
+	ton.APIClientWrapped
+	jettons map[string]nft.ContentAny
+	txs     []*tlb.Transaction
+}
+
+func (a *tonAPI) CurrentMasterchainInfo(ctx context.Context) (*ton.BlockIDExt, error) {
+	return &ton.BlockIDExt{SeqNo: 42}, nil
+}
+
+func (a *tonAPI) WaitForBlock(seqno uint32) ton.APIClientWrapped {
+	return a
+}
+
+func (a *tonAPI) RunGetMethod(ctx context.Context, block *ton.BlockIDExt, addr *address.Address, method string, params ...interface{}) (*ton.ExecutionResult, error) {
+	content, ok := a.jettons[addr.String()]
+	if !ok || method != "get_jetton_data" {
+		return nil, ton.ContractExecError{Code: 11}
+	}
+	contentCell, err := content.ContentCell()
+	if err != nil {
+		return nil, err
+	}
+	admin := cell.BeginCell().MustStoreAddr(nil).EndCell().BeginParse()
+	return ton.NewExecutionResult([]interface{}{
+		big.NewInt(1_000_000_000_000), big.NewInt(1), admin, contentCell, cell.BeginCell().EndCell(),
+	}), nil
+}
+
+func (a *tonAPI) ListTransactions(ctx context.Context, addr *address.Address, num uint32, lt uint64, txHash []byte) ([]*tlb.Transaction, error) {
+	for _, tx := range a.txs {
+		if bytes.Equal(tx.AccountAddr, addr.Data()) && tx.LT == lt && bytes.Equal(tx.Hash, txHash) {
+			return []*tlb.Transaction{tx}, nil
+		}
+	}
+	return nil, ton.ErrNoTransactionsWereFound
+}
+
+func tonTx(addr *address.Address, lt uint64, computeSuccess bool, action *tlb.ActionPhase) *tlb.Transaction {
+	tx := &tlb.Transaction{AccountAddr: addr.Data(), LT: lt, Hash: bytes.Repeat([]byte{byte(lt)}, 32)}
+	tx.Description.Description = tlb.TransactionDescriptionOrdinary{
+		ComputePhase: tlb.ComputePhase{Phase: tlb.ComputePhaseVM{Success: computeSuccess}},
+		ActionPhase:  action,
+	}
+	return tx
+}
+
+func TestTonRpc(t *testing.T) {
+	srv := serveFixtures(t, func(r *http.Request) string {
+		return "ton_jetton_metadata"
+	})
+
+	usdt := address.MustParseAddr("EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs")
+	offchain := address.MustParseAddr("EQAvlWFDxGF2lXm67y4yzC17wYKD9A0guwPkMs1gOsM__NOT")
+	onchain := &nft.ContentOnchain{}
+	onchain.SetAttribute("name", "Tether USD")
+	onchain.SetAttribute("symbol", "USD₮")
+	onchain.SetAttribute("decimals", "6")
+
+	wallet := address.MustParseAddr("EQDtFpEwcFAEcRe5mLVh2N6C0x-_hJEM7W61_JLnSF74p4q2")
+	api := &tonAPI{
+		jettons: map[string]nft.ContentAny{
+			usdt.String():     onchain,
+			offchain.String(): &nft.ContentOffchain{URI: srv.URL + "/notcoin.json"},
+		},
+		txs: []*tlb.Transaction{
+			tonTx(wallet, 1, true, nil),
+			tonTx(wallet, 2, true, &tlb.ActionPhase{Success: false, ResultCode: 37}),
+			tonTx(wallet, 3, false, nil),
+		},
+	}
+	w := NewTonRpc(&loader.ChainInfo{Name: "Ton", Client: api})
+	ctx := context.Background()
+
+	ti, err := w.GetTokenInfo(ctx, usdt.String(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "USD₮", ti.TokenName)
+	assert.Equal(t, "Tether USD", ti.FullName)
+	assert.Equal(t, int32(6), ti.Decimals)
+	assert.Equal(t, "1000000000000", ti.TotalSupply.String())
+
+	ti, err = w.GetTokenInfo(ctx, offchain.String(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "NOT", ti.TokenName)
+	assert.Equal(t, "Notcoin", ti.FullName)
+	assert.Equal(t, int32(9), ti.Decimals)
+
+	allowance, err := w.GetAllowance(ctx, wallet.String(), usdt.String(), wallet.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), allowance.Int64())
+
+	for _, tt := range []struct {
+		lt      uint64
+		success bool
+	}{
+		{1, true},
+		{2, false},
+		{3, false},
+	} {
+		hash := fmt.Sprintf("%s:%d:%s", "0:"+hex.EncodeToString(wallet.Data()), tt.lt, hex.EncodeToString(bytes.Repeat([]byte{byte(tt.lt)}, 32)))
+		ok, blockNumber, err := w.IsTxSuccess(ctx, hash)
+		assert.NoError(t, err, hash)
+		assert.Equal(t, tt.success, ok, hash)
+		// the logical time is not a masterchain seqno
+		assert.Zero(t, blockNumber)
+	}
+
+	status, err := w.GetTxStatus(ctx, fmt.Sprintf("%s:2:%x", wallet.String(), bytes.Repeat([]byte{2}, 32)))
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+	assert.Equal(t, "action phase result code 37", status.RevertReason)
+	assert.True(t, status.Final)
+
+	_, _, err = w.IsTxSuccess(ctx, fmt.Sprintf("%s:4:%x", wallet.String(), bytes.Repeat([]byte{4}, 32)))
+	assert.ErrorIs(t, err, ton.ErrNoTransactionsWereFound)
+	status, err = w.GetTxStatus(ctx, fmt.Sprintf("%s:4:%x", wallet.String(), bytes.Repeat([]byte{4}, 32)))
+	assert.NoError(t, err)
+	assert.Equal(t, TxStateDropped, status.State)
+	_, _, err = w.IsTxSuccess(ctx, "abcd")
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"math/big"
+)
+
+// TxState is the normalized state of a transaction across backends.
+type TxState int32
+
+const (
+	// TxStatePending is a transaction known by the node but not in a block yet.
+	TxStatePending TxState = iota + 1
+	// TxStateIncluded is a successful transaction in a block that can still be reverted.
+	TxStateIncluded
+	// TxStateFinalized is a successful transaction that can no longer be reverted.
+	TxStateFinalized
+	// TxStateFailed is a transaction included in a block whose execution failed, it still paid its fee.
+	TxStateFailed
+	// TxStateDropped is a transaction the node knows nothing about: dropped from the mempool,
+	// rejected, or not propagated yet.
+	TxStateDropped
+)
+
+func (s TxState) String() string {
+	switch s {
+	case TxStatePending:
+		return "pending"
+	case TxStateIncluded:
+		return "included"
+	case TxStateFinalized:
+		return "finalized"
+	case TxStateFailed:
+		return "failed"
+	case TxStateDropped:
+		return "dropped"
+	}
+	return "unknown"
+}
+
+// TxStatus is the receipt of a transaction as returned by Rpc.GetTxStatus.
+type TxStatus struct {
+	Hash  string
+	State TxState
+	// BlockNumber is the block, slot or checkpoint of the transaction in the unit of
+	// GetLatestBlockNumber, 0 if it is not in a block. It is always 0 on TON, which only exposes finality.
+	BlockNumber int64
+	// Confirmations counts the blocks from BlockNumber to the head, BlockNumber included, 0 on TON.
+	Confirmations int64
+	// Final is true when the transaction can no longer be reverted, whether it failed or not.
+	Final bool
+	// GasUsed is the gas consumed by the transaction, 0 on chains without gas.
+	GasUsed uint64
+	// Fee is what the transaction paid, in the smallest unit of the gas token, nil when the node
+	// does not return the effective gas price of an EVM transaction.
+	Fee *big.Int
+	// RevertReason explains why a failed transaction failed.
+	RevertReason string
+}
+
+// IsSuccess reports whether the transaction is in a block and succeeded.
+func (s *TxStatus) IsSuccess() bool {
+	return s.State == TxStateIncluded || s.State == TxStateFinalized
+}
+
+// finalize sets State and Final of an included transaction from its execution result.
+func (s *TxStatus) finalize(success bool, final bool, revertReason string) {
+	s.Final = final
+	switch {
+	case !success:
+		s.State = TxStateFailed
+		s.RevertReason = revertReason
+	case final:
+		s.State = TxStateFinalized
+	default:
+		s.State = TxStateIncluded
+	}
+}
+
+// confirmations counts the blocks from blockNumber to head, at least 1 when head lags behind.
+func confirmations(head int64, blockNumber int64) int64 {
+	if blockNumber <= 0 {
+		return 0
+	}
+	if head < blockNumber {
+		return 1
+	}
+	return head - blockNumber + 1
+}
@@ -57,6 +57,18 @@ func (w *ZksliteRpc) GetBalanceAtBlockNumber(ctx context.Context, ownerAddr stri
 	return w.GetBalance(ctx, ownerAddr, tokenAddr)
 }
 
+func (w *ZksliteRpc) GetBalances(ctx context.Context, ownerAddr string, tokenAddrs []string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(tokenAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddr, tokenAddrs[i])
+	})
+}
+
+func (w *ZksliteRpc) GetBalancesForOwners(ctx context.Context, ownerAddrs []string, tokenAddr string) ([]*big.Int, error) {
+	return getBalancesParallel(ctx, len(ownerAddrs), func(ctx context.Context, i int) (*big.Int, error) {
+		return w.GetBalance(ctx, ownerAddrs[i], tokenAddr)
+	})
+}
+
 func (w *ZksliteRpc) GetBalance(ctx context.Context, ownerAddr string, tokenAddr string) (*big.Int, error) {
 	ownerAddr = strings.TrimSpace(ownerAddr)
 	tokenAddr = strings.TrimSpace(tokenAddr)
@@ -120,6 +132,10 @@ func (w *ZksliteRpc) IsTxSuccess(ctx context.Context, hash string) (bool, int64,
 	return false, 0, fmt.Errorf("not impl")
 }
 
+func (w *ZksliteRpc) GetTxStatus(ctx context.Context, hash string) (*TxStatus, error) {
+	return nil, fmt.Errorf("not impl")
+}
+
 func (w *ZksliteRpc) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	return 0, fmt.Errorf("not impl")
 }